@@ -0,0 +1,58 @@
+package json
+
+import (
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	arc.RegisterServiceFormat(serde.FormatJSON, serviceFormat{})
+}
+
+// ServiceJSON is the JSON message of an access service.
+type ServiceJSON struct {
+	Rules map[string][]string
+}
+
+// serviceFormat is the format to encode and decode access services.
+//
+// - implements serde.FormatEngine
+type serviceFormat struct{}
+
+// Encode implements serde.FormatEngine. It encodes the access service if
+// appropriate, otherwise it returns an error.
+func (serviceFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	srvc, ok := msg.(*arc.Service)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message of type '%T'", msg)
+	}
+
+	m := ServiceJSON{
+		Rules: srvc.GetRules(),
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It populates the access service from
+// the data if appropriate, otherwise it returns an error.
+func (serviceFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := ServiceJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal access: %v", err)
+	}
+
+	opts := make([]arc.ServiceOption, 0, len(m.Rules))
+	for rule, idents := range m.Rules {
+		opts = append(opts, arc.WithRule(rule, idents...))
+	}
+
+	return arc.NewService(opts...), nil
+}
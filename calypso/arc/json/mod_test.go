@@ -0,0 +1,106 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+)
+
+func TestServiceFormat_RoundTrip(t *testing.T) {
+	ctx := json.NewContext()
+
+	testCases := []struct {
+		name string
+		srvc *arc.Service
+	}{
+		{"empty", arc.NewService()},
+		{"one rule", arc.NewService(arc.WithRule("read", "bls:aa"))},
+		{"rules", arc.NewService(
+			arc.WithRule("read", "bls:aa", "schnorr:bb"),
+			arc.WithRule("update", "bls:aa"),
+		)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.srvc.Serialize(ctx)
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+
+			ac, err := arc.NewFactory().AccessOf(ctx, data)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+
+			rules := ac.(*arc.Service).GetRules()
+			if !reflect.DeepEqual(rules, tc.srvc.GetRules()) {
+				t.Fatalf("expected %v, got %v", tc.srvc.GetRules(), rules)
+			}
+
+			// The encoding is stable, as the write proofs are bound to it.
+			again, err := ac.(serde.Message).Serialize(ctx)
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+
+			if string(again) != string(data) {
+				t.Fatalf("expected %s, got %s", data, again)
+			}
+		})
+	}
+}
+
+func TestServiceFormat_Encode(t *testing.T) {
+	_, err := serviceFormat{}.Encode(json.NewContext(), fakeMessage{})
+	checkError(t, err, "unsupported message of type 'json.fakeMessage'")
+}
+
+func TestServiceFormat_Decode(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"empty", ``, "couldn't unmarshal access"},
+		{"truncated", `{"Rules":{"read":["bls:aa"]`, "couldn't unmarshal access"},
+		{"not an object", `[]`, "couldn't unmarshal access"},
+		{"rules not a map", `{"Rules":5}`, "couldn't unmarshal access"},
+		{"identities not a list", `{"Rules":{"read":"bls:aa"}}`,
+			"couldn't unmarshal access"},
+		{"identity not a string", `{"Rules":{"read":[5]}}`,
+			"couldn't unmarshal access"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := arc.NewFactory().AccessOf(json.NewContext(), []byte(tc.data))
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func checkError(t *testing.T, err error, substr string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", substr)
+	}
+
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type fakeMessage struct{}
+
+func (fakeMessage) Serialize(serde.Context) ([]byte, error) {
+	return nil, nil
+}
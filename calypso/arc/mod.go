@@ -0,0 +1,182 @@
+// Package arc implements a simple access rights control for the Calypso
+// records. Contrary to the darc service of Dela, the permissions are kept in
+// the service itself so that it can travel along the record it protects.
+package arc
+
+import (
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
+	"golang.org/x/xerrors"
+)
+
+var serviceFormats = registry.NewSimpleRegistry()
+
+// RegisterServiceFormat registers the engine for the provided format.
+func RegisterServiceFormat(format serde.Format, engine serde.FormatEngine) {
+	serviceFormats.Register(format, engine)
+}
+
+// ServiceOption is the option type to create an access service.
+type ServiceOption func(*Service)
+
+// WithRule is an option to allow the identities, in their text form, for the
+// rule.
+func WithRule(rule string, idents ...string) ServiceOption {
+	return func(s *Service) {
+		for _, ident := range idents {
+			if !s.contains(rule, ident) {
+				s.rules[rule] = append(s.rules[rule], ident)
+			}
+		}
+	}
+}
+
+// Service is an access service that keeps, for each rule, the list of the
+// identities that are allowed. An identity is compared using its text
+// representation.
+//
+// - implements access.Service
+// - implements serde.Message
+type Service struct {
+	rules map[string][]string
+}
+
+// NewService returns a new access service. Without any option, no identity is
+// allowed.
+func NewService(opts ...ServiceOption) *Service {
+	s := &Service{
+		rules: make(map[string][]string),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// GetRules returns a copy of the identities, in their text form, allowed for
+// each rule.
+func (s *Service) GetRules() map[string][]string {
+	rules := make(map[string][]string, len(s.rules))
+
+	for rule, idents := range s.rules {
+		rules[rule] = append([]string{}, idents...)
+	}
+
+	return rules
+}
+
+// Match implements access.Service. It returns nil if at least one of the
+// identities is allowed for the rule of the credential. The store is not used
+// as the permissions are held by the service.
+func (s *Service) Match(_ store.Readable, creds access.Credential,
+	idents ...access.Identity) error {
+
+	if len(idents) == 0 {
+		return xerrors.New("expect at least one identity")
+	}
+
+	rule := creds.GetRule()
+
+	_, found := s.rules[rule]
+	if !found {
+		return xerrors.Errorf("rule '%s' not found", rule)
+	}
+
+	for _, ident := range idents {
+		text, err := ident.MarshalText()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal identity: %v", err)
+		}
+
+		if s.contains(rule, string(text)) {
+			return nil
+		}
+	}
+
+	return xerrors.Errorf("no identity allowed for rule '%s'", rule)
+}
+
+// Grant implements access.Service. It allows each of the identities,
+// individually, for the rule of the credential. The store is not used as the
+// permissions are held by the service.
+func (s *Service) Grant(_ store.Snapshot, creds access.Credential,
+	idents ...access.Identity) error {
+
+	rule := creds.GetRule()
+
+	for _, ident := range idents {
+		text, err := ident.MarshalText()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal identity: %v", err)
+		}
+
+		WithRule(rule, string(text))(s)
+	}
+
+	return nil
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data of the service.
+func (s *Service) Serialize(ctx serde.Context) ([]byte, error) {
+	format := serviceFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, s)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't encode access: %v", err)
+	}
+
+	return data, nil
+}
+
+func (s *Service) contains(rule, ident string) bool {
+	for _, a := range s.rules[rule] {
+		if a == ident {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Factory is the factory to deserialize the access services.
+//
+// - implements calypso.AccessFactory
+type Factory struct{}
+
+// NewFactory returns a new instance of the factory.
+func NewFactory() Factory {
+	return Factory{}
+}
+
+// Deserialize implements serde.Factory.
+func (f Factory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	format := serviceFormats.Get(ctx.GetFormat())
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("%v format: %v", ctx.GetFormat(), err)
+	}
+
+	return msg, nil
+}
+
+// AccessOf implements calypso.AccessFactory. It returns the access service of
+// the data if appropriate, otherwise an error.
+func (f Factory) AccessOf(ctx serde.Context, data []byte) (access.Service, error) {
+	msg, err := f.Deserialize(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	srvc, ok := msg.(*Service)
+	if !ok {
+		return nil, xerrors.Errorf("invalid access '%T'", msg)
+	}
+
+	return srvc, nil
+}
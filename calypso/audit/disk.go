@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"go.dedis.ch/dela/core/store/kv"
+	"golang.org/x/xerrors"
+)
+
+// bucketName is the name of the bucket where the entries are stored.
+var bucketName = []byte("calypso-audit")
+
+// NewDisk returns an audit log persisted in the database. The database is not
+// closed by the log.
+func NewDisk(db kv.DB) (*Disk, error) {
+	l := &Disk{
+		db: db,
+	}
+
+	err := db.View(func(txn kv.ReadableTx) error {
+		bucket := txn.GetBucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			l.next++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read db: %v", err)
+	}
+
+	return l, nil
+}
+
+// Disk is an audit log persisted in a key/value database. The entries are
+// stored in JSON at their sequence number.
+//
+// - implements audit.Log
+type Disk struct {
+	sync.Mutex
+	db   kv.DB
+	next uint64
+}
+
+// Append implements audit.Log.
+func (l *Disk) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal entry: %v", err)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, l.next)
+
+	err = l.db.Update(func(txn kv.WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate(bucketName)
+		if err != nil {
+			return xerrors.Errorf("failed to get bucket: %v", err)
+		}
+
+		return bucket.Set(key, data)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to store entry: %v", err)
+	}
+
+	l.next++
+
+	return nil
+}
+
+// Query implements audit.Log.
+func (l *Disk) Query(f Filter) ([]Entry, error) {
+	res := make([]Entry, 0)
+
+	err := l.db.View(func(txn kv.ReadableTx) error {
+		bucket := txn.GetBucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		// The keys are big-endian sequence numbers, therefore the scan of
+		// the bucket returns the entries in order.
+		return bucket.Scan(nil, func(k, v []byte) error {
+			var e Entry
+			err := json.Unmarshal(v, &e)
+			if err != nil {
+				return xerrors.Errorf("failed to unmarshal entry: %v", err)
+			}
+
+			if f.Match(e) {
+				res = append(res, e)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read db: %v", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela/core/store/kv"
+)
+
+func TestDisk_AppendQuery(t *testing.T) {
+	dir, path := tempPath(t)
+	defer os.RemoveAll(dir)
+
+	db := openDB(t, path)
+
+	log, err := NewDisk(db)
+	if err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	entries := makeEntries()
+
+	for _, e := range entries[:2] {
+		err = log.Append(e)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	expectEntries(t, log, Filter{}, entries[:2]...)
+
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("failed to close db: %v", err)
+	}
+
+	// The entries are kept when the log is reopened, and the new ones are
+	// appended after them.
+	db = openDB(t, path)
+	defer db.Close()
+
+	log, err = NewDisk(db)
+	if err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+
+	expectEntries(t, log, Filter{}, entries[:2]...)
+
+	err = log.Append(entries[2])
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	expectEntries(t, log, Filter{}, entries...)
+}
+
+func TestDisk_Filter(t *testing.T) {
+	dir, path := tempPath(t)
+	defer os.RemoveAll(dir)
+
+	db := openDB(t, path)
+	defer db.Close()
+
+	log, err := NewDisk(db)
+	if err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	// An empty log has no bucket yet.
+	expectEntries(t, log, Filter{})
+
+	entries := makeEntries()
+
+	for _, e := range entries {
+		err = log.Append(e)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	expectEntries(t, log, Filter{RecordID: []byte{1}}, entries[0], entries[2])
+	expectEntries(t, log, Filter{Identity: "bls:bob"}, entries[1], entries[2])
+	expectEntries(t, log, Filter{RecordID: []byte{1}, Identity: "bls:bob"},
+		entries[2])
+	expectEntries(t, log, Filter{RecordID: []byte{3}})
+	expectEntries(t, log, Filter{Identity: "bls:eve"})
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeEntries() []Entry {
+	now := time.Unix(1600000000, 0).UTC()
+
+	return []Entry{
+		{
+			Time:       now,
+			Operation:  OpRead,
+			RecordID:   []byte{1},
+			Identities: []string{"bls:alice"},
+			Outcome:    OutcomeSuccess,
+		},
+		{
+			Time:       now.Add(time.Second),
+			Operation:  OpUpdateAccess,
+			RecordID:   []byte{2},
+			Identities: []string{"bls:bob"},
+			Outcome:    OutcomeDenied,
+			Error:      "access denied",
+		},
+		{
+			Time:       now.Add(2 * time.Second),
+			Operation:  OpReadReencrypted,
+			RecordID:   []byte{1},
+			Identities: []string{"bls:alice", "bls:bob"},
+			Outcome:    OutcomeSuccess,
+		},
+	}
+}
+
+func expectEntries(t *testing.T, log Log, f Filter, expected ...Entry) {
+	t.Helper()
+
+	entries, err := log.Query(f)
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+
+	for i, e := range entries {
+		if !e.Time.Equal(expected[i].Time) || e.Operation != expected[i].Operation ||
+			e.Outcome != expected[i].Outcome || e.Error != expected[i].Error ||
+			!f.Match(e) || !(Filter{RecordID: expected[i].RecordID}).Match(e) {
+
+			t.Fatalf("entry %d: expected %v, got %v", i, expected[i], e)
+		}
+	}
+}
+
+func tempPath(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "calypso-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, filepath.Join(dir, "audit.db")
+}
+
+func openDB(t *testing.T, path string) kv.DB {
+	t.Helper()
+
+	db, err := kv.New(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+
+	return db
+}
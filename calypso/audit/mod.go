@@ -0,0 +1,146 @@
+// Package audit defines an append-only log of the operations performed on the
+// Calypso records, so that the data owners can see who accessed their
+// secrets.
+package audit
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Operation is the type of operation recorded in the log.
+type Operation string
+
+const (
+	// OpRead is the operation to read a record.
+	OpRead Operation = "READ"
+
+	// OpReadReencrypted is the operation to read a re-encrypted record.
+	OpReadReencrypted Operation = "READ_REENCRYPTED"
+
+	// OpUpdateAccess is the operation to update the access of a record.
+	OpUpdateAccess Operation = "UPDATE_ACCESS"
+)
+
+// Outcome is the result of an operation.
+type Outcome string
+
+const (
+	// OutcomeSuccess is the outcome of an operation that succeeded.
+	OutcomeSuccess Outcome = "SUCCESS"
+
+	// OutcomeUnauthenticated is the outcome of an operation whose identities
+	// didn't prove they sent it.
+	OutcomeUnauthenticated Outcome = "UNAUTHENTICATED"
+
+	// OutcomeDenied is the outcome of an operation refused by the access
+	// control of the record.
+	OutcomeDenied Outcome = "DENIED"
+
+	// OutcomeNotFound is the outcome of an operation on an unknown record.
+	OutcomeNotFound Outcome = "NOT_FOUND"
+
+	// OutcomeExpired is the outcome of an operation on an expired record.
+	OutcomeExpired Outcome = "EXPIRED"
+
+	// OutcomeFailure is the outcome of an operation that failed for another
+	// reason.
+	OutcomeFailure Outcome = "FAILURE"
+)
+
+// Entry is an entry of the audit log.
+type Entry struct {
+	Time       time.Time
+	Operation  Operation
+	RecordID   []byte
+	Identities []string
+	Outcome    Outcome
+	Error      string `json:",omitempty"`
+}
+
+// Filter selects the entries of a query. Empty fields match any entry.
+type Filter struct {
+	RecordID []byte
+	Identity string
+}
+
+// Match returns true if the entry is selected by the filter.
+func (f Filter) Match(e Entry) bool {
+	if len(f.RecordID) > 0 && !bytes.Equal(f.RecordID, e.RecordID) {
+		return false
+	}
+
+	if f.Identity == "" {
+		return true
+	}
+
+	for _, ident := range e.Identities {
+		if ident == f.Identity {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Log is an append-only log of entries.
+type Log interface {
+	// Append adds the entry at the end of the log.
+	Append(e Entry) error
+
+	// Query returns the entries selected by the filter in the order they have
+	// been appended.
+	Query(f Filter) ([]Entry, error)
+}
+
+// NewInMemory returns a new empty log kept in memory.
+func NewInMemory() *InMemory {
+	return &InMemory{}
+}
+
+// NewBounded returns a new empty log kept in memory that only keeps the last
+// entries, up to the given number.
+func NewBounded(size int) *InMemory {
+	return &InMemory{size: size}
+}
+
+// InMemory is an audit log kept in memory.
+//
+// - implements audit.Log
+type InMemory struct {
+	sync.Mutex
+	entries []Entry
+	// size is the maximum number of entries, or zero for no limit.
+	size int
+}
+
+// Append implements audit.Log. The oldest entry is dropped when the log is
+// full.
+func (l *InMemory) Append(e Entry) error {
+	l.Lock()
+	l.entries = append(l.entries, e)
+
+	if l.size > 0 && len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+
+	l.Unlock()
+
+	return nil
+}
+
+// Query implements audit.Log.
+func (l *InMemory) Query(f Filter) ([]Entry, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	res := make([]Entry, 0)
+	for _, e := range l.entries {
+		if f.Match(e) {
+			res = append(res, e)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,36 @@
+package audit
+
+import "testing"
+
+func TestInMemory_AppendQuery(t *testing.T) {
+	log := NewInMemory()
+
+	entries := makeEntries()
+
+	for _, e := range entries {
+		err := log.Append(e)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	expectEntries(t, log, Filter{}, entries...)
+	expectEntries(t, log, Filter{RecordID: []byte{1}}, entries[0], entries[2])
+}
+
+func TestInMemory_Bounded(t *testing.T) {
+	log := NewBounded(2)
+
+	entries := makeEntries()
+
+	for _, e := range entries {
+		err := log.Append(e)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	// The oldest entry is dropped.
+	expectEntries(t, log, Filter{}, entries[1:]...)
+	expectEntries(t, log, Filter{RecordID: []byte{1}}, entries[2])
+}
@@ -2,16 +2,22 @@ package calypso
 
 import (
 	"bytes"
+	"time"
 
+	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/dela/serde/registry"
 
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela-apps/calypso/audit"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
 	"go.dedis.ch/dela-apps/calypso/storage"
 	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/dkg"
 	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
 	"golang.org/x/xerrors"
 )
 
@@ -21,8 +27,19 @@ const (
 	ArcRuleUpdate = "calypso_update"
 	// ArcRuleRead defines the arc rule to read a value
 	ArcRuleRead = "calypso_read"
+
+	// defaultChallengeTTL is the time during which the nonce of a challenge
+	// can be used, by default.
+	defaultChallengeTTL = 5 * time.Minute
+
+	// maxAttempts is the number of recent attempts that failed to
+	// authenticate kept in memory.
+	maxAttempts = 1000
 )
 
+// suite is the Kyber suite for Pedersen.
+var suite = suites.MustFind("Ed25519")
+
 var recordFormats = registry.NewSimpleRegistry()
 
 // RegisterRecordFormats registers the engine for the provided format.
@@ -34,16 +51,65 @@ func RegisterRecordFormats(format serde.Format, engine serde.FormatEngine) {
 //
 // implements calypso.PrivateStorage
 type Calypso struct {
-	dkgActor dkg.Actor
-	storage  storage.KeyValue
+	dkgActor   dkg.Actor
+	storage    storage.KeyValue
+	auditLog   audit.Log
+	attempts   audit.Log
+	challenges *challenges
+	ledger     Ledger
+}
+
+// Option is the type of option to create a Calypso.
+type Option func(*Calypso)
+
+// WithStorage is an option to set the storage of the records. By default, the
+// records are stored in memory.
+func WithStorage(s storage.KeyValue) Option {
+	return func(c *Calypso) {
+		c.storage = s
+	}
+}
+
+// WithAuditLog is an option to set the log where the reads and the updates
+// are recorded. By default, the log is kept in memory.
+func WithAuditLog(l audit.Log) Option {
+	return func(c *Calypso) {
+		c.auditLog = l
+	}
+}
+
+// WithChallengeTTL is an option to set the time during which the nonce of a
+// challenge can be used. By default, it is five minutes.
+func WithChallengeTTL(ttl time.Duration) Option {
+	return func(c *Calypso) {
+		c.challenges.ttl = ttl
+	}
+}
+
+// WithLedger is an option to anchor the writes, the updates and the reads in a
+// ledger. A secret is then only released once its read has been committed. By
+// default, nothing is anchored.
+func WithLedger(l Ledger) Option {
+	return func(c *Calypso) {
+		c.ledger = l
+	}
 }
 
 // NewCalypso creates a new Calypso
-func NewCalypso(actor dkg.Actor) *Calypso {
-	return &Calypso{
-		dkgActor: actor,
-		storage:  inmemory.NewInMemory(),
+func NewCalypso(actor dkg.Actor, opts ...Option) *Calypso {
+	c := &Calypso{
+		dkgActor:   actor,
+		storage:    inmemory.NewInMemory(),
+		auditLog:   audit.NewInMemory(),
+		attempts:   audit.NewBounded(maxAttempts),
+		challenges: newChallenges(defaultChallengeTTL),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // Setup implements calypso.PrivateStorage
@@ -58,6 +124,25 @@ func (c *Calypso) Setup(ca crypto.CollectiveAuthority,
 	return pubKey, nil
 }
 
+// Reshare implements calypso.PrivateStorage. It requires the DKG actor to
+// implement calypso.Resharer.
+func (c *Calypso) Reshare(ca crypto.CollectiveAuthority,
+	threshold int) (pubKey kyber.Point, err error) {
+
+	resharer, ok := c.dkgActor.(Resharer)
+	if !ok {
+		return nil, xerrors.Errorf("actor '%T': %w", c.dkgActor,
+			ErrReshareNotSupported)
+	}
+
+	pubKey, err = resharer.ReshareTo(ca, threshold)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to reshare: %v", err)
+	}
+
+	return pubKey, nil
+}
+
 // GetPublicKey implements calypso.PrivateStorage
 func (c *Calypso) GetPublicKey() (kyber.Point, error) {
 	if c.dkgActor == nil {
@@ -72,86 +157,528 @@ func (c *Calypso) GetPublicKey() (kyber.Point, error) {
 	return pubKey, nil
 }
 
+// WriteOption is the type of options to write a record.
+type WriteOption func(*writeTemplate)
+
+type writeTemplate struct {
+	expiry time.Time
+}
+
+// ExpireAt is an option to make the record unreadable after the given time.
+// The expired records are eventually deleted by the sweeper.
+func ExpireAt(t time.Time) WriteOption {
+	return func(tmpl *writeTemplate) {
+		tmpl.expiry = t
+	}
+}
+
 // Write implements calypso.PrivateStorage
-func (c *Calypso) Write(em EncryptedMessage,
-	ac access.Service) ([]byte, error) {
+func (c *Calypso) Write(em EncryptedMessage, ac access.Service,
+	opts ...WriteOption) ([]byte, error) {
 
-	var buf bytes.Buffer
+	tmpl := writeTemplate{}
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	if !tmpl.expiry.IsZero() && !tmpl.expiry.After(time.Now()) {
+		return nil, xerrors.Errorf("expiry %v is in the past", tmpl.expiry)
+	}
 
-	_, err := em.GetK().MarshalTo(&buf)
+	err := ValidateCiphertext(em.GetK(), em.GetC())
 	if err != nil {
-		return nil, xerrors.Errorf("failed to marshal K: %v", err)
+		return nil, xerrors.Errorf("failed to validate ciphertext: %w", err)
 	}
 
-	_, err = em.GetC().MarshalTo(&buf)
+	err = VerifyWriteProof(em.GetProof(), em.GetK(), em.GetC(), em.GetData(),
+		tmpl.expiry, ac)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to marshal C: %v", err)
+		return nil, xerrors.Errorf("failed to verify proof: %w", err)
 	}
 
-	hash := crypto.NewSha256Factory().New()
-	_, err = hash.Write(buf.Bytes())
+	key, err := RecordID(em.GetK(), em.GetC())
 	if err != nil {
-		return nil, xerrors.Errorf("failed to compute hash: %v", err)
+		return nil, xerrors.Errorf("failed to compute ID: %v", err)
 	}
 
-	key := hash.Sum(nil)
+	// The storage is looked up first so that a replicated storage can find a
+	// record held by another member, and then again in the batch so that a
+	// concurrent write of the same record is refused.
+	err = checkAbsent(c.storage, key)
+	if err != nil {
+		return nil, err
+	}
 
-	record := Record{
-		k:      em.GetK(),
-		c:      em.GetC(),
-		access: ac,
+	record := NewRecord(em.GetK(), em.GetC(), ac, WithData(em.GetData()),
+		WithProof(em.GetProof()), WithExpiry(tmpl.expiry))
+
+	// The contract refuses a record that already exists in the ledger, so
+	// that the first write to be committed is the one that is stored.
+	if c.ledger != nil {
+		err = c.ledger.Write(key, record)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to anchor write: %v", err)
+		}
 	}
 
-	c.storage.Store(key, record)
+	err = c.storage.Batch(func(tx storage.Transaction) error {
+		err := checkAbsent(tx, key)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Store(key, record)
+		if err != nil {
+			return xerrors.Errorf("failed to store record: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return key, nil
 }
 
-// Read implements calypso.PrivateStorage
-func (c *Calypso) Read(id []byte, idents ...access.Identity) ([]byte, error) {
+// Challenge implements calypso.PrivateStorage. The nonces are authenticated
+// with a key of the node that is kept in memory, therefore the request must be
+// sent to the node that issued the challenge. The node keeps nothing until the
+// nonce is used.
+func (c *Calypso) Challenge(id []byte) ([]byte, time.Time, error) {
+	if len(id) == 0 {
+		return nil, time.Time{}, xerrors.New("record ID is empty")
+	}
+
+	nonce, expiry := c.challenges.issue(id)
+
+	return nonce, expiry, nil
+}
+
+// Read implements calypso.PrivateStorage. The read is recorded in the audit
+// log, and the message is not returned if it can't be recorded.
+func (c *Calypso) Read(id []byte, auth Authentication) ([]byte, error) {
+	msg, idents, err := c.read(id, auth)
+
+	auditErr := c.audit(audit.OpRead, id, idents, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if auditErr != nil {
+		return nil, xerrors.Errorf("failed to audit: %v", auditErr)
+	}
+
+	return msg, nil
+}
+
+// read returns the message of the record, and the identities of the request
+// once they are authenticated.
+func (c *Calypso) read(id []byte,
+	auth Authentication) ([]byte, []access.Identity, error) {
+
+	idents, err := c.authenticate(id, auth, ReadDigest(auth.Nonce, id))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to authenticate: %w", err)
+	}
+
 	record, err := c.getRead(id)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get read: %v", err)
+		return nil, idents, xerrors.Errorf("failed to get read: %w", err)
 	}
 
-	// err = record.access.Match(c.storage, ArcRuleRead, idents...)
-	// if err != nil {
-	// 	return nil, xerrors.Errorf("darc verification failed: %v", err)
-	// }
+	err = c.match(record, NewCredential(id, ArcRuleRead), idents...)
+	if err != nil {
+		return nil, idents, xerrors.Errorf("failed to verify access: %w", err)
+	}
+
+	if c.ledger != nil {
+		err = c.ledger.Read(id, auth, nil, nil)
+		if err != nil {
+			return nil, idents, xerrors.Errorf("failed to anchor read: %v", err)
+		}
+	}
 
-	msg, err := c.dkgActor.Decrypt(record.k, record.c)
+	msg, err := DecryptRecord(c.dkgActor, record)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to decrypt with dkg: %v", err)
+		return nil, idents, xerrors.Errorf("failed to decrypt: %v", err)
 	}
 
-	return msg, nil
+	return msg, idents, nil
+}
+
+// ReadReencrypted implements calypso.PrivateStorage. It requires the DKG actor
+// to implement calypso.Reencrypter so that the node never sees the secret. The
+// read is recorded in the audit log.
+func (c *Calypso) ReadReencrypted(id []byte, pubk kyber.Point, keyProof []byte,
+	auth Authentication) (Reencrypted, error) {
+
+	res, idents, err := c.readReencrypted(id, pubk, keyProof, auth)
+
+	auditErr := c.audit(audit.OpReadReencrypted, id, idents, err)
+	if err != nil {
+		return Reencrypted{}, err
+	}
+
+	if auditErr != nil {
+		return Reencrypted{}, xerrors.Errorf("failed to audit: %v", auditErr)
+	}
+
+	return res, nil
+}
+
+// readReencrypted returns the secret re-encrypted under the key of the reader,
+// and the identities of the request once they are authenticated.
+func (c *Calypso) readReencrypted(id []byte, pubk kyber.Point, keyProof []byte,
+	auth Authentication) (Reencrypted, []access.Identity, error) {
+
+	// The key is verified before anything else, so that no share is ever
+	// computed for a key that the reader doesn't own.
+	err := VerifyReaderKey(keyProof, pubk, auth.Nonce, id)
+	if err != nil {
+		return Reencrypted{}, nil,
+			xerrors.Errorf("failed to verify key: %w", err)
+	}
+
+	digest, err := ReencryptDigest(auth.Nonce, id, pubk, keyProof)
+	if err != nil {
+		return Reencrypted{}, nil,
+			xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	idents, err := c.authenticate(id, auth, digest)
+	if err != nil {
+		return Reencrypted{}, nil,
+			xerrors.Errorf("failed to authenticate: %w", err)
+	}
+
+	record, err := c.getRead(id)
+	if err != nil {
+		return Reencrypted{}, idents,
+			xerrors.Errorf("failed to get read: %w", err)
+	}
+
+	err = c.match(record, NewCredential(id, ArcRuleRead), idents...)
+	if err != nil {
+		return Reencrypted{}, idents,
+			xerrors.Errorf("failed to verify access: %w", err)
+	}
+
+	reencrypter, ok := c.dkgActor.(Reencrypter)
+	if !ok {
+		return Reencrypted{}, idents, xerrors.Errorf("actor '%T': %w",
+			c.dkgActor, ErrReencryptNotSupported)
+	}
+
+	if c.ledger != nil {
+		err = c.ledger.Read(id, auth, pubk, keyProof)
+		if err != nil {
+			return Reencrypted{}, idents,
+				xerrors.Errorf("failed to anchor read: %v", err)
+		}
+	}
+
+	res, err := ReencryptRecord(reencrypter, record, pubk)
+	if err != nil {
+		return Reencrypted{}, idents, err
+	}
+
+	return res, idents, nil
+}
+
+// ReencryptRecord returns the secret of the record re-encrypted under the
+// public key of a reader.
+func ReencryptRecord(reencrypter Reencrypter, record Record,
+	pubk kyber.Point) (Reencrypted, error) {
+
+	xhatEnc, err := reencrypter.Reencrypt(record.k, pubk)
+	if err != nil {
+		return Reencrypted{}, xerrors.Errorf("failed to reencrypt: %v", err)
+	}
+
+	res := Reencrypted{
+		xhatEnc: xhatEnc,
+		c:       record.c,
+		data:    record.data,
+	}
+
+	return res, nil
 }
 
 // UpdateAccess implements calypso.PrivateStorage. It sets a new arc for a given
-// ID, provided the current arc allows the given ident to do so.
-func (c *Calypso) UpdateAccess(id []byte, ident access.Identity,
+// ID, provided the current arc allows one of the identities to do so. The
+// update is recorded in the audit log.
+func (c *Calypso) UpdateAccess(id []byte, auth Authentication,
 	newAc access.Service) error {
 
-	// record, err := c.getRead(id)
-	// if err != nil {
-	// 	return xerrors.Errorf("failed to get read: %v", err)
-	// }
+	idents, err := c.updateAccess(id, auth, newAc)
+
+	auditErr := c.audit(audit.OpUpdateAccess, id, idents, err)
+	if err != nil {
+		return err
+	}
+
+	if auditErr != nil {
+		return xerrors.Errorf("failed to audit: %v", auditErr)
+	}
+
+	return nil
+}
+
+// updateAccess replaces the access control of the record, and returns the
+// identities of the request once they are authenticated.
+func (c *Calypso) updateAccess(id []byte, auth Authentication,
+	newAc access.Service) ([]access.Identity, error) {
+
+	if newAc == nil {
+		return nil, xerrors.New("new access control is nil")
+	}
+
+	digest, err := UpdateDigest(auth.Nonce, id, newAc)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	idents, err := c.authenticate(id, auth, digest)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to authenticate: %w", err)
+	}
+
+	// The record is read first so that a replicated storage can repair it,
+	// and then again in the batch so that it is only replaced if it has not
+	// been updated in the meantime.
+	record, err := c.getRead(id)
+	if err != nil {
+		return idents, xerrors.Errorf("failed to get read: %w", err)
+	}
+
+	err = c.match(record, NewCredential(id, ArcRuleUpdate), idents...)
+	if err != nil {
+		return idents, xerrors.Errorf("failed to verify access: %w", err)
+	}
+
+	// The authentication of the update is kept in the record, so that the
+	// other members and the contract verify it before they replace their copy.
+	updated, err := record.withUpdate(newAc, auth)
+	if err != nil {
+		return idents, xerrors.Errorf("failed to update: %w", err)
+	}
+
+	// The update is committed before it is stored, as the contract only
+	// accepts one update of each version of the record.
+	if c.ledger != nil {
+		err = c.ledger.Update(id, updated)
+		if err != nil {
+			return idents, xerrors.Errorf("failed to anchor update: %v", err)
+		}
+	}
+
+	err = c.storage.Batch(func(tx storage.Transaction) error {
+		current, err := readRecord(tx, id)
+		if err != nil {
+			return xerrors.Errorf("failed to get read: %w", err)
+		}
+
+		if !sameVersion(current, record) {
+			return xerrors.Errorf("record %#x has been updated concurrently", id)
+		}
+
+		// A replicated storage fails the update if it doesn't reach enough
+		// members.
+		updater, ok := tx.(storage.Updater)
+		if ok {
+			err = updater.Update(id, updated)
+		} else {
+			err = tx.Store(id, updated)
+		}
+
+		if err != nil {
+			return xerrors.Errorf("failed to store record: %v", err)
+		}
+
+		return nil
+	})
+
+	return idents, err
+}
+
+// Audit implements calypso.PrivateStorage. Only the admin of a record can see
+// who accessed it. The entries of the log are followed by the recent attempts
+// that failed to authenticate, which are only kept in memory.
+func (c *Calypso) Audit(id []byte, auth Authentication) ([]audit.Entry, error) {
+	idents, err := c.authenticate(id, auth, AuditDigest(auth.Nonce, id))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to authenticate: %w", err)
+	}
+
+	record, err := c.getRead(id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get read: %w", err)
+	}
+
+	err = c.match(record, NewCredential(id, ArcRuleUpdate), idents...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to verify access: %w", err)
+	}
+
+	entries, err := c.auditLog.Query(audit.Filter{RecordID: id})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query audit log: %v", err)
+	}
+
+	attempts, err := c.attempts.Query(audit.Filter{RecordID: id})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query attempts: %v", err)
+	}
+
+	return append(entries, attempts...), nil
+}
+
+// GetRecord returns the record of the ID, without decrypting it. It returns an
+// error wrapping ErrNotFound if the record doesn't exist, or ErrExpired if it
+// has expired. The record is read from a snapshot of the storage, so that a
+// replicated storage only looks it up locally: the lookup doesn't require an
+// authentication and must not make the node query the other members.
+func (c *Calypso) GetRecord(id []byte) (Record, error) {
+	var record Record
+
+	err := c.storage.Snapshot(func(r storage.Reader) error {
+		var err error
+		record, err = readRecord(r, id)
+
+		return err
+	})
+	if err != nil {
+		return Record{}, xerrors.Errorf("failed to get read: %w", err)
+	}
+
+	return record, nil
+}
+
+// GetAuditLog returns the log where the reads and the updates are recorded.
+func (c *Calypso) GetAuditLog() audit.Log {
+	return c.auditLog
+}
+
+// audit appends an entry for the operation to the audit log. The outcome is
+// derived from the error returned by the operation. An operation whose
+// identities are not authenticated can be sent by anyone under any identity,
+// so it is only kept in memory with the recent attempts, and without the
+// identities it claims.
+func (c *Calypso) audit(op audit.Operation, id []byte,
+	idents []access.Identity, opErr error) error {
+
+	entry := audit.Entry{
+		Time:       time.Now(),
+		Operation:  op,
+		RecordID:   id,
+		Identities: make([]string, 0, len(idents)),
+		Outcome:    audit.OutcomeSuccess,
+	}
+
+	for _, ident := range idents {
+		text, err := ident.MarshalText()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal identity: %v", err)
+		}
+
+		entry.Identities = append(entry.Identities, string(text))
+	}
+
+	switch {
+	case opErr == nil:
+	case xerrors.Is(opErr, ErrUnauthenticated):
+		entry.Outcome = audit.OutcomeUnauthenticated
+	case xerrors.Is(opErr, ErrAccessDenied):
+		entry.Outcome = audit.OutcomeDenied
+	case xerrors.Is(opErr, ErrNotFound):
+		entry.Outcome = audit.OutcomeNotFound
+	case xerrors.Is(opErr, ErrExpired):
+		entry.Outcome = audit.OutcomeExpired
+	default:
+		entry.Outcome = audit.OutcomeFailure
+	}
+
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+
+	if idents == nil {
+		return c.attempts.Append(entry)
+	}
+
+	err := c.auditLog.Append(entry)
+	if err != nil {
+		dela.Logger.Warn().Err(err).Msgf("failed to audit %s of %x", op, id)
+		return xerrors.Errorf("failed to append entry: %v", err)
+	}
+
+	return nil
+}
+
+// authenticate verifies that the nonce has been issued for the record and that
+// each identity signed the digest, and then consumes the nonce. It returns the
+// public keys of the identities, which are then matched against the access
+// control of the record.
+func (c *Calypso) authenticate(id []byte, auth Authentication,
+	digest []byte) ([]access.Identity, error) {
+
+	err := c.challenges.verify(auth.Nonce, id)
+	if err != nil {
+		return nil, xerrors.Errorf("%v: %w", err, ErrUnauthenticated)
+	}
+
+	idents, err := VerifyIdentities(auth, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.challenges.consume(auth.Nonce)
+	if err != nil {
+		return nil, xerrors.Errorf("%v: %w", err, ErrUnauthenticated)
+	}
+
+	return idents, nil
+}
 
-	// err = record.access.Match(ArcRuleUpdate, ident)
-	// if err != nil {
-	// 	return xerrors.Errorf("darc verification failed: %v", err)
-	// }
+// match verifies that the access control of the record allows the
+// identities for the credential. The access services of the records are
+// expected to be self-contained, therefore no store is provided.
+func (c *Calypso) match(record Record, creds access.Credential,
+	idents ...access.Identity) error {
 
-	// record.access = newAc
+	ac := record.GetAccess()
+	if ac == nil {
+		return xerrors.Errorf("record has no access control: %w",
+			ErrAccessDenied)
+	}
 
-	// c.storage.Store(id, record)
+	err := ac.Match(nil, creds, idents...)
+	if err != nil {
+		return xerrors.Errorf("%v: %w", err, ErrAccessDenied)
+	}
 
 	return nil
 }
 
 // getRead extract the read information from the storage
 func (c *Calypso) getRead(id []byte) (Record, error) {
-	message, err := c.storage.Read(id)
+	return readRecord(c.storage, id)
+}
+
+// recordReader is implemented by the storages and their transactions.
+type recordReader interface {
+	Read(key []byte) (serde.Message, error)
+}
+
+// readRecord returns the record of the ID, or an error wrapping ErrNotFound if
+// it doesn't exist, or ErrExpired if it has expired.
+func readRecord(r recordReader, id []byte) (Record, error) {
+	message, err := r.Read(id)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		return Record{}, xerrors.Errorf("%#x: %w", id, ErrNotFound)
+	}
+
 	if err != nil {
 		return Record{}, xerrors.Errorf("failed to read message: %v", err)
 	}
@@ -161,24 +688,232 @@ func (c *Calypso) getRead(id []byte) (Record, error) {
 		return Record{}, xerrors.Errorf("expected to find '%T' but found '%T'", record, message)
 	}
 
+	// The expired records are refused even if the sweeper hasn't deleted
+	// them yet.
+	if record.IsExpired(time.Now()) {
+		return Record{}, xerrors.Errorf("%#x: %w", id, ErrExpired)
+	}
+
 	return record, nil
 }
 
+// checkAbsent returns an error wrapping ErrAlreadyExists if the key exists.
+func checkAbsent(r recordReader, key []byte) error {
+	_, err := r.Read(key)
+	if err == nil {
+		return xerrors.Errorf("%#x: %w", key, ErrAlreadyExists)
+	}
+
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		return xerrors.Errorf("failed to read storage: %v", err)
+	}
+
+	return nil
+}
+
+// RecordID returns the identifier of a record, which is the hash of K||C.
+func RecordID(K, C kyber.Point) ([]byte, error) {
+	var buf bytes.Buffer
+
+	_, err := K.MarshalTo(&buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal K: %v", err)
+	}
+
+	_, err = C.MarshalTo(&buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal C: %v", err)
+	}
+
+	hash := crypto.NewSha256Factory().New()
+	_, err = hash.Write(buf.Bytes())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute hash: %v", err)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// DecryptRecord decrypts the secret of the record with the DKG actor. It does
+// not verify the access control, which is the responsibility of the caller.
+func DecryptRecord(actor dkg.Actor, record Record) ([]byte, error) {
+	msg, err := actor.Decrypt(record.k, record.c)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt with dkg: %v", err)
+	}
+
+	// In the hybrid mode, the decrypted point holds the symmetric key of the
+	// data.
+	msg, err = calycrypto.OpenHybrid(msg, record.data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open: %v", err)
+	}
+
+	return msg, nil
+}
+
+// NewAccess returns an access control that allows the owner to update and read
+// a record, and the readers to only read it.
+func NewAccess(owner access.Identity,
+	readers ...access.Identity) (access.Service, error) {
+
+	ac := arc.NewService()
+
+	err := ac.Grant(nil, NewCredential(nil, ArcRuleUpdate), owner)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to grant update: %v", err)
+	}
+
+	err = ac.Grant(nil, NewCredential(nil, ArcRuleRead),
+		append([]access.Identity{owner}, readers...)...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to grant read: %v", err)
+	}
+
+	return ac, nil
+}
+
+// Reencrypted is the secret of a record re-encrypted under the public key of a
+// reader.
+type Reencrypted struct {
+	xhatEnc kyber.Point
+	c       kyber.Point
+	data    []byte
+}
+
+// NewReencrypted creates a new re-encrypted secret.
+func NewReencrypted(xhatEnc, C kyber.Point, data []byte) Reencrypted {
+	return Reencrypted{
+		xhatEnc: xhatEnc,
+		c:       C,
+		data:    data,
+	}
+}
+
+// GetXhatEnc returns the shared secret re-encrypted under the key of the
+// reader.
+func (r Reencrypted) GetXhatEnc() kyber.Point {
+	return r.xhatEnc
+}
+
+// GetC returns C.
+func (r Reencrypted) GetC() kyber.Point {
+	return r.c
+}
+
+// GetData returns the symmetric ciphertext, or nil if the message is embedded
+// in C.
+func (r Reencrypted) GetData() []byte {
+	return r.data
+}
+
+// DecryptReencrypted is used by the reader to recover the message of a
+// re-encrypted secret with its private key xc and the collective public key X.
+// See crypto.DecryptReencrypted.
+func DecryptReencrypted(secret Reencrypted, xc kyber.Scalar,
+	X kyber.Point) ([]byte, error) {
+
+	env := calycrypto.Envelope{C: secret.c, Data: secret.data}
+
+	msg, err := calycrypto.DecryptReencrypted(secret.xhatEnc, env, xc, X)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt: %v", err)
+	}
+
+	return msg, nil
+}
+
+// credential is the credential used to verify the access to a record.
+//
+// - implements access.Credential
+type credential struct {
+	id   []byte
+	rule string
+}
+
+// NewCredential returns a new credential for the record ID and the rule, which
+// should be one of the ArcRule constants.
+func NewCredential(id []byte, rule string) access.Credential {
+	return credential{
+		id:   id,
+		rule: rule,
+	}
+}
+
+// GetID implements access.Credential. It returns the record ID.
+func (c credential) GetID() []byte {
+	return append([]byte{}, c.id...)
+}
+
+// GetRule implements access.Credential. It returns the arc rule.
+func (c credential) GetRule() string {
+	return c.rule
+}
+
 // Record defines what is stored in the db, which is the secrect and its
-// corresponding access control
+// corresponding access control. The access control of the write is kept
+// alongside the updates that replaced it, so that any member can verify the
+// current one from the write proof.
 type Record struct {
-	k      kyber.Point
-	c      kyber.Point
-	access access.Service
+	k       kyber.Point
+	c       kyber.Point
+	data    []byte
+	proof   []byte
+	access  access.Service
+	updates []AccessUpdate
+	expiry  time.Time
 }
 
-// NewRecord creates a new record from the points and the access control.
-func NewRecord(K, C kyber.Point, access access.Service) Record {
-	return Record{
+// RecordOption is the option type to create a record.
+type RecordOption func(*Record)
+
+// WithData is an option to set the symmetric ciphertext of a record in the
+// hybrid mode, where (K, C) protects the symmetric key.
+func WithData(data []byte) RecordOption {
+	return func(r *Record) {
+		r.data = data
+	}
+}
+
+// WithProof is an option to set the write proof of a record.
+func WithProof(proof []byte) RecordOption {
+	return func(r *Record) {
+		r.proof = proof
+	}
+}
+
+// WithExpiry is an option to set the time after which a record can't be read
+// anymore. A zero time means the record never expires.
+func WithExpiry(t time.Time) RecordOption {
+	return func(r *Record) {
+		r.expiry = t
+	}
+}
+
+// WithUpdates is an option to set the updates of the access control of a
+// record, in the order they have been done.
+func WithUpdates(updates ...AccessUpdate) RecordOption {
+	return func(r *Record) {
+		r.updates = updates
+	}
+}
+
+// NewRecord creates a new record from the points and the access control of
+// the write.
+func NewRecord(K, C kyber.Point, access access.Service,
+	opts ...RecordOption) Record {
+
+	r := Record{
 		k:      K,
 		c:      C,
 		access: access,
 	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	return r
 }
 
 // GetK returns K.
@@ -191,11 +926,57 @@ func (r Record) GetC() kyber.Point {
 	return r.c
 }
 
-// GetAccess returns the access control for this record.
+// GetData returns the symmetric ciphertext, or nil if the message is embedded
+// in C.
+func (r Record) GetData() []byte {
+	return r.data
+}
+
+// GetProof returns the write proof of the record.
+func (r Record) GetProof() []byte {
+	return r.proof
+}
+
+// GetAccess returns the current access control for this record, which is the
+// one of the last update, or the one of the write if it has not been updated.
 func (r Record) GetAccess() access.Service {
+	if len(r.updates) > 0 {
+		return r.updates[len(r.updates)-1].access
+	}
+
+	return r.access
+}
+
+// GetWriteAccess returns the access control the record has been written with,
+// which is bound to the write proof.
+func (r Record) GetWriteAccess() access.Service {
 	return r.access
 }
 
+// GetUpdates returns the updates of the access control, in the order they
+// have been done.
+func (r Record) GetUpdates() []AccessUpdate {
+	return append([]AccessUpdate{}, r.updates...)
+}
+
+// GetVersion implements replicated.Versioned. It returns the number of updates
+// of the access control, so that a record only replaces an older one.
+func (r Record) GetVersion() uint64 {
+	return uint64(len(r.updates))
+}
+
+// GetExpiry returns the time after which the record can't be read anymore, or
+// a zero time if it never expires.
+func (r Record) GetExpiry() time.Time {
+	return r.expiry
+}
+
+// IsExpired returns true if the record has an expiry that is before the given
+// time.
+func (r Record) IsExpired(now time.Time) bool {
+	return !r.expiry.IsZero() && !now.Before(r.expiry)
+}
+
 // Serialize implements serde.Message.
 func (r Record) Serialize(ctx serde.Context) ([]byte, error) {
 	format := recordFormats.Get(ctx.GetFormat())
@@ -215,20 +996,23 @@ type AccessKeyFac struct{}
 //
 // - implements serde.Factory
 type recordFactory struct {
-	// darcFactory arc.AccessControlFactory
+	accessFac AccessFactory
 }
 
-// NewRecordFactory returns a new instance of the record factory.
-func NewRecordFactory() serde.Factory {
+// NewRecordFactory returns a new instance of the record factory. The access
+// factory is used to decode the access control of the records.
+func NewRecordFactory(fac AccessFactory) serde.Factory {
 	return recordFactory{
-		// darcFactory: darc.NewFactory(),
+		accessFac: fac,
 	}
 }
 
-// VisitJSON implements serde.Factory. It deserializes the record.
+// Deserialize implements serde.Factory. It deserializes the record.
 func (f recordFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
 	format := recordFormats.Get(ctx.GetFormat())
 
+	ctx = serde.WithFactory(ctx, AccessKeyFac{}, f.accessFac)
+
 	msg, err := format.Decode(ctx, data)
 	if err != nil {
 		return nil, err
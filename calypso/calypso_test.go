@@ -0,0 +1,493 @@
+package calypso
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso/audit"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+func TestCalypso_Write_Concurrent(t *testing.T) {
+	const workers = 16
+
+	alice := bls.NewSigner()
+
+	caly := NewCalypso(nil)
+	msg, ac := makeMessage(t, alice)
+
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			_, errs[i] = caly.Write(msg, ac)
+		}(i)
+	}
+
+	wg.Wait()
+
+	written := 0
+
+	for _, err := range errs {
+		if err == nil {
+			written++
+		} else if !xerrors.Is(err, ErrAlreadyExists) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if written != 1 {
+		t.Fatalf("record written %d times", written)
+	}
+}
+
+func TestCalypso_UpdateAccess_Concurrent(t *testing.T) {
+	const workers = 16
+
+	alice := bls.NewSigner()
+
+	caly := NewCalypso(nil)
+	msg, ac := makeMessage(t, alice)
+
+	id, err := caly.Write(msg, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// Each update gives the record to a new admin, so only the first one
+	// applied is allowed by the access control that alice administers.
+	auths := make([]Authentication, workers)
+	newAcs := make([]access.Service, workers)
+
+	for i := range auths {
+		newAcs[i], err = NewAccess(bls.NewSigner().GetPublicKey())
+		if err != nil {
+			t.Fatalf("failed to create access: %v", err)
+		}
+
+		auths[i] = authenticateUpdate(t, caly, id, newAcs[i], alice)
+	}
+
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = caly.UpdateAccess(id, auths[i], newAcs[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	winner := -1
+
+	for i, err := range errs {
+		if err == nil {
+			if winner >= 0 {
+				t.Fatalf("both %d and %d updated the record", winner, i)
+			}
+
+			winner = i
+		} else if !xerrors.Is(err, ErrAccessDenied) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if winner < 0 {
+		t.Fatal("no update applied")
+	}
+
+	record, err := caly.GetRecord(id)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+
+	if record.GetAccess() != newAcs[winner] {
+		t.Fatal("access control of the record is not the one of the update")
+	}
+}
+
+func TestCalypso_Challenge(t *testing.T) {
+	alice := bls.NewSigner()
+
+	caly := NewCalypso(nil)
+	msg, ac := makeMessage(t, alice)
+
+	id, err := caly.Write(msg, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// The node keeps nothing for the challenges that are not answered.
+	for i := 0; i < 100; i++ {
+		_, _, err = caly.Challenge(id)
+		if err != nil {
+			t.Fatalf("failed to get challenge: %v", err)
+		}
+	}
+
+	if len(caly.challenges.used) != 0 {
+		t.Fatalf("%d nonces kept", len(caly.challenges.used))
+	}
+
+	_, _, err = caly.Challenge(nil)
+	if err == nil {
+		t.Fatal("expected an error for an empty ID")
+	}
+
+	newAc, err := NewAccess(alice.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	auth := authenticateUpdate(t, caly, id, newAc, alice)
+
+	err = caly.UpdateAccess(id, auth, newAc)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	err = caly.UpdateAccess(id, auth, newAc)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a used nonce to be refused, got: %v", err)
+	}
+
+	// A nonce of another node is refused.
+	other := NewCalypso(nil)
+	auth = authenticateUpdate(t, other, id, newAc, alice)
+
+	err = caly.UpdateAccess(id, auth, newAc)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a foreign nonce to be refused, got: %v", err)
+	}
+
+	expired := NewCalypso(nil, WithChallengeTTL(-time.Second))
+
+	_, err = expired.Write(msg, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	auth = authenticateUpdate(t, expired, id, newAc, alice)
+
+	err = expired.UpdateAccess(id, auth, newAc)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected an expired nonce to be refused, got: %v", err)
+	}
+}
+
+func TestCalypso_Audit(t *testing.T) {
+	alice := bls.NewSigner()
+	bob := bls.NewSigner()
+
+	caly := NewCalypso(nil)
+	msg, ac := makeMessage(t, alice)
+
+	id, err := caly.Write(msg, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// Bob is not the admin, so his update is denied and recorded.
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, ac, bob), ac)
+	if !xerrors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected access denied, got: %v", err)
+	}
+
+	entries, err := caly.Audit(id, authenticateAudit(t, caly, id, alice))
+	if err != nil {
+		t.Fatalf("failed to audit: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Outcome != audit.OutcomeDenied {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+
+	// Only the admin can see who accessed the record.
+	_, err = caly.Audit(id, authenticateAudit(t, caly, id, bob))
+	if !xerrors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected access denied, got: %v", err)
+	}
+
+	// Bob claims to be Alice with his own signature.
+	auth := authenticateAudit(t, caly, id, bob)
+	auth.Identities[0].PublicKey = alice.GetPublicKey()
+
+	_, err = caly.Audit(id, auth)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, got: %v", err)
+	}
+
+	// The same forgery on an update is only kept in memory, without the
+	// identity it claims.
+	auth = authenticateUpdate(t, caly, id, ac, bob)
+	auth.Identities[0].PublicKey = alice.GetPublicKey()
+
+	err = caly.UpdateAccess(id, auth, ac)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, got: %v", err)
+	}
+
+	persisted, err := caly.GetAuditLog().Query(audit.Filter{RecordID: id})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+
+	if len(persisted) != 1 {
+		t.Fatalf("unauthenticated attempt persisted: %v", persisted)
+	}
+
+	entries, err = caly.Audit(id, authenticateAudit(t, caly, id, alice))
+	if err != nil {
+		t.Fatalf("failed to audit: %v", err)
+	}
+
+	if len(entries) != 2 || entries[1].Outcome != audit.OutcomeUnauthenticated ||
+		len(entries[1].Identities) != 0 {
+
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+
+	// The signature of a read can't be used to audit.
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err = NewAuthentication(nonce, ReadDigest(nonce, id), alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	_, err = caly.Audit(id, auth)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, got: %v", err)
+	}
+
+	_, err = caly.Audit([]byte{0xaa}, authenticateAudit(t, caly, []byte{0xaa},
+		alice))
+	if !xerrors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+}
+
+func TestCalypso_Ledger(t *testing.T) {
+	alice := bls.NewSigner()
+
+	ledger := &fakeLedger{err: fakeError("refused")}
+	caly := NewCalypso(nil, WithLedger(ledger))
+	msg, ac := makeMessage(t, alice)
+
+	// The record is not stored if its write is refused by the ledger.
+	_, err := caly.Write(msg, ac)
+	if err == nil || err.Error() != "failed to anchor write: refused" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id, err := RecordID(msg.K, msg.C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	_, err = caly.GetRecord(id)
+	if !xerrors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+
+	ledger.err = nil
+
+	_, err = caly.Write(msg, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if ledger.writes != 1 {
+		t.Fatalf("write anchored %d times", ledger.writes)
+	}
+
+	// The secret is not released if the read is refused by the ledger.
+	ledger.err = fakeError("refused")
+
+	_, err = caly.Read(id, authenticateRead(t, caly, id, alice))
+	if err == nil || !strings.Contains(err.Error(), "failed to anchor read: refused") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The access control is not updated if the update is refused.
+	newAc, err := NewAccess(bls.NewSigner().GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, newAc, alice),
+		newAc)
+	if err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	record, err := caly.GetRecord(id)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+
+	if record.GetVersion() != 0 {
+		t.Fatalf("record updated to version %d", record.GetVersion())
+	}
+
+	ledger.err = nil
+
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, newAc, alice),
+		newAc)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	if ledger.updates != 1 {
+		t.Fatalf("update anchored %d times", ledger.updates)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// makeMessage returns a message administered by the signer, with a valid write
+// proof.
+func makeMessage(t *testing.T, admin crypto.Signer) (fakeMessage, access.Service) {
+	t.Helper()
+
+	ac, err := NewAccess(admin.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	pubkey := suite.Point().Pick(random.New())
+
+	k, K, C, err := calycrypto.Encrypt([]byte("hello"), pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	proof, err := NewWriteProof(k, K, C, nil, time.Time{}, ac)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	return fakeMessage{K: K, C: C, proof: proof}, ac
+}
+
+func authenticateUpdate(t *testing.T, caly *Calypso, id []byte,
+	newAc access.Service, signers ...crypto.Signer) Authentication {
+
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	digest, err := UpdateDigest(nonce, id, newAc)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	auth, err := NewAuthentication(nonce, digest, signers...)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+func authenticateAudit(t *testing.T, caly *Calypso, id []byte,
+	signers ...crypto.Signer) Authentication {
+
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err := NewAuthentication(nonce, AuditDigest(nonce, id), signers...)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+func authenticateRead(t *testing.T, caly *Calypso, id []byte,
+	signers ...crypto.Signer) Authentication {
+
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err := NewAuthentication(nonce, ReadDigest(nonce, id), signers...)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+type fakeError string
+
+func (e fakeError) Error() string {
+	return string(e)
+}
+
+// fakeLedger counts the anchored operations, or refuses them with an error.
+//
+// - implements Ledger
+type fakeLedger struct {
+	writes  int
+	updates int
+	err     error
+}
+
+func (l *fakeLedger) Write(ID []byte, record Record) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	l.writes++
+
+	return nil
+}
+
+func (l *fakeLedger) Update(ID []byte, record Record) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	l.updates++
+
+	return nil
+}
+
+func (l *fakeLedger) Read(ID []byte, auth Authentication, pubk kyber.Point,
+	keyProof []byte) error {
+
+	return l.err
+}
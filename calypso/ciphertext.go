@@ -0,0 +1,92 @@
+package calypso
+
+import (
+	"bytes"
+
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
+)
+
+// ErrInvalidCiphertext is returned when K or C is not a point that the
+// encryption of a message can produce.
+var ErrInvalidCiphertext = xerrors.New("invalid ciphertext")
+
+// cofactor is the cofactor of Ed25519. The points of small order are the ones
+// that it sends to the identity.
+var cofactor = suite.Scalar().SetInt64(8)
+
+// UnmarshalPoint decodes a point of a ciphertext. Contrary to UnmarshalBinary,
+// it only accepts the canonical encoding of the point, so that a ciphertext
+// has a single encoding. It returns an error wrapping ErrInvalidCiphertext
+// otherwise.
+func UnmarshalPoint(buf []byte) (kyber.Point, error) {
+	point := suite.Point()
+
+	err := point.UnmarshalBinary(buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal point: %v: %w", err,
+			ErrInvalidCiphertext)
+	}
+
+	canonical, err := point.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal point: %v", err)
+	}
+
+	if !bytes.Equal(canonical, buf) {
+		return nil, xerrors.Errorf("non-canonical encoding %x: %w", buf,
+			ErrInvalidCiphertext)
+	}
+
+	return point, nil
+}
+
+// ValidateCiphertext returns an error wrapping ErrInvalidCiphertext if K or C
+// is degenerate. Both must be points of the prime order subgroup other than
+// the identity, as K = k*G and C = k*X + M, and K must not be the generator,
+// which reveals that k = 1.
+func ValidateCiphertext(K, C kyber.Point) error {
+	err := validatePoint(K)
+	if err != nil {
+		return xerrors.Errorf("K: %v: %w", err, ErrInvalidCiphertext)
+	}
+
+	if K.Equal(suite.Point().Base()) {
+		return xerrors.Errorf("K: generator point: %w", ErrInvalidCiphertext)
+	}
+
+	err = validatePoint(C)
+	if err != nil {
+		return xerrors.Errorf("C: %v: %w", err, ErrInvalidCiphertext)
+	}
+
+	return nil
+}
+
+// validatePoint returns an error if the point is missing, or if it is not a
+// point of the prime order subgroup other than the identity.
+func validatePoint(point kyber.Point) error {
+	if point == nil {
+		return xerrors.New("missing point")
+	}
+
+	null := suite.Point().Null()
+
+	if point.Equal(null) {
+		return xerrors.New("identity point")
+	}
+
+	if suite.Point().Mul(cofactor, point).Equal(null) {
+		return xerrors.New("small order point")
+	}
+
+	// The scalar -1 is the order of the subgroup minus one, so that only the
+	// points of the subgroup are negated by it.
+	minusOne := suite.Scalar().Neg(suite.Scalar().One())
+
+	if !suite.Point().Mul(minusOne, point).Equal(suite.Point().Neg(point)) {
+		return xerrors.New("point not in the prime order subgroup")
+	}
+
+	return nil
+}
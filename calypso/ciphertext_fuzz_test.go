@@ -0,0 +1,99 @@
+//go:build go1.18
+// +build go1.18
+
+package calypso
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func FuzzValidateCiphertext(f *testing.F) {
+	base, err := suite.Point().Base().MarshalBinary()
+	if err != nil {
+		f.Fatalf("failed to marshal base: %v", err)
+	}
+
+	seeds := []string{identityHex, order2Hex, order4Hex, order8Hex, mixedHex,
+		notOnCurve, negIdentity, yEqualsP, yEqualsPPlus}
+
+	for _, seed := range seeds {
+		buf, err := hex.DecodeString(seed)
+		if err != nil {
+			f.Fatalf("failed to decode seed: %v", err)
+		}
+
+		f.Add(buf, base)
+		f.Add(base, buf)
+	}
+
+	_, K, C, err := calycrypto.Encrypt([]byte("hello"), suite.Point().Base(),
+		random.New())
+	if err != nil {
+		f.Fatalf("failed to encrypt: %v", err)
+	}
+
+	f.Add(marshal(f, K), marshal(f, C))
+
+	// A point P of the prime order subgroup is the only one to be equal to
+	// 8^-1 * (8 * P), as the multiplication by 8 clears the other components.
+	inv := suite.Scalar().Inv(cofactor)
+
+	f.Fuzz(func(t *testing.T, kBuf, cBuf []byte) {
+		K, err := UnmarshalPoint(kBuf)
+		if err != nil {
+			return
+		}
+
+		C, err := UnmarshalPoint(cBuf)
+		if err != nil {
+			return
+		}
+
+		err = ValidateCiphertext(K, C)
+		if err != nil {
+			return
+		}
+
+		if K.Equal(suite.Point().Base()) {
+			t.Fatal("generator accepted")
+		}
+
+		for _, buf := range [][]byte{kBuf, cBuf} {
+			P := decodePoint(t, hex.EncodeToString(buf))
+
+			canonical, err := P.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if !bytes.Equal(buf, canonical) {
+				t.Fatalf("non-canonical encoding accepted: %x", buf)
+			}
+
+			cleared := suite.Point().Mul(cofactor, P)
+
+			if cleared.Equal(suite.Point().Null()) {
+				t.Fatalf("small order point accepted: %x", buf)
+			}
+
+			if !suite.Point().Mul(inv, cleared).Equal(P) {
+				t.Fatalf("point out of the subgroup accepted: %x", buf)
+			}
+		}
+	})
+}
+
+func marshal(f *testing.F, point kyber.Point) []byte {
+	buf, err := point.MarshalBinary()
+	if err != nil {
+		f.Fatalf("failed to marshal: %v", err)
+	}
+
+	return buf
+}
@@ -0,0 +1,210 @@
+package calypso
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+// Encodings of degenerate points of Ed25519. The small order ones are the
+// points of order 2, 4 and 8.
+const (
+	identityHex  = "0100000000000000000000000000000000000000000000000000000000000000"
+	order2Hex    = "ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"
+	order4Hex    = "0000000000000000000000000000000000000000000000000000000000000000"
+	order8Hex    = "26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05"
+	mixedHex     = "0300000000000000000000000000000000000000000000000000000000000000"
+	notOnCurve   = "0200000000000000000000000000000000000000000000000000000000000000"
+	negIdentity  = "0100000000000000000000000000000000000000000000000000000000000080"
+	yEqualsP     = "edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"
+	yEqualsPPlus = "eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"
+)
+
+func TestUnmarshalPoint(t *testing.T) {
+	base, err := suite.Point().Base().MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal base: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		hex  string
+		err  string
+	}{
+		{"canonical", hex.EncodeToString(base), ""},
+		{"small order", order8Hex, ""},
+		{"empty", "", "failed to unmarshal point"},
+		{"short", hex.EncodeToString(base[1:]), "failed to unmarshal point"},
+		{"not on curve", notOnCurve, "failed to unmarshal point"},
+		{"identity with sign", negIdentity, "non-canonical encoding"},
+		{"y equals p", yEqualsP, "non-canonical encoding"},
+		{"y equals p+1", yEqualsPPlus, "non-canonical encoding"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalPoint(decodeHex(t, tc.hex))
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+func TestValidateCiphertext(t *testing.T) {
+	pubkey := suite.Point().Pick(random.New())
+
+	_, K, C, err := calycrypto.Encrypt([]byte("hello"), pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	torsion := decodePoint(t, order8Hex)
+	mixed := suite.Point().Add(K, torsion)
+
+	testCases := []struct {
+		name string
+		K    kyber.Point
+		C    kyber.Point
+		err  string
+	}{
+		{"valid", K, C, ""},
+		{"missing K", nil, C, "K: missing point"},
+		{"missing C", K, nil, "C: missing point"},
+		{"identity K", suite.Point().Null(), C, "K: identity point"},
+		{"identity C", K, suite.Point().Null(), "C: identity point"},
+		{"order 2 K", decodePoint(t, order2Hex), C, "K: small order point"},
+		{"order 4 K", decodePoint(t, order4Hex), C, "K: small order point"},
+		{"order 8 K", torsion, C, "K: small order point"},
+		{"order 8 C", K, torsion, "C: small order point"},
+		{"mixed order K", mixed, C, "K: point not in the prime order subgroup"},
+		{"mixed order C", K, mixed, "C: point not in the prime order subgroup"},
+		{"off subgroup K", decodePoint(t, mixedHex), C,
+			"K: point not in the prime order subgroup"},
+		{"generator K", suite.Point().Base(), C, "K: generator point"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCiphertext(tc.K, tc.C)
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+func TestCalypso_Write_InvalidCiphertext(t *testing.T) {
+	signer := bls.NewSigner()
+
+	ac, err := NewAccess(signer.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	pubkey := suite.Point().Pick(random.New())
+
+	k, K, C, err := calycrypto.Encrypt([]byte("hello"), pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	caly := NewCalypso(nil)
+
+	// A proof for the identity is easy to forge with k = 0, which is what the
+	// validation prevents.
+	zero := suite.Scalar().Zero()
+	null := suite.Point().Null()
+
+	proof, err := NewWriteProof(zero, null, C, nil, time.Time{}, ac)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	_, err = caly.Write(fakeMessage{K: null, C: C, proof: proof}, ac)
+	if !xerrors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected an invalid ciphertext, got: %v", err)
+	}
+
+	proof, err = NewWriteProof(k, K, C, nil, time.Time{}, ac)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	_, err = caly.Write(fakeMessage{K: K, C: C, proof: proof}, ac)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type fakeMessage struct {
+	K     kyber.Point
+	C     kyber.Point
+	proof []byte
+}
+
+func (m fakeMessage) GetK() kyber.Point {
+	return m.K
+}
+
+func (m fakeMessage) GetC() kyber.Point {
+	return m.C
+}
+
+func (m fakeMessage) GetData() []byte {
+	return nil
+}
+
+func (m fakeMessage) GetProof() []byte {
+	return m.proof
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+
+	if expected == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return
+	}
+
+	if !xerrors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected an invalid ciphertext, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected error '%s', got: %v", expected, err)
+	}
+}
+
+func decodeHex(t *testing.T, str string) []byte {
+	t.Helper()
+
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		t.Fatalf("failed to decode hex: %v", err)
+	}
+
+	return buf
+}
+
+func decodePoint(t *testing.T, str string) kyber.Point {
+	t.Helper()
+
+	point := suite.Point()
+
+	err := point.UnmarshalBinary(decodeHex(t, str))
+	if err != nil {
+		t.Fatalf("failed to unmarshal point: %v", err)
+	}
+
+	return point
+}
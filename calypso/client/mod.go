@@ -0,0 +1,552 @@
+// Package client implements a client for the JSON API that a Calypso node
+// exposes on its proxy. The messages are encrypted locally with the
+// collective public key, which is fetched once and then cached, so that the
+// node never sees the secrets in clear before they are read.
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+// suite is the Kyber suite for Pedersen.
+var suite = calycrypto.Suite
+
+// Error is the error returned when the node answers a request with a failure.
+// It wraps the matching Calypso error when there is one, so that it can be
+// tested with xerrors.Is, for instance against calypso.ErrNotFound.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("calypso: %d %s: %s", e.StatusCode,
+		http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap returns the Calypso error that matches the status code, or nil.
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return calypso.ErrNotFound
+	case http.StatusUnauthorized:
+		return calypso.ErrUnauthenticated
+	case http.StatusForbidden:
+		return calypso.ErrAccessDenied
+	case http.StatusConflict:
+		return calypso.ErrAlreadyExists
+	case http.StatusGone:
+		return calypso.ErrExpired
+	default:
+		return nil
+	}
+}
+
+// Secret is a message encrypted with the collective public key, alongside the
+// access control it will be written with.
+type Secret struct {
+	K     kyber.Point
+	C     kyber.Point
+	Data  []byte
+	Proof []byte
+	// Admin is the identity allowed to update and read the record, as a
+	// public key in its text form such as "bls:<hex>".
+	Admin string
+	// Readers are the identities allowed to read the record, in the same
+	// form.
+	Readers []string
+	// Expiry is the time after which the record can't be read anymore, or
+	// zero if it never expires. It is bound to the proof, see EncryptUntil.
+	Expiry time.Time
+}
+
+// Option is the type of option to create a client.
+type Option func(*Client)
+
+// WithHTTPClient is an option to set the HTTP client used to send the
+// requests. By default, http.DefaultClient is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// Client is a client of the Calypso API of a node.
+type Client struct {
+	sync.Mutex
+
+	addr   string
+	http   *http.Client
+	pubkey kyber.Point
+}
+
+// NewClient creates a new client for the node's proxy at the address, for
+// example "http://127.0.0.1:8081".
+func NewClient(addr string, opts ...Option) *Client {
+	c := &Client{
+		addr: strings.TrimSuffix(addr, "/"),
+		http: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetPublicKey returns the collective public key. It is fetched from the node
+// the first time only.
+func (c *Client) GetPublicKey() (kyber.Point, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.pubkey != nil {
+		return c.pubkey, nil
+	}
+
+	var res api.PubkeyResponse
+	err := c.do(http.MethodGet, "/pubkey", nil, &res)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get public key: %w", err)
+	}
+
+	pubkey, err := decodePoint(res.PublicKey)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid public key: %v", err)
+	}
+
+	c.pubkey = pubkey
+
+	return pubkey, nil
+}
+
+// Encrypt encrypts the message with the collective public key and creates the
+// proof that the admin and the readers are the intended access control.
+// Messages that don't fit in a point are sealed with a symmetric key.
+func (c *Client) Encrypt(message []byte, admin string,
+	readers ...string) (Secret, error) {
+
+	return c.EncryptUntil(message, time.Time{}, admin, readers...)
+}
+
+// EncryptUntil is like Encrypt for a record that can't be read anymore after
+// the expiry. The expiry is bound to the proof, so that the secret must be
+// written with it.
+func (c *Client) EncryptUntil(message []byte, expiry time.Time, admin string,
+	readers ...string) (Secret, error) {
+
+	pubkey, err := c.GetPublicKey()
+	if err != nil {
+		return Secret{}, xerrors.Errorf("failed to get public key: %w", err)
+	}
+
+	ac, err := newAccess(admin, readers)
+	if err != nil {
+		return Secret{}, xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	k, env, err := calycrypto.EncryptHybrid(message, pubkey, random.New())
+	if err != nil {
+		return Secret{}, xerrors.Errorf("failed to encrypt: %v", err)
+	}
+
+	proof, err := calypso.NewWriteProof(k, env.K, env.C, env.Data, expiry, ac)
+	if err != nil {
+		return Secret{}, xerrors.Errorf("failed to create proof: %v", err)
+	}
+
+	secret := Secret{
+		K:       env.K,
+		C:       env.C,
+		Data:    env.Data,
+		Proof:   proof,
+		Admin:   admin,
+		Readers: readers,
+		Expiry:  expiry,
+	}
+
+	return secret, nil
+}
+
+// Write writes the secret and returns the ID of its record.
+func (c *Client) Write(secret Secret) ([]byte, error) {
+	kHex, err := encodePoint(secret.K)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid K: %v", err)
+	}
+
+	cHex, err := encodePoint(secret.C)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid C: %v", err)
+	}
+
+	req := api.WriteRequest{
+		K:       kHex,
+		C:       cHex,
+		Data:    hex.EncodeToString(secret.Data),
+		Proof:   hex.EncodeToString(secret.Proof),
+		Admin:   secret.Admin,
+		Readers: secret.Readers,
+	}
+
+	if !secret.Expiry.IsZero() {
+		req.Expiry = &secret.Expiry
+	}
+
+	var res api.WriteResponse
+	err = c.do(http.MethodPost, "/write", req, &res)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to write: %w", err)
+	}
+
+	id, err := hex.DecodeString(res.ID)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid ID: %v", err)
+	}
+
+	return id, nil
+}
+
+// EncryptAndWrite encrypts the message and writes it. It returns the ID of its
+// record.
+func (c *Client) EncryptAndWrite(message []byte, admin string,
+	readers ...string) ([]byte, error) {
+
+	secret, err := c.Encrypt(message, admin, readers...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encrypt: %w", err)
+	}
+
+	id, err := c.Write(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// Challenge returns a new nonce issued by the node for the record, which the
+// identities include in the digest they sign to authenticate a read or an
+// update of the record.
+func (c *Client) Challenge(id []byte) ([]byte, error) {
+	req := api.ChallengeRequest{
+		ID: hex.EncodeToString(id),
+	}
+
+	var res api.ChallengeResponse
+	err := c.do(http.MethodPost, "/challenge", req, &res)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get challenge: %w", err)
+	}
+
+	nonce, err := hex.DecodeString(res.Nonce)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid nonce: %v", err)
+	}
+
+	return nonce, nil
+}
+
+// Read returns the decrypted message of the record if one of the identities
+// of the signers is allowed to read it. The signers answer a challenge of the
+// node to prove that they hold the identities.
+func (c *Client) Read(id []byte, signers ...crypto.Signer) ([]byte, error) {
+	nonce, err := c.Challenge(id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read: %w", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		signers...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	nonceHex, idents, err := api.EncodeAuthentication(auth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode authentication: %v", err)
+	}
+
+	req := api.ReadRequest{
+		ID:         hex.EncodeToString(id),
+		Nonce:      nonceHex,
+		Identities: idents,
+	}
+
+	var res api.ReadResponse
+	err = c.do(http.MethodPost, "/read", req, &res)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read: %w", err)
+	}
+
+	msg, err := hex.DecodeString(res.Message)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid message: %v", err)
+	}
+
+	return msg, nil
+}
+
+// ReadReencrypted returns the message of the record, which the node
+// re-encrypts under the public key of the reader's private key xc so that no
+// node sees it in clear. The signers must be allowed to read the record.
+func (c *Client) ReadReencrypted(id []byte, xc kyber.Scalar,
+	signers ...crypto.Signer) ([]byte, error) {
+
+	X, err := c.GetPublicKey()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read: %w", err)
+	}
+
+	nonce, err := c.Challenge(id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read: %w", err)
+	}
+
+	pubk := suite.Point().Mul(xc, nil)
+
+	keyProof, err := calypso.NewReaderKeyProof(xc, pubk, nonce, id)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to prove reader key: %v", err)
+	}
+
+	digest, err := calypso.ReencryptDigest(nonce, id, pubk, keyProof)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signers...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	nonceHex, idents, err := api.EncodeAuthentication(auth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode authentication: %v", err)
+	}
+
+	pubkBuf, err := pubk.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal public key: %v", err)
+	}
+
+	req := api.ReadReencryptedRequest{
+		ID:         hex.EncodeToString(id),
+		PublicKey:  hex.EncodeToString(pubkBuf),
+		KeyProof:   hex.EncodeToString(keyProof),
+		Nonce:      nonceHex,
+		Identities: idents,
+	}
+
+	var res api.ReadReencryptedResponse
+	err = c.do(http.MethodPost, "/read-reencrypted", req, &res)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read: %w", err)
+	}
+
+	secret, err := res.Decode()
+	if err != nil {
+		return nil, xerrors.Errorf("invalid response: %v", err)
+	}
+
+	msg, err := calypso.DecryptReencrypted(secret, xc, X)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt: %v", err)
+	}
+
+	return msg, nil
+}
+
+// UpdateAccess replaces the access control of the record by the admin and the
+// readers, if the identity of the signer is allowed to update it.
+func (c *Client) UpdateAccess(id []byte, signer crypto.Signer, admin string,
+	readers ...string) error {
+
+	ac, err := newAccess(admin, readers)
+	if err != nil {
+		return xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	nonce, err := c.Challenge(id)
+	if err != nil {
+		return xerrors.Errorf("failed to update access: %w", err)
+	}
+
+	digest, err := calypso.UpdateDigest(nonce, id, ac)
+	if err != nil {
+		return xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signer)
+	if err != nil {
+		return xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	nonceHex, idents, err := api.EncodeAuthentication(auth)
+	if err != nil {
+		return xerrors.Errorf("failed to encode authentication: %v", err)
+	}
+
+	req := api.UpdateAccessRequest{
+		ID:       hex.EncodeToString(id),
+		Nonce:    nonceHex,
+		Identity: idents[0],
+		Admin:    admin,
+		Readers:  readers,
+	}
+
+	err = c.do(http.MethodPost, "/update-access", req, nil)
+	if err != nil {
+		return xerrors.Errorf("failed to update access: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecord returns the metadata of the record.
+func (c *Client) GetRecord(id []byte) (api.RecordResponse, error) {
+	var res api.RecordResponse
+	err := c.do(http.MethodGet, "/records/"+hex.EncodeToString(id), nil, &res)
+	if err != nil {
+		return res, xerrors.Errorf("failed to get record: %w", err)
+	}
+
+	return res, nil
+}
+
+// do sends the request with the body encoded in JSON, if any, and decodes the
+// response into res, if any. It returns an *Error when the node answers with
+// a failure.
+func (c *Client) do(method, path string, body, res interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return xerrors.Errorf("failed to marshal request: %v", err)
+		}
+
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, c.addr+api.Prefix+path, reader)
+	if err != nil {
+		return xerrors.Errorf("failed to create request: %v", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return xerrors.Errorf("failed to send request: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return xerrors.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errRes api.ErrorResponse
+		err = json.Unmarshal(data, &errRes)
+		if err != nil || errRes.Message == "" {
+			errRes.Message = string(data)
+		}
+
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Message:    errRes.Message,
+		}
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return xerrors.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	return nil
+}
+
+// newAccess returns the access control of the admin and the readers, as the
+// node will create it, so that the proof can be verified.
+func newAccess(admin string, readers []string) (access.Service, error) {
+	if admin == "" {
+		return nil, xerrors.New("admin identity is empty")
+	}
+
+	owner, err := calypso.ParseIdentity(admin)
+	if err != nil {
+		return nil, xerrors.Errorf("admin: %v", err)
+	}
+
+	readIDs := make([]access.Identity, len(readers))
+	for i, reader := range readers {
+		readIDs[i], err = calypso.ParseIdentity(reader)
+		if err != nil {
+			return nil, xerrors.Errorf("reader %d: %v", i, err)
+		}
+	}
+
+	ac, err := calypso.NewAccess(owner, readIDs...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	return ac, nil
+}
+
+func decodePoint(str string) (kyber.Point, error) {
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode hex: %v", err)
+	}
+
+	point := suite.Point()
+	err = point.UnmarshalBinary(buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal point: %v", err)
+	}
+
+	return point, nil
+}
+
+func encodePoint(point kyber.Point) (string, error) {
+	if point == nil {
+		return "", xerrors.New("point is nil")
+	}
+
+	buf, err := point.MarshalBinary()
+	if err != nil {
+		return "", xerrors.Errorf("failed to marshal point: %v", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
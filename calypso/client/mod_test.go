@@ -0,0 +1,425 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/dkg"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+func TestClient_GetPublicKey(t *testing.T) {
+	actor := newFakeActor()
+	srv, calls := newServer(actor)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	for i := 0; i < 2; i++ {
+		pubkey, err := client.GetPublicKey()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !pubkey.Equal(actor.pubkey) {
+			t.Fatalf("wrong public key: %v", pubkey)
+		}
+	}
+
+	if atomic.LoadInt32(calls) != 1 {
+		t.Fatalf("public key fetched %d times", *calls)
+	}
+}
+
+func TestClient_WriteRead(t *testing.T) {
+	srv, _ := newServer(newFakeActor())
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	messages := [][]byte{
+		[]byte("short secret"),
+		bytes.Repeat([]byte("a long secret "), 10),
+	}
+
+	for _, message := range messages {
+		id, err := client.EncryptAndWrite(message, identity(t, alice),
+			identity(t, bob))
+		if err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+
+		msg, err := client.Read(id, bob)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+
+		if !bytes.Equal(msg, message) {
+			t.Fatalf("wrong message: %q", msg)
+		}
+
+		record, err := client.GetRecord(id)
+		if err != nil {
+			t.Fatalf("failed to get record: %v", err)
+		}
+
+		hybrid := len(message) > suite.Point().EmbedLen()
+		if record.Hybrid != hybrid {
+			t.Fatalf("wrong hybrid flag for %d bytes", len(message))
+		}
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv, _ := newServer(newFakeActor())
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	secret, err := client.Encrypt([]byte("secret"), identity(t, alice))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	id, err := client.Write(secret)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	_, err = client.Write(secret)
+	expectError(t, err, http.StatusConflict, calypso.ErrAlreadyExists)
+
+	_, err = client.Read(id, eve)
+	expectError(t, err, http.StatusForbidden, calypso.ErrAccessDenied)
+
+	_, err = client.Read([]byte{0xaa}, alice)
+	expectError(t, err, http.StatusNotFound, calypso.ErrNotFound)
+
+	secret.Admin = identity(t, eve)
+	_, err = client.Write(secret)
+	expectError(t, err, http.StatusBadRequest, nil)
+
+	secret.Admin = "alice"
+	_, err = client.Write(secret)
+	expectError(t, err, http.StatusBadRequest, nil)
+}
+
+func TestClient_Unauthenticated(t *testing.T) {
+	srv, _ := newServer(newFakeActor())
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	id, err := client.EncryptAndWrite([]byte("secret"), identity(t, alice))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	nonce, err := client.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	// Eve claims to be Alice with her own signature.
+	auth, err := calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		eve)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	auth.Identities[0].PublicKey = alice.GetPublicKey()
+
+	err = sendRead(client, id, auth)
+	expectError(t, err, http.StatusUnauthorized, calypso.ErrUnauthenticated)
+
+	// The nonce is only used once the signatures are verified, so that a
+	// forged request doesn't lock the identity out, but it can't be used
+	// twice.
+	auth, err = calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = sendRead(client, id, auth)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	err = sendRead(client, id, auth)
+	expectError(t, err, http.StatusUnauthorized, calypso.ErrUnauthenticated)
+
+	// The signature of another record is refused.
+	nonce, err = client.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err = calypso.NewAuthentication(nonce,
+		calypso.ReadDigest(nonce, []byte{0xaa}), alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = sendRead(client, id, auth)
+	expectError(t, err, http.StatusUnauthorized, calypso.ErrUnauthenticated)
+
+	// The nonce of another record is refused.
+	nonce, err = client.Challenge([]byte{0xaa})
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err = calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = sendRead(client, id, auth)
+	expectError(t, err, http.StatusUnauthorized, calypso.ErrUnauthenticated)
+
+	// A forged nonce is refused.
+	nonce[len(nonce)-1] ^= 1
+
+	auth, err = calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = sendRead(client, id, auth)
+	expectError(t, err, http.StatusUnauthorized, calypso.ErrUnauthenticated)
+}
+
+func TestClient_Expiry(t *testing.T) {
+	srv, _ := newServer(newFakeActor())
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	secret, err := client.EncryptUntil([]byte("secret"),
+		time.Now().Add(-time.Second), identity(t, alice))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	_, err = client.Write(secret)
+	expectError(t, err, http.StatusBadRequest, nil)
+
+	secret, err = client.EncryptUntil([]byte("secret"),
+		time.Now().Add(100*time.Millisecond), identity(t, alice))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	// The expiry and the data are bound to the proof, so that a copy of the
+	// secret can't take its ID with other ones.
+	copied := secret
+	copied.Expiry = secret.Expiry.Add(-50 * time.Millisecond)
+	_, err = client.Write(copied)
+	expectError(t, err, http.StatusBadRequest, nil)
+
+	copied = secret
+	copied.Expiry = time.Time{}
+	_, err = client.Write(copied)
+	expectError(t, err, http.StatusBadRequest, nil)
+
+	copied = secret
+	copied.Data = []byte("other data")
+	_, err = client.Write(copied)
+	expectError(t, err, http.StatusBadRequest, nil)
+
+	id, err := client.Write(secret)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	record, err := client.GetRecord(id)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+
+	if record.Expiry == nil || !record.Expiry.Equal(secret.Expiry) {
+		t.Fatalf("unexpected expiry: %v", record.Expiry)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	_, err = client.Read(id, alice)
+	expectError(t, err, http.StatusGone, calypso.ErrExpired)
+}
+
+func TestClient_UpdateAccess(t *testing.T) {
+	srv, _ := newServer(newFakeActor())
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	id, err := client.EncryptAndWrite([]byte("secret"), identity(t, alice),
+		identity(t, bob))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	err = client.UpdateAccess(id, bob, identity(t, bob))
+	expectError(t, err, http.StatusForbidden, calypso.ErrAccessDenied)
+
+	err = client.UpdateAccess(id, alice, identity(t, alice), identity(t, carol))
+	if err != nil {
+		t.Fatalf("failed to update access: %v", err)
+	}
+
+	_, err = client.Read(id, bob)
+	expectError(t, err, http.StatusForbidden, calypso.ErrAccessDenied)
+
+	_, err = client.Read(id, carol)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+}
+
+func TestClient_Unavailable(t *testing.T) {
+	srv, _ := newServer(fakeActor{})
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	_, err := client.Encrypt([]byte("secret"), identity(t, alice))
+	expectError(t, err, http.StatusServiceUnavailable, nil)
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// The identities of the tests use both algorithms.
+var (
+	alice = bls.NewSigner()
+	bob   = ed25519.NewSigner()
+	carol = bls.NewSigner()
+	eve   = ed25519.NewSigner()
+)
+
+func identity(t *testing.T, signer crypto.Signer) string {
+	t.Helper()
+
+	text, err := signer.GetPublicKey().MarshalText()
+	if err != nil {
+		t.Fatalf("failed to marshal identity: %v", err)
+	}
+
+	return string(text)
+}
+
+// sendRead sends the read request of the authentication as is.
+func sendRead(client *Client, id []byte, auth calypso.Authentication) error {
+	nonce, idents, err := api.EncodeAuthentication(auth)
+	if err != nil {
+		return err
+	}
+
+	req := api.ReadRequest{
+		ID:         hex.EncodeToString(id),
+		Nonce:      nonce,
+		Identities: idents,
+	}
+
+	return client.do(http.MethodPost, "/read", req, &api.ReadResponse{})
+}
+
+func expectError(t *testing.T, err error, code int, target error) {
+	t.Helper()
+
+	var apiErr *Error
+	if !xerrors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+
+	if apiErr.StatusCode != code {
+		t.Fatalf("expected status %d, got %d", code, apiErr.StatusCode)
+	}
+
+	if target != nil && !xerrors.Is(err, target) {
+		t.Fatalf("expected %v, got %v", target, err)
+	}
+}
+
+// newServer returns a server with the Calypso API, and the counter of the
+// requests for the public key.
+func newServer(actor dkg.Actor) (*httptest.Server, *int32) {
+	mux := http.NewServeMux()
+	api.NewCtrl(calypso.NewCalypso(actor)).Register(mux.HandleFunc)
+
+	calls := new(int32)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		if r.URL.Path == api.Prefix+"/pubkey" {
+			atomic.AddInt32(calls, 1)
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+
+	return srv, calls
+}
+
+// fakeActor is a DKG actor that holds the whole private key. A zero actor has
+// not been setup.
+//
+// - implements dkg.Actor
+type fakeActor struct {
+	secret kyber.Scalar
+	pubkey kyber.Point
+}
+
+func newFakeActor() fakeActor {
+	secret := suite.Scalar().Pick(suite.RandomStream())
+
+	return fakeActor{
+		secret: secret,
+		pubkey: suite.Point().Mul(secret, nil),
+	}
+}
+
+func (a fakeActor) Setup(crypto.CollectiveAuthority, int) (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) GetPublicKey() (kyber.Point, error) {
+	if a.pubkey == nil {
+		return nil, xerrors.New("setup not done")
+	}
+
+	return a.pubkey, nil
+}
+
+func (a fakeActor) Encrypt(message []byte) (K, C kyber.Point, remainder []byte,
+	err error) {
+
+	_, K, C, err = calycrypto.Encrypt(message, a.pubkey, random.New())
+
+	return K, C, nil, err
+}
+
+func (a fakeActor) Decrypt(K, C kyber.Point) ([]byte, error) {
+	return calycrypto.Decrypt(a.secret, K, C)
+}
+
+func (a fakeActor) Reshare() error {
+	return nil
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/dkg"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minoch"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	// The messages of the DKG are sent in JSON through minoch.
+	_ "go.dedis.ch/dela-apps/calypso/dkg/pedersen/json"
+)
+
+func TestClient_ReadReencrypted(t *testing.T) {
+	actor := setupDKG(t, 4, 3)
+
+	srv, _ := newServer(actor)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	messages := [][]byte{
+		[]byte("short secret"),
+		bytes.Repeat([]byte("a long secret "), 10),
+	}
+
+	for _, message := range messages {
+		id, err := client.EncryptAndWrite(message, identity(t, alice),
+			identity(t, bob))
+		if err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+
+		xc := suite.Scalar().Pick(random.New())
+
+		msg, err := client.ReadReencrypted(id, xc, bob)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+
+		if !bytes.Equal(msg, message) {
+			t.Fatalf("wrong message: %q", msg)
+		}
+
+		_, err = client.ReadReencrypted(id, xc, eve)
+		expectError(t, err, http.StatusForbidden, calypso.ErrAccessDenied)
+	}
+}
+
+func TestClient_ReadReencrypted_ReaderKey(t *testing.T) {
+	actor := setupDKG(t, 4, 3)
+
+	srv, _ := newServer(actor)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	idA, err := client.EncryptAndWrite([]byte("A"), identity(t, alice),
+		identity(t, bob))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	idB, err := client.EncryptAndWrite([]byte("B"), identity(t, alice))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// Bob asks the secret of A under K_B - K_A, which would give him the
+	// shared secret of B.
+	K := make([]kyber.Point, 2)
+	for i, id := range [][]byte{idA, idB} {
+		record, err := client.GetRecord(id)
+		if err != nil {
+			t.Fatalf("failed to get record: %v", err)
+		}
+
+		K[i], err = decodePoint(record.K)
+		if err != nil {
+			t.Fatalf("failed to decode K: %v", err)
+		}
+	}
+
+	forged := suite.Point().Sub(K[1], K[0])
+
+	xc := suite.Scalar().Pick(random.New())
+	pubk := suite.Point().Mul(xc, nil)
+
+	torsion := suite.Point()
+	err = torsion.UnmarshalBinary([]byte{
+		0x26, 0xe8, 0x95, 0x8f, 0xc2, 0xb2, 0x27, 0xb0,
+		0x45, 0xc3, 0xf4, 0x89, 0xf2, 0xef, 0x98, 0xf0,
+		0xd5, 0xdf, 0xac, 0x05, 0xd3, 0xc6, 0x33, 0x39,
+		0xb1, 0x38, 0x02, 0x88, 0x6d, 0x53, 0xfc, 0x05,
+	})
+	if err != nil {
+		t.Fatalf("failed to unmarshal torsion point: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		pubk  kyber.Point
+		proof func(nonce []byte) []byte
+	}{
+		{"forged key", forged, func(nonce []byte) []byte {
+			return readerKeyProof(t, xc, forged, nonce, idA)
+		}},
+		{"small order key", torsion, func(nonce []byte) []byte {
+			return readerKeyProof(t, suite.Scalar().Zero(), torsion, nonce, idA)
+		}},
+		{"mixed key", suite.Point().Add(pubk, torsion), func(nonce []byte) []byte {
+			return readerKeyProof(t, xc, suite.Point().Add(pubk, torsion), nonce, idA)
+		}},
+		{"proof of another record", pubk, func(nonce []byte) []byte {
+			return readerKeyProof(t, xc, pubk, nonce, idB)
+		}},
+		{"proof of another nonce", pubk, func(nonce []byte) []byte {
+			return readerKeyProof(t, xc, pubk, []byte("other nonce"), idA)
+		}},
+		{"missing proof", pubk, func(nonce []byte) []byte {
+			return nil
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nonce, err := client.Challenge(idA)
+			if err != nil {
+				t.Fatalf("failed to get challenge: %v", err)
+			}
+
+			err = sendReadReencrypted(client, idA, tc.pubk, tc.proof(nonce),
+				nonce, bob)
+			expectError(t, err, http.StatusBadRequest, nil)
+
+			if !strings.Contains(err.Error(), calypso.ErrInvalidReaderKey.Error()) {
+				t.Fatalf("expected an invalid reader key, got: %v", err)
+			}
+		})
+	}
+
+	// The same request with the proof of the key is served.
+	nonce, err := client.Challenge(idA)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	err = sendReadReencrypted(client, idA, pubk,
+		readerKeyProof(t, xc, pubk, nonce, idA), nonce, bob)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+}
+
+// setupDKG runs the setup of the pedersen DKG on n nodes connected through
+// minoch, and returns the actor of the first one.
+func setupDKG(t *testing.T, n, threshold int) dkg.Actor {
+	t.Helper()
+
+	manager := minoch.NewManager()
+
+	addrs := make([]mino.Address, n)
+	pubkeys := make([]crypto.PublicKey, n)
+	actors := make([]dkg.Actor, n)
+
+	for i := 0; i < n; i++ {
+		m := minoch.MustCreate(manager, fmt.Sprintf("node%d", i))
+
+		p, pubkey, err := pedersen.NewPedersen(m, pedersen.NewInMemoryStorage())
+		if err != nil {
+			t.Fatalf("failed to create pedersen: %v", err)
+		}
+
+		actors[i], err = p.Listen()
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+
+		addrs[i] = m.GetAddress()
+		pubkeys[i] = ed25519.NewPublicKeyFromPoint(pubkey)
+	}
+
+	_, err := actors[0].Setup(authority.New(addrs, pubkeys), threshold)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+
+	return actors[0]
+}
+
+func readerKeyProof(t *testing.T, xc kyber.Scalar, pubk kyber.Point, nonce,
+	id []byte) []byte {
+
+	t.Helper()
+
+	proof, err := calypso.NewReaderKeyProof(xc, pubk, nonce, id)
+	if err != nil {
+		t.Fatalf("failed to prove reader key: %v", err)
+	}
+
+	return proof
+}
+
+// sendReadReencrypted sends the re-encrypted read request of the key and its
+// proof as is, signed by the signers.
+func sendReadReencrypted(client *Client, id []byte, pubk kyber.Point,
+	keyProof, nonce []byte, signers ...crypto.Signer) error {
+
+	digest, err := calypso.ReencryptDigest(nonce, id, pubk, keyProof)
+	if err != nil {
+		return err
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signers...)
+	if err != nil {
+		return err
+	}
+
+	nonceHex, idents, err := api.EncodeAuthentication(auth)
+	if err != nil {
+		return err
+	}
+
+	pubkHex, err := encodePoint(pubk)
+	if err != nil {
+		return err
+	}
+
+	req := api.ReadReencryptedRequest{
+		ID:         hex.EncodeToString(id),
+		PublicKey:  pubkHex,
+		KeyProof:   hex.EncodeToString(keyProof),
+		Nonce:      nonceHex,
+		Identities: idents,
+	}
+
+	return client.do(http.MethodPost, "/read-reencrypted", req,
+		&api.ReadReencryptedResponse{})
+}
@@ -0,0 +1,210 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela/core/execution/native"
+	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/txn/pool"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
+)
+
+// defaultTimeout is the default time to wait for a transaction to be
+// committed.
+const defaultTimeout = 30 * time.Second
+
+// LedgerOption is the type of option to create a ledger.
+type LedgerOption func(*Ledger)
+
+// WithTimeout is an option to set the time to wait for a transaction to be
+// committed.
+func WithTimeout(timeout time.Duration) LedgerOption {
+	return func(l *Ledger) {
+		l.timeout = timeout
+	}
+}
+
+// Ledger anchors the Calypso records with the transactions of the contract,
+// signed by the transaction manager of the node.
+//
+// - implements calypso.Ledger
+type Ledger struct {
+	sync.Mutex
+
+	srvc    ordering.Service
+	pool    pool.Pool
+	mgr     txn.Manager
+	context serde.Context
+	timeout time.Duration
+	synced  bool
+}
+
+// NewLedger creates a new ledger that adds the transactions to the pool, and
+// waits for the ordering service to commit them.
+func NewLedger(srvc ordering.Service, p pool.Pool, mgr txn.Manager,
+	opts ...LedgerOption) *Ledger {
+
+	l := &Ledger{
+		srvc:    srvc,
+		pool:    p,
+		mgr:     mgr,
+		context: json.NewContext(),
+		timeout: defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Write implements calypso.Ledger. It sends a WRITE transaction with the
+// record.
+func (l *Ledger) Write(id []byte, record calypso.Record) error {
+	data, err := record.Serialize(l.context)
+	if err != nil {
+		return xerrors.Errorf("failed to serialize record: %v", err)
+	}
+
+	return l.send(
+		txn.Arg{Key: CmdArg, Value: []byte(CmdWrite)},
+		txn.Arg{Key: RecordArg, Value: data},
+	)
+}
+
+// Update implements calypso.Ledger. It sends an UPDATE transaction with the
+// record, which carries the authentication of its updates.
+func (l *Ledger) Update(id []byte, record calypso.Record) error {
+	data, err := record.Serialize(l.context)
+	if err != nil {
+		return xerrors.Errorf("failed to serialize record: %v", err)
+	}
+
+	return l.send(
+		txn.Arg{Key: CmdArg, Value: []byte(CmdUpdate)},
+		txn.Arg{Key: RecordArg, Value: data},
+	)
+}
+
+// Read implements calypso.Ledger. It sends a READ transaction with the
+// authentication of the read, and the key of the reader if any.
+func (l *Ledger) Read(id []byte, auth calypso.Authentication, pubk kyber.Point,
+	keyProof []byte) error {
+
+	authData, err := calypso.EncodeAuthentication(auth)
+	if err != nil {
+		return xerrors.Errorf("failed to encode authentication: %v", err)
+	}
+
+	args := []txn.Arg{
+		{Key: CmdArg, Value: []byte(CmdRead)},
+		{Key: IDArg, Value: []byte(hex.EncodeToString(id))},
+		{Key: AuthArg, Value: authData},
+	}
+
+	if pubk != nil {
+		pubkBuf, err := pubk.MarshalBinary()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal reader key: %v", err)
+		}
+
+		args = append(args,
+			txn.Arg{Key: PubkeyArg, Value: []byte(hex.EncodeToString(pubkBuf))},
+			txn.Arg{Key: KeyProofArg, Value: []byte(hex.EncodeToString(keyProof))})
+	}
+
+	return l.send(args...)
+}
+
+// send adds a transaction of the contract with the arguments to the pool, and
+// waits until it is committed.
+func (l *Ledger) send(args ...txn.Arg) error {
+	// Start listening for new transactions before sending the new one, to be
+	// sure the event will be received.
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	events := l.srvc.Watch(ctx)
+
+	tx, err := l.add(args...)
+	if err != nil {
+		return err
+	}
+
+	for event := range events {
+		for _, res := range event.Transactions {
+			if !bytes.Equal(res.GetTransaction().GetID(), tx.GetID()) {
+				continue
+			}
+
+			accepted, msg := res.GetStatus()
+			if !accepted {
+				l.desync()
+				return xerrors.Errorf("transaction refused: %s", msg)
+			}
+
+			dela.Logger.Debug().Hex("id", tx.GetID()).
+				Msg("calypso transaction committed")
+
+			return nil
+		}
+	}
+
+	l.desync()
+
+	return xerrors.Errorf("transaction %#x not committed after %v", tx.GetID(),
+		l.timeout)
+}
+
+// add creates the transaction and adds it to the pool. The manager is
+// synchronized with the ledger the first time, and then increments the nonce
+// of each transaction, so that the transactions of the node can be pending at
+// the same time.
+func (l *Ledger) add(args ...txn.Arg) (txn.Transaction, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.synced {
+		err := l.mgr.Sync()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to sync manager: %v", err)
+		}
+
+		l.synced = true
+	}
+
+	args = append([]txn.Arg{{Key: native.ContractArg, Value: []byte(ContractName)}},
+		args...)
+
+	tx, err := l.mgr.Make(args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to make transaction: %v", err)
+	}
+
+	err = l.pool.Add(tx)
+	if err != nil {
+		l.synced = false
+		return nil, xerrors.Errorf("failed to add transaction: %v", err)
+	}
+
+	return tx, nil
+}
+
+// desync makes the manager synchronize again before the next transaction, as
+// the nonce of a transaction that is not committed is not used.
+func (l *Ledger) desync() {
+	l.Lock()
+	l.synced = false
+	l.Unlock()
+}
@@ -0,0 +1,314 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/core/execution"
+	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/txn/pool"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestLedger_Calypso(t *testing.T) {
+	actor := newFakeActor()
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+	chain := newFakeChain(NewContract(fac))
+
+	ledger := NewLedger(chain, chain, signed.NewManager(eve, chain))
+	caly := calypso.NewCalypso(actor, calypso.WithStorage(inmemory.NewInMemory()),
+		calypso.WithLedger(ledger))
+
+	record, _ := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	id, err := caly.Write(record, record.GetAccess())
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if !chain.snap.has(recordKey(id)) {
+		t.Fatal("write not anchored")
+	}
+
+	// The record is refused by the contract as it already exists.
+	_, err = calypso.NewCalypso(actor, calypso.WithLedger(ledger)).
+		Write(record, record.GetAccess())
+	checkError(t, err, "transaction refused")
+
+	msg, err := caly.Read(id, authenticateChallenge(t, caly, id, bob))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	if !bytes.Equal(msg, []byte("secret")) {
+		t.Fatalf("wrong message: %q", msg)
+	}
+
+	// The secret of the committed read is re-encrypted under the key of the
+	// reader only.
+	xc := suite.Scalar().Pick(random.New())
+	pubk := suite.Point().Mul(xc, nil)
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	proof, err := calypso.NewReaderKeyProof(xc, pubk, nonce, id)
+	if err != nil {
+		t.Fatalf("failed to create key proof: %v", err)
+	}
+
+	digest, err := calypso.ReencryptDigest(nonce, id, pubk, proof)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, bob)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	_, err = caly.ReadReencrypted(id, pubk, proof, auth)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	secret, err := ReadCommitted(chain.snap, fac, actor, chain.last.GetID())
+	if err != nil {
+		t.Fatalf("failed to read committed: %v", err)
+	}
+
+	msg, err = calypso.DecryptReencrypted(secret, xc, actor.pubkey)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+
+	if !bytes.Equal(msg, []byte("secret")) {
+		t.Fatalf("wrong message: %q", msg)
+	}
+
+	// The update of the access control is anchored, and the reads follow it.
+	ac, err := calypso.NewAccess(eve.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	nonce, _, err = caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	digest, err = calypso.UpdateDigest(nonce, id, ac)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	auth, err = calypso.NewAuthentication(nonce, digest, alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = caly.UpdateAccess(id, auth, ac)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	anchored, err := GetRecord(chain.snap, fac, id)
+	if err != nil || anchored.GetVersion() != 1 {
+		t.Fatalf("update not anchored: %v", err)
+	}
+
+	_, err = caly.Read(id, authenticateChallenge(t, caly, id, eve))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+}
+
+func TestLedger_Refused(t *testing.T) {
+	actor := newFakeActor()
+	chain := newFakeChain(NewContract(calypso.NewRecordFactory(arc.NewFactory())))
+	ledger := NewLedger(chain, chain, signed.NewManager(eve, chain))
+
+	record, _ := makeRecord(t, actor, []byte("secret"), time.Time{})
+	id := recordID(t, record)
+
+	err := ledger.Read(id, authenticateRead(t, id, bob), nil, nil)
+	checkError(t, err, "transaction refused: failed to READ")
+
+	// The manager is synchronized again after a refused transaction, as its
+	// nonce has not been used.
+	err = ledger.Write(id, record)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if chain.syncs != 2 {
+		t.Fatalf("manager synchronized %d times", chain.syncs)
+	}
+
+	chain.err = fakeError("pool is full")
+
+	err = ledger.Write(id, record)
+	checkError(t, err, "failed to add transaction: pool is full")
+}
+
+func TestLedger_Timeout(t *testing.T) {
+	actor := newFakeActor()
+	chain := newFakeChain(NewContract(calypso.NewRecordFactory(arc.NewFactory())))
+	chain.drop = true
+
+	ledger := NewLedger(chain, chain, signed.NewManager(eve, chain),
+		WithTimeout(10*time.Millisecond))
+
+	record, _ := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	err := ledger.Write(recordID(t, record), record)
+	checkError(t, err, "not committed after 10ms")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// authenticateChallenge returns the authentication of the read of the record
+// by the signer, with a challenge of Calypso.
+func authenticateChallenge(t *testing.T, caly *calypso.Calypso, id []byte,
+	signer crypto.Signer) calypso.Authentication {
+
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		signer)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+type fakeError string
+
+func (e fakeError) Error() string {
+	return string(e)
+}
+
+// fakeChain executes the transactions of the pool with the contract as soon as
+// they are added, and notifies the watchers of the results.
+//
+// - implements ordering.Service
+// - implements pool.Pool
+// - implements signed.Client
+type fakeChain struct {
+	ordering.Service
+	pool.Pool
+
+	sync.Mutex
+
+	contract Contract
+	snap     fakeSnapshot
+	watchers []chan ordering.Event
+	index    uint64
+	nonce    uint64
+	syncs    int
+	last     txn.Transaction
+	drop     bool
+	err      error
+}
+
+func newFakeChain(contract Contract) *fakeChain {
+	return &fakeChain{
+		contract: contract,
+		snap:     newFakeSnapshot(),
+	}
+}
+
+func (c *fakeChain) Close() error {
+	return nil
+}
+
+func (c *fakeChain) Watch(ctx context.Context) <-chan ordering.Event {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan ordering.Event, 10)
+	c.watchers = append(c.watchers, ch)
+
+	go func() {
+		<-ctx.Done()
+
+		c.Lock()
+		defer c.Unlock()
+
+		for i, w := range c.watchers {
+			if w == ch {
+				c.watchers = append(c.watchers[:i], c.watchers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (c *fakeChain) Add(tx txn.Transaction) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.drop {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	res := simple.NewTransactionResult(tx, true, "")
+
+	err := c.contract.Execute(c.snap, execution.Step{Current: tx})
+	if err != nil {
+		res = simple.NewTransactionResult(tx, false, err.Error())
+	} else {
+		c.nonce = tx.GetNonce() + 1
+		c.last = tx
+	}
+
+	c.index++
+
+	event := ordering.Event{
+		Index:        c.index,
+		Transactions: []validation.TransactionResult{res},
+	}
+
+	for _, w := range c.watchers {
+		w <- event
+	}
+
+	return nil
+}
+
+func (c *fakeChain) GetNonce(access.Identity) (uint64, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.syncs++
+
+	return c.nonce, nil
+}
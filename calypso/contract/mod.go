@@ -0,0 +1,475 @@
+// Package contract implements a native contract that anchors the Calypso
+// records in the ledger. The writes, the updates and the reads are
+// transactions ordered by the consensus, so that the reads leave a trace and
+// the secrets are only released once a read has been committed.
+//
+// The reads are authenticated by the identities of the access control, as
+// for Calypso, rather than by the signer of the transaction, which is usually
+// the node that anchors them. A committed read only releases the secret
+// re-encrypted under the key of the reader.
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela/core/execution"
+	"go.dedis.ch/dela/core/execution/native"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+	"golang.org/x/xerrors"
+)
+
+// suite is the Kyber suite of the keys of the readers.
+var suite = suites.MustFind("Ed25519")
+
+const (
+	// ContractName is the name of the contract.
+	ContractName = "go.dedis.ch/dela.Calypso"
+
+	// CmdArg is the argument's name to indicate the kind of command we want to
+	// run on the contract. Should be one of the Command type.
+	CmdArg = "calypso:command"
+
+	// RecordArg is the argument's name in the transaction that contains the
+	// serialized record to write.
+	RecordArg = "calypso:record"
+
+	// IDArg is the argument's name in the transaction that contains the ID of
+	// the record to read, as a hex string.
+	IDArg = "calypso:id"
+
+	// AuthArg is the argument's name in the transaction that contains the
+	// authentication of the read, as encoded by calypso.EncodeAuthentication.
+	AuthArg = "calypso:auth"
+
+	// PubkeyArg is the argument's name in the transaction that contains the
+	// public key of the reader, as a hex string. The secret of the record is
+	// released re-encrypted under that key.
+	PubkeyArg = "calypso:pubkey"
+
+	// KeyProofArg is the argument's name in the transaction that contains the
+	// proof that the reader knows the private key of its public key, as a hex
+	// string.
+	KeyProofArg = "calypso:keyproof"
+)
+
+// Command defines a type of command for the Calypso contract
+type Command string
+
+const (
+	// CmdWrite defines the command to write a record
+	CmdWrite Command = "WRITE"
+
+	// CmdRead defines the command to request the read of a record
+	CmdRead Command = "READ"
+
+	// CmdUpdate defines the command to update the access control of a record
+	CmdUpdate Command = "UPDATE"
+)
+
+// The prefixes are prepended to the IDs before hashing them to compute the
+// keys of the entries in the store, so that the entries of the contract can't
+// collide with each other or with the ones of other contracts, and the keys
+// stay within the 32 bytes of the store.
+var (
+	recordPrefix = []byte("calypso:record:")
+	readPrefix   = []byte("calypso:read:")
+	readerPrefix = []byte("calypso:reader:")
+	noncePrefix  = []byte("calypso:nonce:")
+)
+
+// RegisterContract registers the Calypso contract to the given execution
+// service.
+func RegisterContract(exec *native.Service, c Contract) {
+	exec.Set(ContractName, c)
+}
+
+// Contract is a smart contract that stores the Calypso records and verifies
+// the read requests against their access control.
+//
+// - implements native.Contract
+type Contract struct {
+	fac       serde.Factory
+	context   serde.Context
+	validator calypso.ReplicaValidator
+}
+
+// NewContract creates a new Calypso contract. The factory is used to
+// deserialize the records from the transactions and the store.
+func NewContract(fac serde.Factory) Contract {
+	return Contract{
+		fac:       fac,
+		context:   json.NewContext(),
+		validator: calypso.NewReplicaValidator(),
+	}
+}
+
+// Execute implements native.Contract. It runs the appropriate command.
+func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
+	cmd := step.Current.GetArg(CmdArg)
+	if len(cmd) == 0 {
+		return xerrors.Errorf("'%s' not found in tx arg", CmdArg)
+	}
+
+	switch Command(cmd) {
+	case CmdWrite:
+		err := c.write(snap, step)
+		if err != nil {
+			return xerrors.Errorf("failed to WRITE: %v", err)
+		}
+	case CmdRead:
+		err := c.read(snap, step)
+		if err != nil {
+			return xerrors.Errorf("failed to READ: %v", err)
+		}
+	case CmdUpdate:
+		err := c.update(snap, step)
+		if err != nil {
+			return xerrors.Errorf("failed to UPDATE: %v", err)
+		}
+	default:
+		return xerrors.Errorf("unknown command: %s", cmd)
+	}
+
+	return nil
+}
+
+// write stores the record of the transaction under the key of its ID. A record
+// can only be written once.
+func (c Contract) write(snap store.Snapshot, step execution.Step) error {
+	data := step.Current.GetArg(RecordArg)
+	if len(data) == 0 {
+		return xerrors.Errorf("'%s' not found in tx arg", RecordArg)
+	}
+
+	record, err := c.decodeRecord(data)
+	if err != nil {
+		return xerrors.Errorf("failed to decode record: %v", err)
+	}
+
+	id, err := calypso.RecordID(record.GetK(), record.GetC())
+	if err != nil {
+		return xerrors.Errorf("failed to compute ID: %v", err)
+	}
+
+	if record.GetVersion() != 0 {
+		return xerrors.Errorf("record %#x has already been updated", id)
+	}
+
+	err = calypso.ValidateRecord(id, record)
+	if err != nil {
+		return xerrors.Errorf("invalid record: %v", err)
+	}
+
+	existing, err := snap.Get(recordKey(id))
+	if err != nil {
+		return xerrors.Errorf("failed to read store: %v", err)
+	}
+
+	if len(existing) > 0 {
+		return xerrors.Errorf("record %#x already exists", id)
+	}
+
+	err = snap.Set(recordKey(id), data)
+	if err != nil {
+		return xerrors.Errorf("failed to set record: %v", err)
+	}
+
+	dela.Logger.Info().Str("contract", ContractName).Msgf("record %x written", id)
+
+	return nil
+}
+
+// update replaces the record of the transaction if it is a newer version of
+// the one stored, whose updates are authenticated by the admins.
+func (c Contract) update(snap store.Snapshot, step execution.Step) error {
+	data := step.Current.GetArg(RecordArg)
+	if len(data) == 0 {
+		return xerrors.Errorf("'%s' not found in tx arg", RecordArg)
+	}
+
+	record, err := c.decodeRecord(data)
+	if err != nil {
+		return xerrors.Errorf("failed to decode record: %v", err)
+	}
+
+	id, err := calypso.RecordID(record.GetK(), record.GetC())
+	if err != nil {
+		return xerrors.Errorf("failed to compute ID: %v", err)
+	}
+
+	prev, err := GetRecord(snap, c.fac, id)
+	if err != nil {
+		return xerrors.Errorf("failed to get record: %v", err)
+	}
+
+	err = c.validator.ValidateUpdate(id, prev, record)
+	if err != nil {
+		return xerrors.Errorf("invalid update: %v", err)
+	}
+
+	err = snap.Set(recordKey(id), data)
+	if err != nil {
+		return xerrors.Errorf("failed to set record: %v", err)
+	}
+
+	dela.Logger.Info().Str("contract", ContractName).
+		Msgf("record %x updated to version %d", id, record.GetVersion())
+
+	return nil
+}
+
+// read verifies that the identities of the authentication are allowed to read
+// the record, and stores the ID of the record and the key of the reader at
+// the ID of the transaction, so that the secret can be released once the
+// transaction is committed. The nonce of the authentication can only be used
+// once, but its expiry is verified by the node that issued it, as the time is
+// only known outside of the execution of the transactions.
+func (c Contract) read(snap store.Snapshot, step execution.Step) error {
+	idHex := step.Current.GetArg(IDArg)
+	if len(idHex) == 0 {
+		return xerrors.Errorf("'%s' not found in tx arg", IDArg)
+	}
+
+	id, err := hex.DecodeString(string(idHex))
+	if err != nil {
+		return xerrors.Errorf("failed to decode ID: %v", err)
+	}
+
+	authData := step.Current.GetArg(AuthArg)
+	if len(authData) == 0 {
+		return xerrors.Errorf("'%s' not found in tx arg", AuthArg)
+	}
+
+	auth, err := calypso.DecodeAuthentication(authData)
+	if err != nil {
+		return xerrors.Errorf("failed to decode authentication: %v", err)
+	}
+
+	pubk, keyProof, err := decodeReaderKey(step)
+	if err != nil {
+		return xerrors.Errorf("failed to decode reader key: %v", err)
+	}
+
+	digest := calypso.ReadDigest(auth.Nonce, id)
+
+	if pubk != nil {
+		err = calypso.VerifyReaderKey(keyProof, pubk, auth.Nonce, id)
+		if err != nil {
+			return xerrors.Errorf("failed to verify reader key: %v", err)
+		}
+
+		digest, err = calypso.ReencryptDigest(auth.Nonce, id, pubk, keyProof)
+		if err != nil {
+			return xerrors.Errorf("failed to compute digest: %v", err)
+		}
+	}
+
+	idents, err := calypso.VerifyIdentities(auth, digest)
+	if err != nil {
+		return xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	record, err := GetRecord(snap, c.fac, id)
+	if err != nil {
+		return xerrors.Errorf("failed to get record: %v", err)
+	}
+
+	creds := calypso.NewCredential(id, calypso.ArcRuleRead)
+
+	err = record.GetAccess().Match(snap, creds, idents...)
+	if err != nil {
+		return xerrors.Errorf("identities not authorized: %v", err)
+	}
+
+	used, err := snap.Get(nonceKey(auth.Nonce))
+	if err != nil {
+		return xerrors.Errorf("failed to read store: %v", err)
+	}
+
+	if len(used) > 0 {
+		return xerrors.Errorf("nonce %#x has already been used", auth.Nonce)
+	}
+
+	err = snap.Set(nonceKey(auth.Nonce), []byte{1})
+	if err != nil {
+		return xerrors.Errorf("failed to set nonce: %v", err)
+	}
+
+	err = snap.Set(readKey(step.Current.GetID()), id)
+	if err != nil {
+		return xerrors.Errorf("failed to set read: %v", err)
+	}
+
+	if pubk != nil {
+		pubkBuf, err := pubk.MarshalBinary()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal reader key: %v", err)
+		}
+
+		err = snap.Set(readerKey(step.Current.GetID()), pubkBuf)
+		if err != nil {
+			return xerrors.Errorf("failed to set reader key: %v", err)
+		}
+	}
+
+	dela.Logger.Info().Str("contract", ContractName).
+		Msgf("read of %x granted to %v", id, idents)
+
+	return nil
+}
+
+// decodeReaderKey returns the key of the reader and its proof, or nil if the
+// transaction reads the message itself.
+func decodeReaderKey(step execution.Step) (kyber.Point, []byte, error) {
+	pubkHex := step.Current.GetArg(PubkeyArg)
+	if len(pubkHex) == 0 {
+		return nil, nil, nil
+	}
+
+	pubkBuf, err := hex.DecodeString(string(pubkHex))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode key: %v", err)
+	}
+
+	pubk := suite.Point()
+
+	err = pubk.UnmarshalBinary(pubkBuf)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+
+	keyProof, err := hex.DecodeString(string(step.Current.GetArg(KeyProofArg)))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode proof: %v", err)
+	}
+
+	return pubk, keyProof, nil
+}
+
+func (c Contract) decodeRecord(data []byte) (calypso.Record, error) {
+	msg, err := c.fac.Deserialize(c.context, data)
+	if err != nil {
+		return calypso.Record{}, xerrors.Errorf("failed to deserialize: %v", err)
+	}
+
+	record, ok := msg.(calypso.Record)
+	if !ok {
+		return calypso.Record{}, xerrors.Errorf("invalid record '%T'", msg)
+	}
+
+	return record, nil
+}
+
+// GetRecord returns the record stored at the ID, or an error wrapping
+// calypso.ErrNotFound if it doesn't exist.
+func GetRecord(ledger store.Readable, fac serde.Factory,
+	id []byte) (calypso.Record, error) {
+
+	data, err := ledger.Get(recordKey(id))
+	if err != nil {
+		return calypso.Record{}, xerrors.Errorf("failed to read store: %v", err)
+	}
+
+	if len(data) == 0 {
+		return calypso.Record{}, xerrors.Errorf("%#x: %w", id,
+			calypso.ErrNotFound)
+	}
+
+	return NewContract(fac).decodeRecord(data)
+}
+
+// ReadCommitted returns the secret of the record targeted by a committed read
+// transaction, re-encrypted under the key of the reader of the transaction.
+// The access has been verified by the contract when the transaction was
+// executed. It returns an error wrapping calypso.ErrNotFound if no read has
+// been committed for the transaction ID, calypso.ErrAccessDenied if the read
+// has no reader key, or calypso.ErrExpired if the record has expired since.
+func ReadCommitted(ledger store.Readable, fac serde.Factory,
+	reencrypter calypso.Reencrypter, txID []byte) (calypso.Reencrypted, error) {
+
+	id, err := ledger.Get(readKey(txID))
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("failed to read store: %v", err)
+	}
+
+	if len(id) == 0 {
+		return calypso.Reencrypted{}, xerrors.Errorf("no read for tx %#x: %w",
+			txID, calypso.ErrNotFound)
+	}
+
+	pubkBuf, err := ledger.Get(readerKey(txID))
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("failed to read store: %v", err)
+	}
+
+	// The message of a read without a reader key has been returned by the
+	// node that anchored it, and is not released again.
+	if len(pubkBuf) == 0 {
+		return calypso.Reencrypted{}, xerrors.Errorf("read of tx %#x has no "+
+			"reader key: %w", txID, calypso.ErrAccessDenied)
+	}
+
+	pubk := suite.Point()
+
+	err = pubk.UnmarshalBinary(pubkBuf)
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("failed to unmarshal "+
+			"reader key: %v", err)
+	}
+
+	record, err := GetRecord(ledger, fac, id)
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("failed to get record: %w", err)
+	}
+
+	// The ledger keeps the expired records, which are refused here as the
+	// time is only known outside of the execution of the transactions.
+	if record.IsExpired(time.Now()) {
+		return calypso.Reencrypted{}, xerrors.Errorf("%#x: %w", id,
+			calypso.ErrExpired)
+	}
+
+	res, err := calypso.ReencryptRecord(reencrypter, record, pubk)
+	if err != nil {
+		return calypso.Reencrypted{}, err
+	}
+
+	return res, nil
+}
+
+// recordKey returns the key of the record with the given ID in the store.
+func recordKey(id []byte) []byte {
+	return hashKey(recordPrefix, id)
+}
+
+// readKey returns the key of the read of the given transaction in the store.
+func readKey(txID []byte) []byte {
+	return hashKey(readPrefix, txID)
+}
+
+// readerKey returns the key of the public key of the reader of the given
+// transaction in the store.
+func readerKey(txID []byte) []byte {
+	return hashKey(readerPrefix, txID)
+}
+
+// nonceKey returns the key of a nonce that has been used in the store.
+func nonceKey(nonce []byte) []byte {
+	return hashKey(noncePrefix, nonce)
+}
+
+func hashKey(prefix, id []byte) []byte {
+	h := sha256.New()
+	h.Write(prefix)
+	h.Write(id)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,647 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela/core/execution"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+
+	// The records are serialized in JSON in the transactions and the store.
+	_ "go.dedis.ch/dela-apps/calypso/json"
+)
+
+var (
+	alice = bls.NewSigner()
+	bob   = bls.NewSigner()
+	eve   = bls.NewSigner()
+)
+
+func TestContract_Write(t *testing.T) {
+	actor := newFakeActor()
+	contract := NewContract(calypso.NewRecordFactory(arc.NewFactory()))
+	snap := newFakeSnapshot()
+
+	record, data := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	err := contract.Execute(snap, writeStep(t, alice, data))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	id := recordID(t, record)
+
+	// The record is stored under a key of the namespace of the contract, and
+	// never under its raw ID.
+	if snap.has(id) {
+		t.Fatal("record stored under its raw ID")
+	}
+
+	if !snap.has(recordKey(id)) {
+		t.Fatal("record not stored under its key")
+	}
+
+	// A record can only be written once, even by another identity.
+	err = contract.Execute(snap, writeStep(t, eve, data))
+	checkError(t, err, "already exists")
+
+	err = contract.Execute(snap, writeStep(t, alice, nil))
+	checkError(t, err, "'calypso:record' not found in tx arg")
+
+	err = contract.Execute(snap, writeStep(t, alice, []byte("{}")))
+	checkError(t, err, "failed to decode record")
+}
+
+func TestContract_Write_InvalidProof(t *testing.T) {
+	actor := newFakeActor()
+	contract := NewContract(calypso.NewRecordFactory(arc.NewFactory()))
+
+	record, _ := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	// The proof of another record doesn't bind this one.
+	other, _ := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	forged := calypso.NewRecord(record.GetK(), record.GetC(),
+		record.GetAccess(), calypso.WithProof(other.GetProof()))
+
+	err := contract.Execute(newFakeSnapshot(), writeStep(t, alice,
+		serialize(t, forged)))
+	checkError(t, err, "failed to verify proof")
+}
+
+func TestContract_Read(t *testing.T) {
+	actor := newFakeActor()
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+	contract := NewContract(fac)
+	snap := newFakeSnapshot()
+
+	record, data := makeRecord(t, actor, []byte("secret"), time.Time{})
+
+	err := contract.Execute(snap, writeStep(t, alice, data))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	id := recordID(t, record)
+
+	// Only the admin and the readers are allowed to read.
+	step := readStep(t, alice, id, authenticateRead(t, id, eve))
+	err = contract.Execute(snap, step)
+	checkError(t, err, "identities not authorized")
+
+	_, err = ReadCommitted(snap, fac, actor, step.Current.GetID())
+	checkErrorIs(t, err, calypso.ErrNotFound)
+
+	// The reads are authenticated by the identities of the access control,
+	// whoever signs the transaction.
+	xc := suite.Scalar().Pick(random.New())
+
+	for _, signer := range []crypto.Signer{alice, bob} {
+		step = readStep(t, eve, id, authenticateRead(t, id, signer), xc)
+
+		err = contract.Execute(snap, step)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+
+		secret, err := ReadCommitted(snap, fac, actor, step.Current.GetID())
+		if err != nil {
+			t.Fatalf("failed to read committed: %v", err)
+		}
+
+		msg, err := calypso.DecryptReencrypted(secret, xc, actor.pubkey)
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+
+		if !bytes.Equal(msg, []byte("secret")) {
+			t.Fatalf("wrong message: %q", msg)
+		}
+	}
+
+	// The nonce of an authentication can only be used once.
+	auth := authenticateRead(t, id, bob)
+
+	step = readStep(t, eve, id, auth)
+
+	err = contract.Execute(snap, step)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	err = contract.Execute(snap, readStep(t, eve, id, auth))
+	checkError(t, err, "has already been used")
+
+	// The message of a read without a reader key is not released again.
+	_, err = ReadCommitted(snap, fac, actor, step.Current.GetID())
+	checkErrorIs(t, err, calypso.ErrAccessDenied)
+
+	// The signature of bob doesn't authenticate alice.
+	forged := authenticateRead(t, id, bob)
+	forged.Identities[0].PublicKey = alice.GetPublicKey()
+
+	err = contract.Execute(snap, readStep(t, eve, id, forged))
+	checkError(t, err, "failed to authenticate")
+
+	// The identities sign the key of the reader.
+	err = contract.Execute(snap, readStep(t, eve, id, authenticateRead(t, id, bob),
+		xc))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	auth = authenticateRead(t, id, bob)
+
+	err = contract.Execute(snap, makeStep(t, eve, readArgs(t, id, auth,
+		signed.WithArg(PubkeyArg, []byte(hex.EncodeToString(pubkey(t, xc)))),
+		signed.WithArg(KeyProofArg, []byte(hex.EncodeToString(keyProof(t, xc,
+			auth.Nonce, id)))))...))
+	checkError(t, err, "failed to authenticate")
+
+	err = contract.Execute(snap, readStep(t, eve, []byte{0xaa},
+		authenticateRead(t, []byte{0xaa}, alice)))
+	checkError(t, err, "record not found")
+
+	badCases := []struct {
+		name string
+		opts []signed.TransactionOption
+		err  string
+	}{
+		{"no id", []signed.TransactionOption{
+			signed.WithArg(CmdArg, []byte(CmdRead))},
+			"'calypso:id' not found in tx arg"},
+		{"bad id", []signed.TransactionOption{
+			signed.WithArg(CmdArg, []byte(CmdRead)),
+			signed.WithArg(IDArg, []byte("zz"))}, "failed to decode ID"},
+		{"no auth", []signed.TransactionOption{
+			signed.WithArg(CmdArg, []byte(CmdRead)),
+			signed.WithArg(IDArg, []byte("aa"))}, "'calypso:auth' not found in tx arg"},
+		{"bad auth", []signed.TransactionOption{
+			signed.WithArg(CmdArg, []byte(CmdRead)),
+			signed.WithArg(IDArg, []byte("aa")),
+			signed.WithArg(AuthArg, []byte("zz"))}, "failed to decode authentication"},
+		{"bad key", readArgs(t, id, authenticateRead(t, id, bob),
+			signed.WithArg(PubkeyArg, []byte("zz"))), "failed to decode reader key"},
+		{"bad proof", readArgs(t, id, authenticateRead(t, id, bob),
+			signed.WithArg(PubkeyArg, []byte(hex.EncodeToString(pubkey(t, xc)))),
+			signed.WithArg(KeyProofArg, []byte("00"))), "failed to verify reader key"},
+		{"unknown command", []signed.TransactionOption{
+			signed.WithArg(CmdArg, []byte("UNKNOWN"))}, "unknown command: UNKNOWN"},
+		{"no command", nil, "'calypso:command' not found in tx arg"},
+	}
+
+	for _, tc := range badCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := contract.Execute(snap, makeStep(t, alice, tc.opts...))
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+func TestContract_Update(t *testing.T) {
+	actor := newFakeActor()
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+	contract := NewContract(fac)
+	snap := newFakeSnapshot()
+
+	record, data := makeRecord(t, actor, []byte("secret"), time.Time{})
+	id := recordID(t, record)
+
+	updated := updateRecord(t, record, eve)
+
+	err := contract.Execute(snap, updateStep(t, alice, serialize(t, updated)))
+	checkError(t, err, "record not found")
+
+	// A record is written before its updates.
+	err = contract.Execute(snap, writeStep(t, alice, serialize(t, updated)))
+	checkError(t, err, "has already been updated")
+
+	err = contract.Execute(snap, writeStep(t, alice, data))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// The update is only valid for the access control signed by the admin.
+	forged := calypso.NewRecord(record.GetK(), record.GetC(), record.GetAccess(),
+		calypso.WithProof(record.GetProof()),
+		calypso.WithUpdates(calypso.NewAccessUpdate(record.GetAccess(),
+			updated.GetUpdates()[0].GetProof())))
+
+	err = contract.Execute(snap, updateStep(t, alice, serialize(t, forged)))
+	checkError(t, err, "invalid update")
+
+	err = contract.Execute(snap, updateStep(t, bob, serialize(t, updated)))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	stored, err := GetRecord(snap, fac, id)
+	if err != nil || stored.GetVersion() != 1 {
+		t.Fatalf("update not stored: %v", err)
+	}
+
+	// The reads are verified against the updated access control.
+	err = contract.Execute(snap, readStep(t, alice, id, authenticateRead(t, id, bob)))
+	checkError(t, err, "identities not authorized")
+
+	err = contract.Execute(snap, readStep(t, alice, id, authenticateRead(t, id, eve)))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	err = contract.Execute(snap, updateStep(t, alice, serialize(t, updated)))
+	checkError(t, err, "version 1 is not newer than 1")
+
+	err = contract.Execute(snap, updateStep(t, alice, nil))
+	checkError(t, err, "'calypso:record' not found in tx arg")
+
+	err = contract.Execute(snap, updateStep(t, alice, []byte("{}")))
+	checkError(t, err, "failed to decode record")
+}
+
+func TestReadCommitted_Expired(t *testing.T) {
+	actor := newFakeActor()
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+	contract := NewContract(fac)
+	snap := newFakeSnapshot()
+
+	// The time is not known when the transactions are executed, so an expired
+	// record can still be written and its read committed.
+	record, data := makeRecord(t, actor, []byte("secret"),
+		time.Now().Add(-time.Minute))
+
+	err := contract.Execute(snap, writeStep(t, alice, data))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	id := recordID(t, record)
+	xc := suite.Scalar().Pick(random.New())
+
+	step := readStep(t, bob, id, authenticateRead(t, id, bob), xc)
+
+	err = contract.Execute(snap, step)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	_, err = ReadCommitted(snap, fac, actor, step.Current.GetID())
+	checkErrorIs(t, err, calypso.ErrExpired)
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// makeRecord returns a record of the message readable by bob and administered
+// by alice, and its serialization.
+func makeRecord(t *testing.T, actor fakeActor, message []byte,
+	expiry time.Time) (calypso.Record, []byte) {
+
+	t.Helper()
+
+	ac, err := calypso.NewAccess(alice.GetPublicKey(), bob.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	k, K, C, err := calycrypto.Encrypt(message, actor.pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	proof, err := calypso.NewWriteProof(k, K, C, nil, expiry, ac)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	record := calypso.NewRecord(K, C, ac, calypso.WithProof(proof),
+		calypso.WithExpiry(expiry))
+
+	return record, serialize(t, record)
+}
+
+func serialize(t *testing.T, record calypso.Record) []byte {
+	t.Helper()
+
+	data, err := record.Serialize(json.NewContext())
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+
+	return data
+}
+
+func recordID(t *testing.T, record calypso.Record) []byte {
+	t.Helper()
+
+	id, err := calypso.RecordID(record.GetK(), record.GetC())
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	return id
+}
+
+func writeStep(t *testing.T, signer crypto.Signer, data []byte) execution.Step {
+	return makeStep(t, signer,
+		signed.WithArg(CmdArg, []byte(CmdWrite)),
+		signed.WithArg(RecordArg, data))
+}
+
+func updateStep(t *testing.T, signer crypto.Signer, data []byte) execution.Step {
+	return makeStep(t, signer,
+		signed.WithArg(CmdArg, []byte(CmdUpdate)),
+		signed.WithArg(RecordArg, data))
+}
+
+// readStep returns the step of a read authenticated by auth, in a transaction
+// of the signer. The secret is released under the key of xc if it is given.
+func readStep(t *testing.T, signer crypto.Signer, id []byte,
+	auth calypso.Authentication, xc ...kyber.Scalar) execution.Step {
+
+	var opts []signed.TransactionOption
+
+	for _, x := range xc {
+		proof := keyProof(t, x, auth.Nonce, id)
+
+		opts = append(opts,
+			signed.WithArg(PubkeyArg, []byte(hex.EncodeToString(pubkey(t, x)))),
+			signed.WithArg(KeyProofArg, []byte(hex.EncodeToString(proof))))
+
+		// The identities sign the key of the reader.
+		digest, err := calypso.ReencryptDigest(auth.Nonce, id,
+			suite.Point().Mul(x, nil), proof)
+		if err != nil {
+			t.Fatalf("failed to compute digest: %v", err)
+		}
+
+		auth = resign(t, auth, digest)
+	}
+
+	return makeStep(t, signer, readArgs(t, id, auth, opts...)...)
+}
+
+func readArgs(t *testing.T, id []byte, auth calypso.Authentication,
+	opts ...signed.TransactionOption) []signed.TransactionOption {
+
+	t.Helper()
+
+	data, err := calypso.EncodeAuthentication(auth)
+	if err != nil {
+		t.Fatalf("failed to encode authentication: %v", err)
+	}
+
+	return append([]signed.TransactionOption{
+		signed.WithArg(CmdArg, []byte(CmdRead)),
+		signed.WithArg(IDArg, []byte(hex.EncodeToString(id))),
+		signed.WithArg(AuthArg, data),
+	}, opts...)
+}
+
+// signers are the signers of the authentications, so that they can sign
+// another digest with the same nonce.
+var signers = []crypto.Signer{alice, bob, eve}
+
+// authenticateRead returns the authentication of the read of the record by the
+// signer, with a new nonce.
+func authenticateRead(t *testing.T, id []byte,
+	signer crypto.Signer) calypso.Authentication {
+
+	t.Helper()
+
+	nonce := make([]byte, 32)
+	random.Bytes(nonce, random.New())
+
+	auth, err := calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		signer)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+// resign returns the authentication with the digest signed by the same
+// identities.
+func resign(t *testing.T, auth calypso.Authentication,
+	digest []byte) calypso.Authentication {
+
+	t.Helper()
+
+	var ss []crypto.Signer
+	for _, ident := range auth.Identities {
+		for _, signer := range signers {
+			if signer.GetPublicKey().Equal(ident.PublicKey) {
+				ss = append(ss, signer)
+			}
+		}
+	}
+
+	auth, err := calypso.NewAuthentication(auth.Nonce, digest, ss...)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+func pubkey(t *testing.T, xc kyber.Scalar) []byte {
+	t.Helper()
+
+	data, err := suite.Point().Mul(xc, nil).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return data
+}
+
+func keyProof(t *testing.T, xc kyber.Scalar, nonce, id []byte) []byte {
+	t.Helper()
+
+	proof, err := calypso.NewReaderKeyProof(xc, suite.Point().Mul(xc, nil),
+		nonce, id)
+	if err != nil {
+		t.Fatalf("failed to create key proof: %v", err)
+	}
+
+	return proof
+}
+
+// updateRecord returns the record with its access control updated by alice
+// to the admin.
+func updateRecord(t *testing.T, record calypso.Record,
+	admin crypto.Signer) calypso.Record {
+
+	t.Helper()
+
+	local := inmemory.NewInMemory()
+	caly := calypso.NewCalypso(nil, calypso.WithStorage(local))
+
+	id, err := caly.Write(record, record.GetAccess(),
+		calypso.ExpireAt(record.GetExpiry()))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	ac, err := calypso.NewAccess(admin.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	digest, err := calypso.UpdateDigest(nonce, id, ac)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, alice)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	err = caly.UpdateAccess(id, auth, ac)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	value, err := local.Read(id)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	return value.(calypso.Record)
+}
+
+// nonce makes the ID of each transaction unique.
+var nonce uint64
+
+func makeStep(t *testing.T, signer crypto.Signer,
+	opts ...signed.TransactionOption) execution.Step {
+
+	t.Helper()
+
+	nonce++
+
+	tx, err := signed.NewTransaction(nonce, signer.GetPublicKey(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+
+	return execution.Step{Current: tx}
+}
+
+func checkError(t *testing.T, err error, substr string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", substr)
+	}
+
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func checkErrorIs(t *testing.T, err, target error) {
+	t.Helper()
+
+	if !xerrors.Is(err, target) {
+		t.Fatalf("expected %v, got: %v", target, err)
+	}
+}
+
+// fakeSnapshot is an in-memory snapshot of the store.
+//
+// - implements store.Snapshot
+type fakeSnapshot struct {
+	entries map[string][]byte
+}
+
+func newFakeSnapshot() fakeSnapshot {
+	return fakeSnapshot{
+		entries: make(map[string][]byte),
+	}
+}
+
+func (s fakeSnapshot) Get(key []byte) ([]byte, error) {
+	return s.entries[string(key)], nil
+}
+
+func (s fakeSnapshot) Set(key, value []byte) error {
+	s.entries[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (s fakeSnapshot) Delete(key []byte) error {
+	delete(s.entries, string(key))
+	return nil
+}
+
+func (s fakeSnapshot) has(key []byte) bool {
+	_, found := s.entries[string(key)]
+	return found
+}
+
+// fakeActor is a DKG actor that holds the whole private key.
+//
+// - implements dkg.Actor
+// - implements calypso.Reencrypter
+type fakeActor struct {
+	secret kyber.Scalar
+	pubkey kyber.Point
+}
+
+func newFakeActor() fakeActor {
+	secret := suite.Scalar().Pick(suite.RandomStream())
+
+	return fakeActor{
+		secret: secret,
+		pubkey: suite.Point().Mul(secret, nil),
+	}
+}
+
+func (a fakeActor) Setup(crypto.CollectiveAuthority, int) (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) GetPublicKey() (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) Encrypt(message []byte) (K, C kyber.Point, remainder []byte,
+	err error) {
+
+	_, K, C, err = calycrypto.Encrypt(message, a.pubkey, random.New())
+
+	return K, C, nil, err
+}
+
+func (a fakeActor) Decrypt(K, C kyber.Point) ([]byte, error) {
+	return calycrypto.Decrypt(a.secret, K, C)
+}
+
+func (a fakeActor) Reshare() error {
+	return nil
+}
+
+func (a fakeActor) Reencrypt(K, pubk kyber.Point) (kyber.Point, error) {
+	return suite.Point().Mul(a.secret, suite.Point().Add(K, pubk)), nil
+}
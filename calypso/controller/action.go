@@ -3,21 +3,43 @@ package controller
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
+	"io/ioutil"
+	"os"
 	"strings"
+	"time"
 
+	"go.dedis.ch/dela"
 	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela-apps/calypso/audit"
+	"go.dedis.ch/dela-apps/calypso/contract"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
 	guictrl "go.dedis.ch/dela-apps/calypso/controller/gui/controllers"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated"
+	"go.dedis.ch/dela/cli"
 	"go.dedis.ch/dela/cli/node"
-	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/txn/pool"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/dkg"
 	"go.dedis.ch/dela/mino"
 	"go.dedis.ch/dela/mino/proxy"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
 	"golang.org/x/xerrors"
 )
 
-// listenAction is an action that starts DKG. Must be called on each node.
+// listenAction is an action that starts DKG and Calypso. Must be called on
+// each node.
 //
 // - implements node.ActionTemplate
 type listenAction struct{}
@@ -34,50 +56,124 @@ func (a listenAction) Execute(ctx node.Context) error {
 		return xerrors.Errorf("failed to listen dkg: %v", err)
 	}
 
-	ctx.Injector.Inject(actor)
+	err = startCalypso(ctx.Injector, actor)
+	if err != nil {
+		return xerrors.Errorf("failed to start calypso: %v", err)
+	}
+
+	return nil
+}
+
+// startCalypso creates Calypso on top of the DKG actor and the storage, and
+// injects both.
+func startCalypso(inj node.Injector, actor dkg.Actor) error {
+	var storage storage.KeyValue
+	err := inj.Resolve(&storage)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve storage: %v", err)
+	}
+
+	var auditLog audit.Log
+	err = inj.Resolve(&auditLog)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve audit log: %v", err)
+	}
+
+	opts := []calypso.Option{
+		calypso.WithStorage(storage),
+		calypso.WithAuditLog(auditLog),
+	}
+
+	ledger, err := newLedger(inj)
+	if err == nil {
+		opts = append(opts, calypso.WithLedger(ledger))
+	} else {
+		dela.Logger.Warn().Msgf("calypso not anchored in the ledger: %v", err)
+	}
+
+	caly := calypso.NewCalypso(actor, opts...)
+
+	inj.Inject(actor)
+	inj.Inject(caly)
 
 	return nil
 }
 
-// registerAction is an action that registers the handlers to the dela proxy
+// newLedger returns the ledger where Calypso anchors the records and their
+// reads with the transactions of the contract, signed by the node.
+func newLedger(inj node.Injector) (*contract.Ledger, error) {
+	var srvc ordering.Service
+	err := inj.Resolve(&srvc)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to resolve ordering service: %v", err)
+	}
+
+	var p pool.Pool
+	err = inj.Resolve(&p)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to resolve pool: %v", err)
+	}
+
+	var mgr txn.Manager
+	err = inj.Resolve(&mgr)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to resolve transaction manager: %v", err)
+	}
+
+	return contract.NewLedger(srvc, p, mgr), nil
+}
+
+// registerAction is an action that registers the handlers to the dela proxy.
+// Calypso must have been started with the listen action. The GUI is served from
+// the files embedded in the binary, unless a folder is given with the gui-dir
+// flag.
 //
 // - implements node.ActionTemplate
 type registerAction struct{}
 
 func (a registerAction) Execute(ctx node.Context) error {
-	var actor dkg.Actor
-	err := ctx.Injector.Resolve(&actor)
+	var caly *calypso.Calypso
+	err := ctx.Injector.Resolve(&caly)
 	if err != nil {
-		return xerrors.Errorf("failed to resolve actor: %v", err)
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
 	}
 
-	caly := calypso.NewCalypso(actor)
-
-	ctx.Injector.Inject(caly)
-
 	var proxy proxy.Proxy
 	err = ctx.Injector.Resolve(&proxy)
 	if err != nil {
 		return xerrors.Errorf("failed to resolve proxy: %v", err)
 	}
 
-	ctrl := guictrl.NewCtrl(caly)
+	var opts []guictrl.Option
+
+	dir := ctx.Flags.String("gui-dir")
+	if dir != "" {
+		opts = append(opts, guictrl.WithFiles(os.DirFS(dir)))
+	}
+
+	ctrl, err := guictrl.NewCtrl(caly, opts...)
+	if err != nil {
+		return xerrors.Errorf("failed to create gui: %v", err)
+	}
 
-	fs := http.FileServer(http.Dir(ctrl.Abs("gui/assets")))
-	proxy.RegisterHandler("/assets/", tofunc(http.StripPrefix("/assets/", fs)))
+	proxy.RegisterHandler("/assets/", ctrl.AssetsHandler())
 	proxy.RegisterHandler("/", ctrl.HomeHandler())
 	proxy.RegisterHandler("/pubkey", ctrl.PubkeyHandler())
 	proxy.RegisterHandler("/encrypt", ctrl.EncryptHandler())
 	proxy.RegisterHandler("/write", ctrl.WriteHandler())
 	proxy.RegisterHandler("/read", ctrl.ReadHandler())
+	proxy.RegisterHandler("/update", ctrl.UpdateHandler())
+	proxy.RegisterHandler("/audit", ctrl.AuditHandler())
 
-	return nil
-}
+	var ns *nodeStatus
+	err = ctx.Injector.Resolve(&ns)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve status: %v", err)
+	}
 
-func tofunc(h http.Handler) http.HandlerFunc {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.ServeHTTP(w, r)
-	})
+	api.NewCtrl(caly, api.WithStatus(ns.get)).Register(proxy.RegisterHandler)
+
+	return nil
 }
 
 // setupAction is an action to setup Calypso. This action performs the DKG key
@@ -100,71 +196,888 @@ func (a setupAction) Execute(ctx node.Context) error {
 		return xerrors.Errorf("failed to resolve calypso: %v", err)
 	}
 
-	pubkeysStr := strings.Split(ctx.Flags.String("pubkeys"), ",")
-	if len(pubkeysStr) == 0 {
-		return xerrors.New("pubkeys not found")
+	roster, err := getRoster(ctx.Flags)
+	if err != nil {
+		return xerrors.Errorf("failed to get roster: %v", err)
+	}
+
+	ca, err := roster.toCA(no.GetAddressFactory())
+	if err != nil {
+		return xerrors.Errorf("invalid roster: %v", err)
+	}
+
+	threshold := roster.Threshold
+	if ctx.Flags.Int("threshold") != 0 {
+		threshold = ctx.Flags.Int("threshold")
+	}
+
+	err = checkThreshold(threshold, ca.Len())
+	if err != nil {
+		return xerrors.Errorf("invalid threshold: %v", err)
+	}
+
+	pubkey, err := ps.Setup(ca, threshold)
+	if err != nil {
+		return xerrors.Errorf("failed to setup calypso: %v", err)
+	}
+
+	pubkeyBuf, err := pubkey.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to mashal pubkey: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "Calypso has been successfully setup. "+
+		"Here is the Calypso shared pub key: %s\n", hex.EncodeToString(pubkeyBuf))
+
+	return nil
+}
+
+// reshareAction is an action to move the distributed key to a new collective
+// authority and threshold. The collective public key stays the same.
+//
+// - implements node.ActionTemplate
+type reshareAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a reshareAction) Execute(ctx node.Context) error {
+	var no mino.Mino
+	err := ctx.Injector.Resolve(&no)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve mino: %v", err)
+	}
+
+	var ps calypso.PrivateStorage
+	err = ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	roster, err := getRoster(ctx.Flags)
+	if err != nil {
+		return xerrors.Errorf("failed to get roster: %v", err)
 	}
 
-	addrsStr := strings.Split(ctx.Flags.String("addrs"), ",")
-	if len(addrsStr) == 0 {
-		return xerrors.New("addrs not found")
+	ca, err := roster.toCA(no.GetAddressFactory())
+	if err != nil {
+		return xerrors.Errorf("invalid roster: %v", err)
 	}
 
-	threshold := ctx.Flags.Int("threshold")
-	if threshold == 0 || threshold < 0 {
-		return xerrors.Errorf("threshold wrong or not provided: %d", threshold)
+	threshold := roster.Threshold
+	if ctx.Flags.Int("threshold") != 0 {
+		threshold = ctx.Flags.Int("threshold")
 	}
 
-	if len(pubkeysStr) != len(addrsStr) {
-		return xerrors.Errorf("there should be the same number of "+
-			"pubkkeys and addrs, but got %d pubkeys and %d addrs",
-			len(pubkeysStr), len(addrsStr))
+	err = checkThreshold(threshold, ca.Len())
+	if err != nil {
+		return xerrors.Errorf("invalid threshold: %v", err)
 	}
 
-	pubkeys := make([]ed25519.PublicKey, len(pubkeysStr))
-	addrs := make([]mino.Address, len(addrsStr))
-	for i, keyHex := range pubkeysStr {
-		point := suite.Point()
+	var store *replicated.Store
+	err = ctx.Injector.Resolve(&store)
+	if err != nil {
+		store = nil
+	}
 
-		keyBuf, err := hex.DecodeString(keyHex)
+	// The new members fetch the records from the members that remain, so at
+	// least one of them must be kept.
+	if store != nil {
+		players, err := store.GetPlayers()
 		if err != nil {
-			return xerrors.Errorf("failed to decode hex key: %v", err)
+			return xerrors.Errorf("failed to get replication players: %v", err)
 		}
 
-		err = point.UnmarshalBinary(keyBuf)
+		if !overlaps(players, ca.addrs) {
+			return xerrors.New("at least one member must be kept to serve " +
+				"the records to the new ones")
+		}
+	}
+
+	pubkey, err := ps.Reshare(ca, threshold)
+	if err != nil {
+		return xerrors.Errorf("failed to reshare: %v", err)
+	}
+
+	pubkeyBuf, err := pubkey.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to mashal pubkey: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "Calypso has been successfully reshared to %d "+
+		"members. The shared pub key is still: %s\n", ca.Len(),
+		hex.EncodeToString(pubkeyBuf))
+
+	return nil
+}
+
+// overlaps returns true if at least one address is in both lists.
+func overlaps(a, b []mino.Address) bool {
+	for _, addr := range a {
+		for _, other := range b {
+			if addr.Equal(other) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getRoster returns the roster of the roster file, or of the list of public
+// keys and addresses.
+func getRoster(flags cli.Flags) (rosterFile, error) {
+	path := flags.String("roster")
+	if path != "" {
+		return readRoster(path)
+	}
+
+	pubkeys := splitList(flags.String("pubkeys"))
+	addrs := splitList(flags.String("addrs"))
+
+	if len(pubkeys) == 0 || len(addrs) == 0 {
+		return rosterFile{}, xerrors.New("either roster or pubkeys and addrs " +
+			"must be provided")
+	}
+
+	if len(pubkeys) != len(addrs) {
+		return rosterFile{}, xerrors.Errorf("there should be the same number of "+
+			"pubkeys and addrs, but got %d pubkeys and %d addrs",
+			len(pubkeys), len(addrs))
+	}
+
+	roster := rosterFile{
+		Members: make([]rosterMember, len(pubkeys)),
+	}
+
+	for i, pubkey := range pubkeys {
+		addrBuf, err := base64.StdEncoding.DecodeString(addrs[i])
 		if err != nil {
-			return xerrors.Errorf("failed to unmarshal point: %v", err)
+			return rosterFile{}, xerrors.Errorf("base64 address: %v", err)
+		}
+
+		roster.Members[i] = rosterMember{
+			Address:   addrBuf,
+			PublicKey: pubkey,
 		}
+	}
+
+	return roster, nil
+}
+
+// statusAction is an action to print the status of the node.
+//
+// - implements node.ActionTemplate
+type statusAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a statusAction) Execute(ctx node.Context) error {
+	var ns *nodeStatus
+	err := ctx.Injector.Resolve(&ns)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve status: %v", err)
+	}
+
+	status, err := ns.get()
+	if err != nil {
+		return xerrors.Errorf("failed to get status: %v", err)
+	}
 
-		pubkeys[i] = ed25519.NewPublicKeyFromPoint(point)
+	if ctx.Flags.Bool("json") {
+		enc := json.NewEncoder(ctx.Out)
+		enc.SetIndent("", "  ")
 
-		addrBuf, err := base64.StdEncoding.DecodeString(addrsStr[i])
+		err = enc.Encode(status)
 		if err != nil {
-			return xerrors.Errorf("base64 address: %v", err)
+			return xerrors.Errorf("failed to encode status: %v", err)
 		}
 
-		addrs[i] = no.GetAddressFactory().FromText(addrBuf)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Phase: %s\n", status.Phase)
+	fmt.Fprintf(ctx.Out, "Backend: %s\n", status.Backend)
+	fmt.Fprintf(ctx.Out, "Records: %d\n", status.Records)
+	fmt.Fprintf(ctx.Out, "Expired: %d (%d deleted)\n", status.Expired, status.Swept)
 
-		fmt.Println("addr:", addrs[i].String())
+	if status.Phase != string(pedersen.PhaseReady) {
+		return nil
 	}
 
-	ca := internalCA{
-		players: mino.NewAddresses(addrs...),
-		pubkeys: pubkeys,
+	fmt.Fprintf(ctx.Out, "Threshold: %d\n", status.Threshold)
+	fmt.Fprintf(ctx.Out, "Fingerprint: %s\n", status.Fingerprint)
+	fmt.Fprintf(ctx.Out, "Members:\n")
+
+	for _, member := range status.Members {
+		fmt.Fprintf(ctx.Out, "  %s %s\n",
+			base64.StdEncoding.EncodeToString(member.Address), member.PublicKey)
 	}
 
-	pubkey, err := ps.Setup(ca, threshold)
+	return nil
+}
+
+// pubkeyAction is an action to print the collective public key, in hex
+// string.
+//
+// - implements node.ActionTemplate
+type pubkeyAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a pubkeyAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
 	if err != nil {
-		return xerrors.Errorf("failed to setup calypso: %v", err)
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	pubkey, err := ps.GetPublicKey()
+	if err != nil {
+		return xerrors.Errorf("failed to get public key: %v", err)
 	}
 
 	pubkeyBuf, err := pubkey.MarshalBinary()
 	if err != nil {
-		return xerrors.Errorf("failed to mashal pubkey: %v", err)
+		return xerrors.Errorf("failed to marshal pubkey: %v", err)
 	}
 
-	fmt.Printf("Calypso has been successfully setup. "+
-		"Here is the Calypso shared pub key: %s\n", hex.EncodeToString(pubkeyBuf))
+	fmt.Fprintf(ctx.Out, "%x\n", pubkeyBuf)
+
+	return nil
+}
+
+// encryptAction is an action to encrypt a message with the collective public
+// key. It prints the encrypted message in JSON, which can be given to the
+// write action.
+//
+// - implements node.ActionTemplate
+type encryptAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a encryptAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	pubkey, err := ps.GetPublicKey()
+	if err != nil {
+		return xerrors.Errorf("failed to get public key: %v", err)
+	}
+
+	message := []byte(ctx.Flags.String("message"))
+	if len(message) == 0 {
+		return xerrors.New("message is empty")
+	}
+
+	readers := splitList(ctx.Flags.String("readers"))
+
+	ac, err := newAccess(ctx.Flags.String("admin"), readers)
+	if err != nil {
+		return xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	k, env, err := calycrypto.EncryptHybrid(message, pubkey, random.New())
+	if err != nil {
+		return xerrors.Errorf("failed to encrypt: %v", err)
+	}
+
+	// The expiry is bound to the proof, so that it can't be changed when the
+	// message is written.
+	var expiry time.Time
+
+	expiryPtr := expiryOf(ctx.Flags)
+	if expiryPtr != nil {
+		expiry = *expiryPtr
+	}
+
+	proof, err := calypso.NewWriteProof(k, env.K, env.C, env.Data, expiry, ac)
+	if err != nil {
+		return xerrors.Errorf("failed to create proof: %v", err)
+	}
+
+	kBuf, err := env.K.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal K: %v", err)
+	}
+
+	cBuf, err := env.C.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal C: %v", err)
+	}
+
+	req := api.WriteRequest{
+		K:       hex.EncodeToString(kBuf),
+		C:       hex.EncodeToString(cBuf),
+		Data:    hex.EncodeToString(env.Data),
+		Proof:   hex.EncodeToString(proof),
+		Admin:   ctx.Flags.String("admin"),
+		Readers: readers,
+		Expiry:  expiryPtr,
+	}
+
+	err = json.NewEncoder(ctx.Out).Encode(req)
+	if err != nil {
+		return xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return nil
+}
+
+// writeAction is an action to write an encrypted message. The message is
+// either given by the flags or by a JSON file, as printed by the encrypt
+// action. The expiry is bound to the proof, therefore a record that expires is
+// written from the file. It prints the ID of the record, in hex string.
+//
+// - implements node.ActionTemplate
+type writeAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a writeAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	req := api.WriteRequest{
+		K:       ctx.Flags.String("k"),
+		C:       ctx.Flags.String("c"),
+		Data:    ctx.Flags.String("data"),
+		Proof:   ctx.Flags.String("proof"),
+		Admin:   ctx.Flags.String("admin"),
+		Readers: splitList(ctx.Flags.String("readers")),
+	}
+
+	file := ctx.Flags.String("file")
+	if file != "" {
+		req, err = readWriteRequest(file)
+		if err != nil {
+			return xerrors.Errorf("failed to read file: %v", err)
+		}
+	}
+
+	em, ac, err := req.Decode()
+	if err != nil {
+		return xerrors.Errorf("failed to decode message: %v", err)
+	}
+
+	id, err := ps.Write(em, ac, req.Options()...)
+	if err != nil {
+		return xerrors.Errorf("failed to write: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "%x\n", id)
 
 	return nil
 }
+
+// readAction is an action to read the message of a record. It prints the
+// message in hex string.
+//
+// - implements node.ActionTemplate
+type readAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a readAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	signers, err := loadSigners(splitList(ctx.Flags.String("key")))
+	if err != nil {
+		return xerrors.Errorf("failed to load keys: %v", err)
+	}
+
+	nonce, _, err := ps.Challenge(id)
+	if err != nil {
+		return xerrors.Errorf("failed to get challenge: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, calypso.ReadDigest(nonce, id),
+		signers...)
+	if err != nil {
+		return xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	msg, err := ps.Read(id, auth)
+	if err != nil {
+		return xerrors.Errorf("failed to read: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "%x\n", msg)
+
+	return nil
+}
+
+// readReencryptedAction is an action to read the secret of a record
+// re-encrypted under the public key of a reader. It prints the re-encrypted
+// secret in JSON, which the reader decrypts with its private key.
+//
+// - implements node.ActionTemplate
+type readReencryptedAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a readReencryptedAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	xc, err := loadReaderKey(ctx.Flags.String("reader"))
+	if err != nil {
+		return xerrors.Errorf("failed to load reader key: %v", err)
+	}
+
+	pubk := suite.Point().Mul(xc, nil)
+
+	signers, err := loadSigners(splitList(ctx.Flags.String("key")))
+	if err != nil {
+		return xerrors.Errorf("failed to load keys: %v", err)
+	}
+
+	nonce, _, err := ps.Challenge(id)
+	if err != nil {
+		return xerrors.Errorf("failed to get challenge: %v", err)
+	}
+
+	keyProof, err := calypso.NewReaderKeyProof(xc, pubk, nonce, id)
+	if err != nil {
+		return xerrors.Errorf("failed to prove reader key: %v", err)
+	}
+
+	digest, err := calypso.ReencryptDigest(nonce, id, pubk, keyProof)
+	if err != nil {
+		return xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signers...)
+	if err != nil {
+		return xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	secret, err := ps.ReadReencrypted(id, pubk, keyProof, auth)
+	if err != nil {
+		return xerrors.Errorf("failed to read: %v", err)
+	}
+
+	return printReencrypted(ctx.Out, secret)
+}
+
+// printReencrypted prints the re-encrypted secret in the JSON format of the
+// API.
+func printReencrypted(out io.Writer, secret calypso.Reencrypted) error {
+	xhatEnc, err := secret.GetXhatEnc().MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal XhatEnc: %v", err)
+	}
+
+	cBuf, err := secret.GetC().MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal C: %v", err)
+	}
+
+	res := api.ReadReencryptedResponse{
+		XhatEnc: hex.EncodeToString(xhatEnc),
+		C:       hex.EncodeToString(cBuf),
+		Data:    hex.EncodeToString(secret.GetData()),
+	}
+
+	err = json.NewEncoder(out).Encode(res)
+	if err != nil {
+		return xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return nil
+}
+
+// updateAccessAction is an action to replace the access control of a record.
+// The identity of the key must be allowed to update the record by the current
+// access control.
+//
+// - implements node.ActionTemplate
+type updateAccessAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a updateAccessAction) Execute(ctx node.Context) error {
+	var ps calypso.PrivateStorage
+	err := ctx.Injector.Resolve(&ps)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve calypso: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	ac, err := newAccess(ctx.Flags.String("admin"),
+		splitList(ctx.Flags.String("readers")))
+	if err != nil {
+		return xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	signer, err := loadSigner(ctx.Flags.String("key"))
+	if err != nil {
+		return xerrors.Errorf("failed to load key: %v", err)
+	}
+
+	nonce, _, err := ps.Challenge(id)
+	if err != nil {
+		return xerrors.Errorf("failed to get challenge: %v", err)
+	}
+
+	digest, err := calypso.UpdateDigest(nonce, id, ac)
+	if err != nil {
+		return xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signer)
+	if err != nil {
+		return xerrors.Errorf("failed to authenticate: %v", err)
+	}
+
+	err = ps.UpdateAccess(id, auth, ac)
+	if err != nil {
+		return xerrors.Errorf("failed to update access: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "access of %x updated\n", id)
+
+	return nil
+}
+
+// keygenAction is an action to create the key of a new BLS identity. The key
+// is saved in a file, and the identity is printed as expected by the access
+// controls. With the reader flag, it creates the Ed25519 key of a reader of
+// re-encrypted secrets instead, and prints its public key in hex string.
+//
+// - implements node.ActionTemplate
+type keygenAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a keygenAction) Execute(ctx node.Context) error {
+	path := ctx.Flags.String("out")
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return xerrors.Errorf("file '%s' already exists", path)
+	}
+
+	if ctx.Flags.Bool("reader") {
+		xc := suite.Scalar().Pick(random.New())
+
+		data, err := xc.MarshalBinary()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal key: %v", err)
+		}
+
+		err = ioutil.WriteFile(path, data, 0600)
+		if err != nil {
+			return xerrors.Errorf("failed to write key: %v", err)
+		}
+
+		pubk, err := suite.Point().Mul(xc, nil).MarshalBinary()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal public key: %v", err)
+		}
+
+		fmt.Fprintf(ctx.Out, "%x\n", pubk)
+
+		return nil
+	}
+
+	signer := bls.NewSigner()
+
+	data, err := signer.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal key: %v", err)
+	}
+
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return xerrors.Errorf("failed to write key: %v", err)
+	}
+
+	text, err := signer.GetPublicKey().MarshalText()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal identity: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "%s\n", text)
+
+	return nil
+}
+
+// signAction is an action to answer the challenge of a read, of an update
+// when the new access control is given, or of an audit query. It prints the
+// signature of the digest in hex string, as expected by the GUI.
+//
+// - implements node.ActionTemplate
+type signAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a signAction) Execute(ctx node.Context) error {
+	signer, err := loadSigner(ctx.Flags.String("key"))
+	if err != nil {
+		return xerrors.Errorf("failed to load key: %v", err)
+	}
+
+	nonce, err := hex.DecodeString(ctx.Flags.String("nonce"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode nonce: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	digest := calypso.ReadDigest(nonce, id)
+
+	admin := ctx.Flags.String("admin")
+
+	switch {
+	case ctx.Flags.Bool("audit"):
+		if admin != "" {
+			return xerrors.New("audit doesn't take a new access control")
+		}
+
+		digest = calypso.AuditDigest(nonce, id)
+	case admin != "":
+		ac, err := newAccess(admin, splitList(ctx.Flags.String("readers")))
+		if err != nil {
+			return xerrors.Errorf("failed to create access: %v", err)
+		}
+
+		digest, err = calypso.UpdateDigest(nonce, id, ac)
+		if err != nil {
+			return xerrors.Errorf("failed to compute digest: %v", err)
+		}
+	}
+
+	sig, err := signer.Sign(digest)
+	if err != nil {
+		return xerrors.Errorf("failed to sign: %v", err)
+	}
+
+	data, err := sig.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal signature: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "%x\n", data)
+
+	return nil
+}
+
+// ledgerReadAction is an action to print the secret of a read transaction
+// committed in the ledger by the Calypso contract, re-encrypted under the key
+// of the reader of the transaction, so that only the reader can decrypt it.
+//
+// - implements node.ActionTemplate
+type ledgerReadAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a ledgerReadAction) Execute(ctx node.Context) error {
+	var srvc ordering.Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve ordering service: %v", err)
+	}
+
+	var actor dkg.Actor
+	err = ctx.Injector.Resolve(&actor)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve actor: %v", err)
+	}
+
+	reencrypter, ok := actor.(calypso.Reencrypter)
+	if !ok {
+		return xerrors.Errorf("actor '%T': %w", actor,
+			calypso.ErrReencryptNotSupported)
+	}
+
+	txID, err := hex.DecodeString(ctx.Flags.String("tx"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode tx: %v", err)
+	}
+
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+
+	secret, err := contract.ReadCommitted(srvc.GetStore(), fac, reencrypter, txID)
+	if err != nil {
+		return xerrors.Errorf("failed to read: %v", err)
+	}
+
+	return printReencrypted(ctx.Out, secret)
+}
+
+// auditAction is an action to print the entries of the audit log.
+//
+// - implements node.ActionTemplate
+type auditAction struct{}
+
+// Execute implements node.ActionTemplate
+func (a auditAction) Execute(ctx node.Context) error {
+	var auditLog audit.Log
+	err := ctx.Injector.Resolve(&auditLog)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve audit log: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	filter := audit.Filter{
+		RecordID: id,
+		Identity: ctx.Flags.String("identity"),
+	}
+
+	entries, err := auditLog.Query(filter)
+	if err != nil {
+		return xerrors.Errorf("failed to query audit log: %v", err)
+	}
+
+	enc := json.NewEncoder(ctx.Out)
+	for _, entry := range entries {
+		err = enc.Encode(entry)
+		if err != nil {
+			return xerrors.Errorf("failed to encode entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// newAccess returns the access control of the admin and the readers, whose
+// identities are public keys in their text form.
+func newAccess(admin string, readers []string) (access.Service, error) {
+	if admin == "" {
+		return nil, xerrors.New("admin identity is empty")
+	}
+
+	owner, err := calypso.ParseIdentity(admin)
+	if err != nil {
+		return nil, xerrors.Errorf("admin: %v", err)
+	}
+
+	readIDs := make([]access.Identity, len(readers))
+	for i, reader := range readers {
+		readIDs[i], err = calypso.ParseIdentity(reader)
+		if err != nil {
+			return nil, xerrors.Errorf("reader %d: %v", i, err)
+		}
+	}
+
+	return calypso.NewAccess(owner, readIDs...)
+}
+
+// loadSigner returns the BLS signer saved in the file, as created by the
+// keygen action.
+func loadSigner(path string) (crypto.Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read key: %v", err)
+	}
+
+	signer, err := bls.NewSignerFromBytes(data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+
+	return signer, nil
+}
+
+// loadReaderKey returns the Ed25519 private key of a reader saved in the file,
+// as created by the keygen action with the reader flag.
+func loadReaderKey(path string) (kyber.Scalar, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read key: %v", err)
+	}
+
+	xc := suite.Scalar()
+
+	err = xc.UnmarshalBinary(data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+
+	return xc, nil
+}
+
+// loadSigners returns the signers of each of the files.
+func loadSigners(paths []string) ([]crypto.Signer, error) {
+	if len(paths) == 0 {
+		return nil, xerrors.New("no key")
+	}
+
+	signers := make([]crypto.Signer, len(paths))
+
+	for i, path := range paths {
+		signer, err := loadSigner(path)
+		if err != nil {
+			return nil, xerrors.Errorf("key '%s': %v", path, err)
+		}
+
+		signers[i] = signer
+	}
+
+	return signers, nil
+}
+
+// expiryOf returns the expiry given by the duration of the expiry flag, or nil
+// if the flag is not set.
+func expiryOf(flags cli.Flags) *time.Time {
+	d := flags.Duration("expiry")
+	if d <= 0 {
+		return nil
+	}
+
+	expiry := time.Now().Add(d)
+
+	return &expiry
+}
+
+// splitList returns the non-empty elements of a list separated by commas.
+func splitList(list string) []string {
+	elements := make([]string, 0)
+
+	for _, element := range strings.Split(list, ",") {
+		if element != "" {
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
+
+func readWriteRequest(path string) (api.WriteRequest, error) {
+	var req api.WriteRequest
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return req, xerrors.Errorf("failed to read: %v", err)
+	}
+
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return req, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,618 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestPubkeyAction_Execute(t *testing.T) {
+	actor := newFakeActor()
+
+	out, err := execute(pubkeyAction{}, newInjector(actor), fakeFlags{})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	expected, err := actor.pubkey.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if out != hex.EncodeToString(expected)+"\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	_, err = execute(pubkeyAction{}, node.NewInjector(), fakeFlags{})
+	checkError(t, err, "failed to resolve calypso")
+}
+
+func TestKeygenAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "alice.key")
+
+	out, err := execute(keygenAction{}, nil, fakeFlags{"out": path})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	signer, err := loadSigner(path)
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+
+	if out != identity(t, signer)+"\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	_, err = execute(keygenAction{}, nil, fakeFlags{"out": path})
+	checkError(t, err, "already exists")
+
+	path = filepath.Join(dir, "reader.key")
+
+	out, err = execute(keygenAction{}, nil, fakeFlags{"out": path, "reader": true})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	xc, err := loadReaderKey(path)
+	if err != nil {
+		t.Fatalf("failed to load reader key: %v", err)
+	}
+
+	pubk, err := suite.Point().Mul(xc, nil).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if out != hex.EncodeToString(pubk)+"\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	_, err = execute(keygenAction{}, nil,
+		fakeFlags{"out": filepath.Join(dir, "missing", "key")})
+	checkError(t, err, "failed to write key")
+}
+
+func TestEncryptWriteRead(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	actor := newFakeActor()
+	inj := newInjector(actor)
+
+	alice := keygen(t, dir, "alice")
+	bob := keygen(t, dir, "bob")
+	eve := keygen(t, dir, "eve")
+
+	for _, message := range []string{"hello", strings.Repeat("long ", 20)} {
+		out, err := execute(encryptAction{}, inj, fakeFlags{
+			"message": message,
+			"admin":   alice.ident,
+			"readers": bob.ident,
+		})
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+
+		file := filepath.Join(dir, "secret.json")
+
+		err = ioutil.WriteFile(file, []byte(out), 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		out, err = execute(writeAction{}, inj, fakeFlags{"file": file})
+		if err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+
+		id := strings.TrimSpace(out)
+
+		_, err = execute(writeAction{}, inj, fakeFlags{"file": file})
+		checkError(t, err, "already exists")
+
+		out, err = execute(readAction{}, inj, fakeFlags{"id": id, "key": bob.path})
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+
+		if out != hex.EncodeToString([]byte(message))+"\n" {
+			t.Fatalf("unexpected output: %q", out)
+		}
+
+		_, err = execute(readAction{}, inj, fakeFlags{"id": id, "key": eve.path})
+		checkError(t, err, "access denied")
+	}
+}
+
+func TestEncryptAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inj := newInjector(newFakeActor())
+	alice := keygen(t, dir, "alice")
+
+	out, err := execute(encryptAction{}, inj, fakeFlags{
+		"message": "hello",
+		"admin":   alice.ident,
+		"expiry":  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	var req api.WriteRequest
+
+	err = json.Unmarshal([]byte(out), &req)
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+
+	if req.Expiry == nil || time.Until(*req.Expiry) <= 0 {
+		t.Fatalf("unexpected expiry: %v", req.Expiry)
+	}
+
+	// The expiry is bound to the proof.
+	em, ac, err := req.Decode()
+	if err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+
+	err = calypso.VerifyWriteProof(em.GetProof(), em.GetK(), em.GetC(),
+		em.GetData(), *req.Expiry, ac)
+	if err != nil {
+		t.Fatalf("invalid proof: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		flags fakeFlags
+		err   string
+	}{
+		{"empty message", fakeFlags{"admin": alice.ident}, "message is empty"},
+		{"no admin", fakeFlags{"message": "hello"}, "admin identity is empty"},
+		{"bad reader", fakeFlags{"message": "hello", "admin": alice.ident,
+			"readers": "bls:zz"}, "reader 0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := execute(encryptAction{}, inj, tc.flags)
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+func TestWriteAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inj := newInjector(newFakeActor())
+	alice := keygen(t, dir, "alice")
+
+	out, err := execute(encryptAction{}, inj, fakeFlags{
+		"message": "hello",
+		"admin":   alice.ident,
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	var req api.WriteRequest
+
+	err = json.Unmarshal([]byte(out), &req)
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+
+	flags := fakeFlags{
+		"k":     req.K,
+		"c":     req.C,
+		"proof": req.Proof,
+		"admin": alice.ident,
+	}
+
+	// The proof binds the access control.
+	forged := fakeFlags{"readers": alice.ident}
+	for k, v := range flags {
+		forged[k] = v
+	}
+
+	forged["admin"] = keygen(t, dir, "eve").ident
+
+	_, err = execute(writeAction{}, inj, forged)
+	checkError(t, err, "invalid write proof")
+
+	_, err = execute(writeAction{}, inj, flags)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	_, err = execute(writeAction{}, inj, fakeFlags{"k": "zz"})
+	checkError(t, err, "failed to decode message")
+
+	_, err = execute(writeAction{}, inj,
+		fakeFlags{"file": filepath.Join(dir, "missing.json")})
+	checkError(t, err, "failed to read file")
+
+	_, err = execute(writeAction{}, node.NewInjector(), flags)
+	checkError(t, err, "failed to resolve calypso")
+}
+
+func TestReadAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inj := newInjector(newFakeActor())
+	alice := keygen(t, dir, "alice")
+
+	testCases := []struct {
+		name  string
+		flags fakeFlags
+		err   string
+	}{
+		{"bad id", fakeFlags{"id": "zz", "key": alice.path}, "failed to decode id"},
+		{"missing key", fakeFlags{"id": "aa", "key": filepath.Join(dir, "none")},
+			"failed to load keys"},
+		{"unknown record", fakeFlags{"id": "aa", "key": alice.path}, "not found"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := execute(readAction{}, inj, tc.flags)
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+func TestReadReencryptedAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	actor := newFakeActor()
+	inj := newInjector(actor)
+
+	alice := keygen(t, dir, "alice")
+
+	readerPath := filepath.Join(dir, "reader.key")
+
+	_, err := execute(keygenAction{}, nil, fakeFlags{"out": readerPath, "reader": true})
+	if err != nil {
+		t.Fatalf("failed to create reader key: %v", err)
+	}
+
+	id := write(t, inj, "hello", alice)
+
+	out, err := execute(readReencryptedAction{}, inj, fakeFlags{
+		"id":     id,
+		"key":    alice.path,
+		"reader": readerPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	var res api.ReadReencryptedResponse
+
+	err = json.Unmarshal([]byte(out), &res)
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+
+	secret, err := res.Decode()
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+
+	xc, err := loadReaderKey(readerPath)
+	if err != nil {
+		t.Fatalf("failed to load reader key: %v", err)
+	}
+
+	msg, err := calypso.DecryptReencrypted(secret, xc, actor.pubkey)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+
+	if string(msg) != "hello" {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+
+	_, err = execute(readReencryptedAction{}, inj, fakeFlags{
+		"id":     id,
+		"key":    alice.path,
+		"reader": filepath.Join(dir, "none"),
+	})
+	checkError(t, err, "failed to load reader key")
+}
+
+func TestSignAction_Execute(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	alice := keygen(t, dir, "alice")
+	bob := keygen(t, dir, "bob")
+
+	nonce := []byte{1, 2, 3}
+	id := []byte{4, 5, 6}
+
+	ac, err := newAccess(alice.ident, []string{bob.ident})
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	updateDigest, err := calypso.UpdateDigest(nonce, id, ac)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		flags  fakeFlags
+		digest []byte
+	}{
+		{"read", fakeFlags{}, calypso.ReadDigest(nonce, id)},
+		{"audit", fakeFlags{"audit": true}, calypso.AuditDigest(nonce, id)},
+		{"update", fakeFlags{"admin": alice.ident, "readers": bob.ident},
+			updateDigest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.flags["key"] = alice.path
+			tc.flags["nonce"] = hex.EncodeToString(nonce)
+			tc.flags["id"] = hex.EncodeToString(id)
+
+			out, err := execute(signAction{}, nil, tc.flags)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+
+			data, err := hex.DecodeString(strings.TrimSpace(out))
+			if err != nil {
+				t.Fatalf("failed to decode signature: %v", err)
+			}
+
+			err = alice.signer.GetPublicKey().Verify(tc.digest, bls.NewSignature(data))
+			if err != nil {
+				t.Fatalf("wrong signature: %v", err)
+			}
+		})
+	}
+
+	badCases := []struct {
+		name  string
+		flags fakeFlags
+		err   string
+	}{
+		{"missing key", fakeFlags{"key": filepath.Join(dir, "none")},
+			"failed to load key"},
+		{"bad nonce", fakeFlags{"key": alice.path, "nonce": "zz"},
+			"failed to decode nonce"},
+		{"bad id", fakeFlags{"key": alice.path, "id": "zz"}, "failed to decode id"},
+		{"audit with access", fakeFlags{"key": alice.path, "audit": true,
+			"admin": alice.ident}, "audit doesn't take a new access control"},
+		{"bad admin", fakeFlags{"key": alice.path, "admin": "bls:zz"},
+			"failed to create access"},
+	}
+
+	for _, tc := range badCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := execute(signAction{}, nil, tc.flags)
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// execute runs the action with the flags, and returns its output.
+func execute(action node.ActionTemplate, inj node.Injector,
+	flags fakeFlags) (string, error) {
+
+	out := new(bytes.Buffer)
+
+	ctx := node.Context{
+		Injector: inj,
+		Flags:    flags,
+		Out:      out,
+	}
+
+	err := action.Execute(ctx)
+
+	return out.String(), err
+}
+
+// newInjector returns an injector with a Calypso of the actor.
+func newInjector(actor fakeActor) node.Injector {
+	inj := node.NewInjector()
+	inj.Inject(calypso.NewCalypso(actor))
+
+	return inj
+}
+
+// keyFile is a key created by the keygen action.
+type keyFile struct {
+	path   string
+	ident  string
+	signer crypto.Signer
+}
+
+func keygen(t *testing.T, dir, name string) keyFile {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".key")
+
+	out, err := execute(keygenAction{}, nil, fakeFlags{"out": path})
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+
+	signer, err := loadSigner(path)
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+
+	return keyFile{
+		path:   path,
+		ident:  strings.TrimSpace(out),
+		signer: signer,
+	}
+}
+
+// write encrypts and writes the message administered by the key, and returns
+// the ID of its record.
+func write(t *testing.T, inj node.Injector, message string, admin keyFile) string {
+	t.Helper()
+
+	out, err := execute(encryptAction{}, inj, fakeFlags{
+		"message": message,
+		"admin":   admin.ident,
+	})
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	var req api.WriteRequest
+
+	err = json.Unmarshal([]byte(out), &req)
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+
+	out, err = execute(writeAction{}, inj, fakeFlags{
+		"k":     req.K,
+		"c":     req.C,
+		"data":  req.Data,
+		"proof": req.Proof,
+		"admin": admin.ident,
+	})
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	return strings.TrimSpace(out)
+}
+
+func identity(t *testing.T, signer crypto.Signer) string {
+	t.Helper()
+
+	text, err := signer.GetPublicKey().MarshalText()
+	if err != nil {
+		t.Fatalf("failed to marshal identity: %v", err)
+	}
+
+	return string(text)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "calypso-action")
+	if err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	return dir
+}
+
+// fakeFlags are the flags of an action, by name. A missing flag has the zero
+// value of its type.
+//
+// - implements cli.Flags
+type fakeFlags map[string]interface{}
+
+func (f fakeFlags) String(name string) string {
+	str, _ := f[name].(string)
+	return str
+}
+
+func (f fakeFlags) StringSlice(name string) []string {
+	slice, _ := f[name].([]string)
+	return slice
+}
+
+func (f fakeFlags) Duration(name string) time.Duration {
+	d, _ := f[name].(time.Duration)
+	return d
+}
+
+func (f fakeFlags) Path(name string) string {
+	return f.String(name)
+}
+
+func (f fakeFlags) Int(name string) int {
+	n, _ := f[name].(int)
+	return n
+}
+
+func (f fakeFlags) Bool(name string) bool {
+	b, _ := f[name].(bool)
+	return b
+}
+
+// fakeActor is a DKG actor that holds the whole private key, and re-encrypts
+// the secrets with it.
+//
+// - implements dkg.Actor
+// - implements calypso.Reencrypter
+type fakeActor struct {
+	secret kyber.Scalar
+	pubkey kyber.Point
+}
+
+func newFakeActor() fakeActor {
+	secret := suite.Scalar().Pick(random.New())
+
+	return fakeActor{
+		secret: secret,
+		pubkey: suite.Point().Mul(secret, nil),
+	}
+}
+
+func (a fakeActor) Setup(crypto.CollectiveAuthority, int) (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) GetPublicKey() (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) Encrypt(message []byte) (K, C kyber.Point, remainder []byte,
+	err error) {
+
+	_, K, C, err = calycrypto.Encrypt(message, a.pubkey, random.New())
+
+	return K, C, nil, err
+}
+
+func (a fakeActor) Decrypt(K, C kyber.Point) ([]byte, error) {
+	return calycrypto.Decrypt(a.secret, K, C)
+}
+
+func (a fakeActor) Reshare() error {
+	return nil
+}
+
+func (a fakeActor) Reencrypt(K, pubk kyber.Point) (kyber.Point, error) {
+	return suite.Point().Mul(a.secret, suite.Point().Add(K, pubk)), nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"golang.org/x/xerrors"
+)
+
+// ChallengeRequest is the request of a challenge for a record.
+type ChallengeRequest struct {
+	// ID is the hex encoded ID of the record to read or update.
+	ID string
+}
+
+// ChallengeResponse is the response of a challenge request.
+type ChallengeResponse struct {
+	// Nonce is the hex encoded nonce that the identities include in the
+	// digest they sign.
+	Nonce string
+	// Expiry is the time after which the nonce is refused.
+	Expiry time.Time
+}
+
+// SignedIdentity is an identity and its signature of the digest of a request.
+type SignedIdentity struct {
+	// Identity is the public key in its text form, for instance
+	// "schnorr:<hex>" or "bls:<hex>".
+	Identity string
+	// Signature is the hex encoded signature of the digest.
+	Signature string
+}
+
+// ChallengeHandler handles the challenge requests. Each request issues a new
+// nonce for a record, which can be used once to authenticate a read or an
+// update of the record on the same node.
+func (c *Ctrl) ChallengeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.challengePOST(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only POST request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) challengePOST(w http.ResponseWriter, r *http.Request) {
+	var req ChallengeRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := hex.DecodeString(req.ID)
+	if err != nil || len(id) == 0 {
+		renderError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+
+	nonce, expiry, err := c.caly.Challenge(id)
+	if err != nil {
+		renderError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	res := ChallengeResponse{
+		Nonce:  hex.EncodeToString(nonce),
+		Expiry: expiry,
+	}
+
+	renderJSON(w, http.StatusOK, res)
+}
+
+// DecodeAuthentication returns the authentication of the nonce and the
+// identities of a request.
+func DecodeAuthentication(nonce string,
+	idents ...SignedIdentity) (calypso.Authentication, error) {
+
+	var auth calypso.Authentication
+
+	buf, err := hex.DecodeString(nonce)
+	if err != nil || len(buf) == 0 {
+		return auth, xerrors.New("invalid nonce")
+	}
+
+	if len(idents) == 0 {
+		return auth, xerrors.New("identities are empty")
+	}
+
+	auth.Nonce = buf
+	auth.Identities = make([]calypso.SignedIdentity, len(idents))
+
+	for i, ident := range idents {
+		pubkey, err := calypso.ParseIdentity(ident.Identity)
+		if err != nil {
+			return auth, xerrors.Errorf("identity %d: %v", i, err)
+		}
+
+		data, err := hex.DecodeString(ident.Signature)
+		if err != nil || len(data) == 0 {
+			return auth, xerrors.Errorf("identity %d: invalid signature", i)
+		}
+
+		sig, err := calypso.ParseSignature(pubkey, data)
+		if err != nil {
+			return auth, xerrors.Errorf("identity %d: %v", i, err)
+		}
+
+		auth.Identities[i] = calypso.SignedIdentity{
+			PublicKey: pubkey,
+			Signature: sig,
+		}
+	}
+
+	return auth, nil
+}
+
+// EncodeAuthentication returns the nonce and the identities of the
+// authentication, as expected by the requests.
+func EncodeAuthentication(auth calypso.Authentication) (string, []SignedIdentity, error) {
+	idents := make([]SignedIdentity, len(auth.Identities))
+
+	for i, ident := range auth.Identities {
+		text, err := ident.PublicKey.MarshalText()
+		if err != nil {
+			return "", nil, xerrors.Errorf("failed to marshal identity: %v", err)
+		}
+
+		sig, err := ident.Signature.MarshalBinary()
+		if err != nil {
+			return "", nil, xerrors.Errorf("failed to marshal signature: %v", err)
+		}
+
+		idents[i] = SignedIdentity{
+			Identity:  string(text),
+			Signature: hex.EncodeToString(sig),
+		}
+	}
+
+	return hex.EncodeToString(auth.Nonce), idents, nil
+}
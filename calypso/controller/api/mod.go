@@ -0,0 +1,159 @@
+// Package api implements the versioned JSON API of Calypso. It offers the
+// same operations as the GUI, for services that integrate with Calypso.
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated"
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
+)
+
+// Prefix is the path prefix of the API endpoints.
+const Prefix = "/api/v1/calypso"
+
+// Option is the type of options to create an API controller.
+type Option func(*Ctrl)
+
+// WithStatus is an option to expose the status of the node, which is gathered
+// by the function.
+func WithStatus(fn StatusFunc) Option {
+	return func(c *Ctrl) {
+		c.status = fn
+	}
+}
+
+// NewCtrl creates a new API controller.
+func NewCtrl(caly *calypso.Calypso, opts ...Option) *Ctrl {
+	c := &Ctrl{
+		caly: caly,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Ctrl holds all the API controllers.
+type Ctrl struct {
+	caly   *calypso.Calypso
+	status StatusFunc
+}
+
+// Register registers the API handlers with the function, which is usually the
+// RegisterHandler of the proxy.
+func (c *Ctrl) Register(register func(string, func(http.ResponseWriter, *http.Request))) {
+	register(Prefix+"/pubkey", c.PubkeyHandler())
+	register(Prefix+"/challenge", c.ChallengeHandler())
+	register(Prefix+"/write", c.WriteHandler())
+	register(Prefix+"/read", c.ReadHandler())
+	register(Prefix+"/read-reencrypted", c.ReadReencryptedHandler())
+	register(Prefix+"/update-access", c.UpdateAccessHandler())
+	register(Prefix+"/records/", c.RecordHandler())
+
+	if c.status != nil {
+		register(Prefix+"/status", c.StatusHandler())
+	}
+}
+
+// ErrorResponse is the body of the responses of failed requests.
+type ErrorResponse struct {
+	Code    int
+	Message string
+}
+
+// renderJSON writes the response in JSON with the status code.
+func renderJSON(w http.ResponseWriter, code int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		js, _ = json.Marshal(ErrorResponse{
+			Code:    code,
+			Message: "failed to marshal response: " + err.Error(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(js)
+}
+
+// renderError writes the error in JSON with the status code.
+func renderError(w http.ResponseWriter, code int, message string) {
+	renderJSON(w, code, ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
+// ErrorCode returns the HTTP status code that corresponds to an error returned
+// by Calypso. It is shared with the GUI.
+func ErrorCode(err error) int {
+	switch {
+	case xerrors.Is(err, calypso.ErrNotFound):
+		return http.StatusNotFound
+	case xerrors.Is(err, calypso.ErrExpired):
+		return http.StatusGone
+	case xerrors.Is(err, calypso.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case xerrors.Is(err, calypso.ErrAccessDenied):
+		return http.StatusForbidden
+	case xerrors.Is(err, calypso.ErrAlreadyExists):
+		return http.StatusConflict
+	case xerrors.Is(err, calypso.ErrInvalidProof),
+		xerrors.Is(err, calypso.ErrInvalidCiphertext),
+		xerrors.Is(err, calypso.ErrInvalidReaderKey):
+		return http.StatusBadRequest
+	case xerrors.Is(err, calypso.ErrReencryptNotSupported):
+		return http.StatusNotImplemented
+	case xerrors.Is(err, replicated.ErrNotReplicated):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeJSON decodes the body of the request, which must be JSON.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err != nil {
+		return xerrors.Errorf("failed to decode body: %v", err)
+	}
+
+	return nil
+}
+
+// decodePoint decodes a point of a ciphertext from its hex encoding, which
+// must be the canonical one.
+func decodePoint(str string) (kyber.Point, error) {
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode hex: %v", err)
+	}
+
+	point, err := calypso.UnmarshalPoint(buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal point: %v", err)
+	}
+
+	return point, nil
+}
+
+// encodePoint returns the hex encoding of the point.
+func encodePoint(point kyber.Point) (string, error) {
+	buf, err := point.MarshalBinary()
+	if err != nil {
+		return "", xerrors.Errorf("failed to marshal point: %v", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,553 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+var suite = suites.MustFind("Ed25519")
+
+var (
+	alice = bls.NewSigner()
+	bob   = bls.NewSigner()
+	eve   = bls.NewSigner()
+)
+
+func TestCtrl_Pubkey(t *testing.T) {
+	actor := newFakeActor()
+	mux := newMux(calypso.NewCalypso(actor))
+
+	var res PubkeyResponse
+	do(t, mux, http.MethodGet, "/pubkey", nil, http.StatusOK, &res)
+
+	expected, err := encodePoint(actor.pubkey)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	if res.PublicKey != expected {
+		t.Fatalf("expected %s, got %s", expected, res.PublicKey)
+	}
+
+	do(t, mux, http.MethodPost, "/pubkey", nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_Challenge(t *testing.T) {
+	mux := newMux(calypso.NewCalypso(newFakeActor()))
+
+	var res ChallengeResponse
+	do(t, mux, http.MethodPost, "/challenge", ChallengeRequest{ID: "aa"},
+		http.StatusOK, &res)
+
+	nonce, err := hex.DecodeString(res.Nonce)
+	if err != nil || len(nonce) == 0 {
+		t.Fatalf("invalid nonce %q", res.Nonce)
+	}
+
+	if !res.Expiry.After(time.Now()) {
+		t.Fatalf("nonce already expired at %v", res.Expiry)
+	}
+
+	do(t, mux, http.MethodPost, "/challenge", ChallengeRequest{},
+		http.StatusBadRequest, nil)
+	do(t, mux, http.MethodPost, "/challenge", ChallengeRequest{ID: "zz"},
+		http.StatusBadRequest, nil)
+	do(t, mux, http.MethodPost, "/challenge", nil, http.StatusBadRequest, nil)
+	do(t, mux, http.MethodGet, "/challenge", nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_WriteRead(t *testing.T) {
+	actor := newFakeActor()
+	caly := calypso.NewCalypso(actor)
+	mux := newMux(caly)
+
+	req := makeWriteRequest(t, actor, []byte("secret"))
+
+	var res WriteResponse
+	do(t, mux, http.MethodPost, "/write", req, http.StatusCreated, &res)
+
+	id, err := hex.DecodeString(res.ID)
+	if err != nil {
+		t.Fatalf("invalid ID %q", res.ID)
+	}
+
+	// A record can only be written once.
+	do(t, mux, http.MethodPost, "/write", req, http.StatusConflict, nil)
+
+	for _, signer := range []crypto.Signer{alice, bob} {
+		var readRes ReadResponse
+		do(t, mux, http.MethodPost, "/read", makeReadRequest(t, caly, id, signer),
+			http.StatusOK, &readRes)
+
+		if readRes.Message != hex.EncodeToString([]byte("secret")) {
+			t.Fatalf("wrong message: %s", readRes.Message)
+		}
+	}
+
+	do(t, mux, http.MethodPost, "/read", makeReadRequest(t, caly, id, eve),
+		http.StatusForbidden, nil)
+
+	// The nonce is used by the first read.
+	readReq := makeReadRequest(t, caly, id, bob)
+	do(t, mux, http.MethodPost, "/read", readReq, http.StatusOK, nil)
+	do(t, mux, http.MethodPost, "/read", readReq, http.StatusUnauthorized, nil)
+
+	// Eve claims to be bob with her own signature.
+	forged := makeReadRequest(t, caly, id, eve)
+	forged.Identities[0].Identity = identity(t, bob)
+	do(t, mux, http.MethodPost, "/read", forged, http.StatusUnauthorized, nil)
+
+	unknown := []byte{0xaa}
+	do(t, mux, http.MethodPost, "/read", makeReadRequest(t, caly, unknown, bob),
+		http.StatusNotFound, nil)
+
+	do(t, mux, http.MethodPost, "/read", ReadRequest{ID: res.ID},
+		http.StatusBadRequest, nil)
+	do(t, mux, http.MethodGet, "/read", nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_Write_Invalid(t *testing.T) {
+	actor := newFakeActor()
+	mux := newMux(calypso.NewCalypso(actor))
+
+	req := makeWriteRequest(t, actor, []byte("secret"))
+
+	// The proof binds the access control, which can't be changed.
+	forged := req
+	forged.Readers = []string{identity(t, eve)}
+	do(t, mux, http.MethodPost, "/write", forged, http.StatusBadRequest, nil)
+
+	past := time.Now().Add(-time.Minute)
+	expired := req
+	expired.Expiry = &past
+	do(t, mux, http.MethodPost, "/write", expired, http.StatusBadRequest, nil)
+
+	noAdmin := req
+	noAdmin.Admin = ""
+	do(t, mux, http.MethodPost, "/write", noAdmin, http.StatusBadRequest, nil)
+
+	badPoint := req
+	badPoint.K = "zz"
+	do(t, mux, http.MethodPost, "/write", badPoint, http.StatusBadRequest, nil)
+
+	do(t, mux, http.MethodPost, "/write", map[string]string{"Unknown": ""},
+		http.StatusBadRequest, nil)
+	do(t, mux, http.MethodGet, "/write", nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_UpdateAccess(t *testing.T) {
+	actor := newFakeActor()
+	caly := calypso.NewCalypso(actor)
+	mux := newMux(caly)
+
+	var res WriteResponse
+	do(t, mux, http.MethodPost, "/write", makeWriteRequest(t, actor, []byte("secret")),
+		http.StatusCreated, &res)
+
+	id, err := hex.DecodeString(res.ID)
+	if err != nil {
+		t.Fatalf("invalid ID %q", res.ID)
+	}
+
+	// Only the admin can update the access.
+	do(t, mux, http.MethodPost, "/update-access",
+		makeUpdateRequest(t, caly, id, bob, bob), http.StatusForbidden, nil)
+
+	// Alice gives the record to bob, and can't read it anymore.
+	do(t, mux, http.MethodPost, "/update-access",
+		makeUpdateRequest(t, caly, id, alice, bob), http.StatusNoContent, nil)
+
+	do(t, mux, http.MethodPost, "/read", makeReadRequest(t, caly, id, alice),
+		http.StatusForbidden, nil)
+	do(t, mux, http.MethodPost, "/read", makeReadRequest(t, caly, id, bob),
+		http.StatusOK, nil)
+
+	do(t, mux, http.MethodPost, "/update-access",
+		makeUpdateRequest(t, caly, id, alice, alice), http.StatusForbidden, nil)
+
+	// The signature covers the new access control.
+	forged := makeUpdateRequest(t, caly, id, bob, bob)
+	forged.Admin = identity(t, eve)
+	do(t, mux, http.MethodPost, "/update-access", forged,
+		http.StatusUnauthorized, nil)
+
+	do(t, mux, http.MethodGet, "/update-access", nil,
+		http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_Record(t *testing.T) {
+	actor := newFakeActor()
+	store := &fakeStorage{KeyValue: inmemory.NewInMemory()}
+	caly := calypso.NewCalypso(actor, calypso.WithStorage(store))
+	mux := newMux(caly)
+
+	var res WriteResponse
+	do(t, mux, http.MethodPost, "/write", makeWriteRequest(t, actor, []byte("secret")),
+		http.StatusCreated, &res)
+
+	store.reads = 0
+
+	var record RecordResponse
+	do(t, mux, http.MethodGet, "/records/"+res.ID, nil, http.StatusOK, &record)
+
+	if record.ID != res.ID || record.Hybrid || record.Expiry != nil {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+
+	// The identities allowed by the access control are exposed.
+	if len(record.Access) == 0 {
+		t.Fatal("access is missing")
+	}
+
+	// A miss is answered from the local storage, which a replicated storage
+	// doesn't forward to the other members.
+	do(t, mux, http.MethodGet, "/records/aa", nil, http.StatusNotFound, nil)
+
+	if store.reads != 0 {
+		t.Fatalf("storage looked up %d times", store.reads)
+	}
+
+	id := storeExpired(t, store, actor)
+	do(t, mux, http.MethodGet, "/records/"+hex.EncodeToString(id), nil,
+		http.StatusGone, nil)
+
+	do(t, mux, http.MethodGet, "/records/zz", nil, http.StatusBadRequest, nil)
+	do(t, mux, http.MethodGet, "/records/", nil, http.StatusBadRequest, nil)
+	do(t, mux, http.MethodPost, "/records/"+res.ID, nil,
+		http.StatusMethodNotAllowed, nil)
+}
+
+func TestCtrl_Status(t *testing.T) {
+	// The status is only exposed when the node gathers it.
+	mux := newMux(calypso.NewCalypso(newFakeActor()))
+	do(t, mux, http.MethodGet, "/status", nil, http.StatusNotFound, nil)
+
+	status := StatusResponse{Phase: "ready", Threshold: 2, Records: 3}
+
+	mux = newMux(calypso.NewCalypso(newFakeActor()),
+		WithStatus(func() (StatusResponse, error) {
+			return status, nil
+		}))
+
+	var res StatusResponse
+	do(t, mux, http.MethodGet, "/status", nil, http.StatusOK, &res)
+
+	if res.Phase != status.Phase || res.Threshold != status.Threshold ||
+		res.Records != status.Records {
+
+		t.Fatalf("expected %+v, got %+v", status, res)
+	}
+
+	mux = newMux(calypso.NewCalypso(newFakeActor()),
+		WithStatus(func() (StatusResponse, error) {
+			return StatusResponse{}, xerrors.New("oops")
+		}))
+
+	do(t, mux, http.MethodGet, "/status", nil, http.StatusInternalServerError, nil)
+	do(t, mux, http.MethodPost, "/status", nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{calypso.ErrNotFound, http.StatusNotFound},
+		{calypso.ErrExpired, http.StatusGone},
+		{calypso.ErrUnauthenticated, http.StatusUnauthorized},
+		{calypso.ErrAccessDenied, http.StatusForbidden},
+		{calypso.ErrAlreadyExists, http.StatusConflict},
+		{calypso.ErrInvalidProof, http.StatusBadRequest},
+		{calypso.ErrInvalidCiphertext, http.StatusBadRequest},
+		{calypso.ErrReencryptNotSupported, http.StatusNotImplemented},
+		{replicated.ErrNotReplicated, http.StatusServiceUnavailable},
+		{xerrors.New("oops"), http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		code := ErrorCode(xerrors.Errorf("failed: %w", c.err))
+		if code != c.code {
+			t.Errorf("%v: expected %d, got %d", c.err, c.code, code)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func newMux(caly *calypso.Calypso, opts ...Option) *http.ServeMux {
+	mux := http.NewServeMux()
+	NewCtrl(caly, opts...).Register(mux.HandleFunc)
+
+	return mux
+}
+
+// do sends the request to the endpoint and checks the status code of the
+// response, which is decoded in res when it is not nil.
+func do(t *testing.T, mux *http.ServeMux, method, endpoint string,
+	req interface{}, code int, res interface{}) {
+
+	t.Helper()
+
+	var body bytes.Buffer
+
+	if req != nil {
+		err := json.NewEncoder(&body).Encode(req)
+		if err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, Prefix+endpoint, &body))
+
+	if rec.Code != code {
+		t.Fatalf("%s %s: expected %d, got %d: %s", method, endpoint, code,
+			rec.Code, rec.Body.String())
+	}
+
+	if res == nil {
+		return
+	}
+
+	err := json.NewDecoder(rec.Body).Decode(res)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
+
+// makeWriteRequest returns the request to write the message, readable by bob
+// and administered by alice.
+func makeWriteRequest(t *testing.T, actor fakeActor, message []byte) WriteRequest {
+	t.Helper()
+
+	k, K, C, err := calycrypto.Encrypt(message, actor.pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	proof, err := calypso.NewWriteProof(k, K, C, nil, time.Time{},
+		makeAccess(t, alice, bob))
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	return WriteRequest{
+		K:       hexPoint(t, K),
+		C:       hexPoint(t, C),
+		Proof:   hex.EncodeToString(proof),
+		Admin:   identity(t, alice),
+		Readers: []string{identity(t, bob)},
+	}
+}
+
+func makeReadRequest(t *testing.T, caly *calypso.Calypso, id []byte,
+	signer crypto.Signer) ReadRequest {
+
+	t.Helper()
+
+	nonce := challenge(t, caly, id)
+
+	nonceHex, idents := authenticate(t, nonce, calypso.ReadDigest(nonce, id), signer)
+
+	return ReadRequest{
+		ID:         hex.EncodeToString(id),
+		Nonce:      nonceHex,
+		Identities: idents,
+	}
+}
+
+// makeUpdateRequest returns the request of the signer to give the record to
+// the new admin.
+func makeUpdateRequest(t *testing.T, caly *calypso.Calypso, id []byte,
+	signer, admin crypto.Signer) UpdateAccessRequest {
+
+	t.Helper()
+
+	nonce := challenge(t, caly, id)
+
+	digest, err := calypso.UpdateDigest(nonce, id, makeAccess(t, admin))
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	nonceHex, idents := authenticate(t, nonce, digest, signer)
+
+	return UpdateAccessRequest{
+		ID:       hex.EncodeToString(id),
+		Nonce:    nonceHex,
+		Identity: idents[0],
+		Admin:    identity(t, admin),
+	}
+}
+
+func challenge(t *testing.T, caly *calypso.Calypso, id []byte) []byte {
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	return nonce
+}
+
+func authenticate(t *testing.T, nonce, digest []byte,
+	signers ...crypto.Signer) (string, []SignedIdentity) {
+
+	t.Helper()
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signers...)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	nonceHex, idents, err := EncodeAuthentication(auth)
+	if err != nil {
+		t.Fatalf("failed to encode authentication: %v", err)
+	}
+
+	return nonceHex, idents
+}
+
+// storeExpired stores an expired record directly, as the write refuses them,
+// and returns its ID.
+func storeExpired(t *testing.T, store storage.KeyValue, actor fakeActor) []byte {
+	t.Helper()
+
+	_, K, C, err := calycrypto.Encrypt([]byte("secret"), actor.pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	id, err := calypso.RecordID(K, C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	record := calypso.NewRecord(K, C, makeAccess(t, alice),
+		calypso.WithExpiry(time.Now().Add(-time.Minute)))
+
+	err = store.Batch(func(tx storage.Transaction) error {
+		return tx.Store(id, record)
+	})
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+
+	return id
+}
+
+func makeAccess(t *testing.T, admin crypto.Signer,
+	readers ...crypto.Signer) access.Service {
+
+	t.Helper()
+
+	ids := make([]access.Identity, len(readers))
+	for i, reader := range readers {
+		ids[i] = reader.GetPublicKey()
+	}
+
+	ac, err := calypso.NewAccess(admin.GetPublicKey(), ids...)
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	return ac
+}
+
+func identity(t *testing.T, signer crypto.Signer) string {
+	t.Helper()
+
+	text, err := signer.GetPublicKey().MarshalText()
+	if err != nil {
+		t.Fatalf("failed to marshal identity: %v", err)
+	}
+
+	return string(text)
+}
+
+func hexPoint(t *testing.T, point kyber.Point) string {
+	t.Helper()
+
+	str, err := encodePoint(point)
+	if err != nil {
+		t.Fatalf("failed to encode point: %v", err)
+	}
+
+	return str
+}
+
+// fakeStorage counts the lookups of the records outside of a snapshot, which
+// a replicated storage forwards to the other members on a miss.
+//
+// - implements storage.KeyValue
+type fakeStorage struct {
+	storage.KeyValue
+	reads int
+}
+
+func (s *fakeStorage) Read(key []byte) (serde.Message, error) {
+	s.reads++
+	return s.KeyValue.Read(key)
+}
+
+// fakeActor is a DKG actor that holds the whole private key.
+//
+// - implements dkg.Actor
+type fakeActor struct {
+	secret kyber.Scalar
+	pubkey kyber.Point
+}
+
+func newFakeActor() fakeActor {
+	secret := suite.Scalar().Pick(suite.RandomStream())
+
+	return fakeActor{
+		secret: secret,
+		pubkey: suite.Point().Mul(secret, nil),
+	}
+}
+
+func (a fakeActor) Setup(crypto.CollectiveAuthority, int) (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) GetPublicKey() (kyber.Point, error) {
+	return a.pubkey, nil
+}
+
+func (a fakeActor) Encrypt(message []byte) (K, C kyber.Point, remainder []byte,
+	err error) {
+
+	_, K, C, err = calycrypto.Encrypt(message, a.pubkey, random.New())
+
+	return K, C, nil, err
+}
+
+func (a fakeActor) Decrypt(K, C kyber.Point) ([]byte, error) {
+	return calycrypto.Decrypt(a.secret, K, C)
+}
+
+func (a fakeActor) Reshare() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import "net/http"
+
+// PubkeyResponse is the response of the public key endpoint.
+type PubkeyResponse struct {
+	// PublicKey is the hex encoded collective public key.
+	PublicKey string
+}
+
+// PubkeyHandler handles the public key requests
+func (c *Ctrl) PubkeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c.pubkeyGET(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only GET request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) pubkeyGET(w http.ResponseWriter, r *http.Request) {
+	pubkey, err := c.caly.GetPublicKey()
+	if err != nil {
+		renderError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	pubkeyHex, err := encodePoint(pubkey)
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	renderJSON(w, http.StatusOK, PubkeyResponse{PublicKey: pubkeyHex})
+}
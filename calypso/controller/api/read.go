@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+)
+
+// ReadRequest is the request to read a secret.
+type ReadRequest struct {
+	// ID is the hex encoded ID of the record.
+	ID string
+	// Nonce is the hex encoded nonce of a challenge.
+	Nonce string
+	// Identities are the identities that signed the read digest of the
+	// record and the nonce.
+	Identities []SignedIdentity
+}
+
+// ReadResponse is the response of a successful read.
+type ReadResponse struct {
+	// Message is the hex encoded decrypted message.
+	Message string
+}
+
+// ReadHandler handles the read requests
+func (c *Ctrl) ReadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.readPOST(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only POST request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) readPOST(w http.ResponseWriter, r *http.Request) {
+	var req ReadRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := hex.DecodeString(req.ID)
+	if err != nil || len(id) == 0 {
+		renderError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+
+	auth, err := DecodeAuthentication(req.Nonce, req.Identities...)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	msg, err := c.caly.Read(id, auth)
+	if err != nil {
+		renderError(w, ErrorCode(err), err.Error())
+		return
+	}
+
+	renderJSON(w, http.StatusOK, ReadResponse{Message: hex.EncodeToString(msg)})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso/arc"
+)
+
+// RecordResponse is the metadata of a record. The secret is not included.
+type RecordResponse struct {
+	ID string
+	K  string
+	C  string
+	// Hybrid is true when (K, C) protects the symmetric key of the message.
+	Hybrid bool
+	// Access are the identities allowed for each rule, when the access
+	// control supports it.
+	Access map[string][]string `json:",omitempty"`
+	// Expiry is the time after which the record can't be read anymore, if
+	// any.
+	Expiry *time.Time `json:",omitempty"`
+}
+
+// RecordHandler handles the requests of the metadata of a record, whose hex
+// encoded ID is the last element of the path.
+func (c *Ctrl) RecordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c.recordGET(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only GET request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) recordGET(w http.ResponseWriter, r *http.Request) {
+	idHex := strings.TrimPrefix(r.URL.Path, Prefix+"/records/")
+
+	id, err := hex.DecodeString(idHex)
+	if err != nil || len(id) == 0 {
+		renderError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+
+	record, err := c.caly.GetRecord(id)
+	if err != nil {
+		renderError(w, ErrorCode(err), err.Error())
+		return
+	}
+
+	kHex, err := encodePoint(record.GetK())
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cHex, err := encodePoint(record.GetC())
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res := RecordResponse{
+		ID:     idHex,
+		K:      kHex,
+		C:      cHex,
+		Hybrid: len(record.GetData()) > 0,
+	}
+
+	if !record.GetExpiry().IsZero() {
+		expiry := record.GetExpiry()
+		res.Expiry = &expiry
+	}
+
+	ac, ok := record.GetAccess().(*arc.Service)
+	if ok {
+		res.Access = ac.GetRules()
+	}
+
+	renderJSON(w, http.StatusOK, res)
+}
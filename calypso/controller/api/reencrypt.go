@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"golang.org/x/xerrors"
+)
+
+// ReadReencryptedRequest is the request to read a secret re-encrypted under
+// the public key of the reader.
+type ReadReencryptedRequest struct {
+	// ID is the hex encoded ID of the record.
+	ID string
+	// PublicKey is the hex encoded Ed25519 point of the reader, under which
+	// the secret is re-encrypted.
+	PublicKey string
+	// KeyProof is the hex encoded proof that the reader knows the private key
+	// of the public key, as created by calypso.NewReaderKeyProof.
+	KeyProof string
+	// Nonce is the hex encoded nonce of a challenge.
+	Nonce string
+	// Identities are the identities that signed the re-encryption digest of
+	// the record, the public key, the key proof and the nonce.
+	Identities []SignedIdentity
+}
+
+// ReadReencryptedResponse is the response of a successful re-encrypted read.
+// The message is recovered by the reader with calypso.DecryptReencrypted.
+type ReadReencryptedResponse struct {
+	// XhatEnc is the hex encoded shared secret re-encrypted under the public
+	// key of the reader.
+	XhatEnc string
+	// C is the hex encoded point of the record.
+	C string
+	// Data is the hex encoded sealed message of the hybrid mode, if any.
+	Data string `json:",omitempty"`
+}
+
+// ReadReencryptedHandler handles the re-encrypted read requests
+func (c *Ctrl) ReadReencryptedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.readReencryptedPOST(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only POST request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) readReencryptedPOST(w http.ResponseWriter, r *http.Request) {
+	var req ReadReencryptedRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := hex.DecodeString(req.ID)
+	if err != nil || len(id) == 0 {
+		renderError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+
+	pubk, err := decodePoint(req.PublicKey)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "invalid public key: "+err.Error())
+		return
+	}
+
+	keyProof, err := hex.DecodeString(req.KeyProof)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "invalid key proof")
+		return
+	}
+
+	auth, err := DecodeAuthentication(req.Nonce, req.Identities...)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, err := c.caly.ReadReencrypted(id, pubk, keyProof, auth)
+	if err != nil {
+		renderError(w, ErrorCode(err), err.Error())
+		return
+	}
+
+	xhatEnc, err := encodePoint(secret.GetXhatEnc())
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cHex, err := encodePoint(secret.GetC())
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res := ReadReencryptedResponse{
+		XhatEnc: xhatEnc,
+		C:       cHex,
+		Data:    hex.EncodeToString(secret.GetData()),
+	}
+
+	renderJSON(w, http.StatusOK, res)
+}
+
+// Decode returns the re-encrypted secret of the response.
+func (res ReadReencryptedResponse) Decode() (calypso.Reencrypted, error) {
+	xhatEnc, err := decodePoint(res.XhatEnc)
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("XhatEnc: %v", err)
+	}
+
+	C, err := decodePoint(res.C)
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("C: %v", err)
+	}
+
+	data, err := hex.DecodeString(res.Data)
+	if err != nil {
+		return calypso.Reencrypted{}, xerrors.Errorf("data: %v", err)
+	}
+
+	if len(data) == 0 {
+		data = nil
+	}
+
+	return calypso.NewReencrypted(xhatEnc, C, data), nil
+}
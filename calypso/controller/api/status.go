@@ -0,0 +1,69 @@
+package api
+
+import "net/http"
+
+// StatusResponse is the response of the status endpoint.
+type StatusResponse struct {
+	// Phase is the phase of the DKG on the node: created, listening or ready.
+	Phase string
+
+	// Threshold is the number of members needed to decrypt, once the node is
+	// ready.
+	Threshold int `json:",omitempty"`
+
+	// Members is the committee that holds the distributed key, once the node
+	// is ready.
+	Members []StatusMember `json:",omitempty"`
+
+	// Fingerprint is a short hex digest of the collective public key, once the
+	// node is ready.
+	Fingerprint string `json:",omitempty"`
+
+	// Records is the number of records stored on the node.
+	Records int
+
+	// Expired is the number of records stored on the node that have expired
+	// and are waiting to be deleted.
+	Expired int
+
+	// Swept is the number of expired records deleted since the node started.
+	Swept int
+
+	// Backend is the storage backend of the records: disk or memory.
+	Backend string
+}
+
+// StatusMember is a member of the committee in the status.
+type StatusMember struct {
+	// Address is the text form of the address, base64 encoded as in the
+	// roster of the setup.
+	Address []byte
+
+	// PublicKey is the hex encoded DKG public key of the member.
+	PublicKey string
+}
+
+// StatusFunc returns the status of the node.
+type StatusFunc func() (StatusResponse, error)
+
+// StatusHandler handles the status requests
+func (c *Ctrl) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c.statusGET(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only GET request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) statusGET(w http.ResponseWriter, r *http.Request) {
+	status, err := c.status()
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	renderJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+)
+
+// UpdateAccessRequest is the request to replace the access control of a
+// record.
+type UpdateAccessRequest struct {
+	// ID is the hex encoded ID of the record.
+	ID string
+	// Nonce is the hex encoded nonce of a challenge.
+	Nonce string
+	// Identity is the identity allowed to update the record, which signed
+	// the update digest of the new access control and the nonce.
+	Identity SignedIdentity
+	// Admin is the new identity allowed to update and read the record.
+	Admin string
+	// Readers are the new identities allowed to read the record.
+	Readers []string `json:",omitempty"`
+}
+
+// UpdateAccessHandler handles the update of the access of a record
+func (c *Ctrl) UpdateAccessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.updateAccessPOST(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only POST request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) updateAccessPOST(w http.ResponseWriter, r *http.Request) {
+	var req UpdateAccessRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := hex.DecodeString(req.ID)
+	if err != nil || len(id) == 0 {
+		renderError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+
+	auth, err := DecodeAuthentication(req.Nonce, req.Identity)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ac, err := newAccess(req.Admin, req.Readers)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = c.caly.UpdateAccess(id, auth, ac)
+	if err != nil {
+		renderError(w, ErrorCode(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/gui/models"
+	"go.dedis.ch/dela/core/access"
+	"golang.org/x/xerrors"
+)
+
+// WriteRequest is the request to write a secret. The binary fields are hex
+// encoded.
+type WriteRequest struct {
+	K     string
+	C     string
+	Data  string `json:",omitempty"`
+	Proof string
+	// Admin is the identity allowed to update and read the record, as a
+	// public key in its text form.
+	Admin string
+	// Readers are the identities allowed to read the record, in the same
+	// form.
+	Readers []string `json:",omitempty"`
+	// Expiry is the optional time after which the record can't be read
+	// anymore, in RFC 3339 format.
+	Expiry *time.Time `json:",omitempty"`
+}
+
+// WriteResponse is the response of a successful write.
+type WriteResponse struct {
+	// ID is the hex encoded ID of the record.
+	ID string
+}
+
+// WriteHandler handles the write requests
+func (c *Ctrl) WriteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.writePOST(w, r)
+		default:
+			renderError(w, http.StatusMethodNotAllowed, "only POST request allowed")
+		}
+	}
+}
+
+func (c *Ctrl) writePOST(w http.ResponseWriter, r *http.Request) {
+	var req WriteRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	em, ac, err := req.Decode()
+	if err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Expiry != nil && !req.Expiry.After(time.Now()) {
+		renderError(w, http.StatusBadRequest, "expiry is in the past")
+		return
+	}
+
+	id, err := c.caly.Write(em, ac, req.Options()...)
+	if err != nil {
+		renderError(w, ErrorCode(err), err.Error())
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, WriteResponse{ID: hex.EncodeToString(id)})
+}
+
+// Decode returns the encrypted message and the access control of the request.
+func (req WriteRequest) Decode() (calypso.EncryptedMessage, access.Service, error) {
+	K, err := decodePoint(req.K)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("invalid K: %v", err)
+	}
+
+	C, err := decodePoint(req.C)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("invalid C: %v", err)
+	}
+
+	data, err := hex.DecodeString(req.Data)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("invalid data: %v", err)
+	}
+
+	proof, err := hex.DecodeString(req.Proof)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("invalid proof: %v", err)
+	}
+
+	if len(data) == 0 {
+		data = nil
+	}
+
+	ac, err := newAccess(req.Admin, req.Readers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return models.NewEncryptedMsg(K, C, data, proof), ac, nil
+}
+
+// Options returns the options of the write of the request.
+func (req WriteRequest) Options() []calypso.WriteOption {
+	if req.Expiry == nil {
+		return nil
+	}
+
+	return []calypso.WriteOption{calypso.ExpireAt(*req.Expiry)}
+}
+
+// newAccess returns the access control of the admin and the readers, whose
+// identities are public keys in their text form.
+func newAccess(admin string, readers []string) (access.Service, error) {
+	if admin == "" {
+		return nil, xerrors.New("admin identity is empty")
+	}
+
+	owner, err := calypso.ParseIdentity(admin)
+	if err != nil {
+		return nil, xerrors.Errorf("admin: %v", err)
+	}
+
+	readIDs := make([]access.Identity, len(readers))
+	for i, reader := range readers {
+		readIDs[i], err = calypso.ParseIdentity(reader)
+		if err != nil {
+			return nil, xerrors.Errorf("reader %d: %v", i, err)
+		}
+	}
+
+	ac, err := calypso.NewAccess(owner, readIDs...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create access: %v", err)
+	}
+
+	return ac, nil
+}
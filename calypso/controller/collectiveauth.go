@@ -1,38 +1,69 @@
 package controller
 
 import (
-	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/crypto/ed25519"
 	"go.dedis.ch/dela/mino"
 )
 
-// internalCA is the internal Collective Authority built from the CLI flags
+// internalCA is the internal Collective Authority built from the roster given
+// to the setup. The addresses and the public keys are stored in the same
+// order.
 //
 // - implements crypto.CollectiveAuthority
 type internalCA struct {
-	players mino.Players
+	addrs   []mino.Address
 	pubkeys []ed25519.PublicKey
 }
 
+// newInternalCA returns a new collective authority. The two lists must have
+// the same length.
+func newInternalCA(addrs []mino.Address, pubkeys []ed25519.PublicKey) internalCA {
+	return internalCA{
+		addrs:   addrs,
+		pubkeys: pubkeys,
+	}
+}
+
 // Len implements mino.Players
 func (ca internalCA) Len() int {
-	return ca.players.Len()
+	return len(ca.addrs)
 }
 
-// Take implements mino.Players
+// Take implements mino.Players. It returns the collective authority of the
+// members selected by the filters.
 func (ca internalCA) Take(fs ...mino.FilterUpdater) mino.Players {
-	return ca.players.Take(fs...)
+	filter := mino.ApplyFilters(fs)
+
+	res := internalCA{
+		addrs:   make([]mino.Address, len(filter.Indices)),
+		pubkeys: make([]ed25519.PublicKey, len(filter.Indices)),
+	}
+
+	for i, k := range filter.Indices {
+		res.addrs[i] = ca.addrs[k]
+		res.pubkeys[i] = ca.pubkeys[k]
+	}
+
+	return res
 }
 
-// AddressIterator implements mino.AddressIterator
+// AddressIterator implements mino.Players
 func (ca internalCA) AddressIterator() mino.AddressIterator {
-	return ca.players.AddressIterator()
+	return mino.NewAddresses(ca.addrs...).AddressIterator()
 }
 
+// GetPublicKey implements crypto.CollectiveAuthority. It returns the public
+// key of the address and its index, or nil and -1 if the address is not a
+// member.
 func (ca internalCA) GetPublicKey(addr mino.Address) (crypto.PublicKey, int) {
-	dela.Logger.Fatal().Msg("GetPublicKey not implemented")
-	return nil, 0
+	for i, a := range ca.addrs {
+		if a.Equal(addr) {
+			return ca.pubkeys[i], i
+		}
+	}
+
+	return nil, -1
 }
 
 // PublicKeyIterator implements crypto.CollectiveAuthority
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minogrpc/session"
+)
+
+func TestInternalCA_GetPublicKey(t *testing.T) {
+	ca, addrs, pubkeys := makeCA(3)
+
+	testCases := []struct {
+		name  string
+		addr  mino.Address
+		index int
+	}{
+		{"first", addrs[0], 0},
+		{"last", addrs[2], 2},
+		{"same text", session.AddressFactory{}.FromText([]byte("F127.0.0.1:2001")), 1},
+		{"unknown", session.NewAddress("127.0.0.1:3000"), -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pubkey, index := ca.GetPublicKey(tc.addr)
+
+			if index != tc.index {
+				t.Fatalf("expected index %d, got %d", tc.index, index)
+			}
+
+			if tc.index < 0 {
+				if pubkey != nil {
+					t.Fatalf("expected no public key, got %v", pubkey)
+				}
+
+				return
+			}
+
+			if !pubkey.Equal(pubkeys[tc.index]) {
+				t.Fatalf("wrong public key for %v", tc.addr)
+			}
+		})
+	}
+}
+
+func TestInternalCA_Take(t *testing.T) {
+	ca, addrs, pubkeys := makeCA(4)
+
+	sub := ca.Take(mino.RangeFilter(1, 3)).(internalCA)
+
+	if sub.Len() != 2 {
+		t.Fatalf("expected 2 members, got %d", sub.Len())
+	}
+
+	it := sub.AddressIterator()
+	pkIt := sub.PublicKeyIterator()
+
+	for i := 1; i < 3; i++ {
+		if !it.HasNext() || !pkIt.HasNext() {
+			t.Fatalf("missing member %d", i)
+		}
+
+		if !it.GetNext().Equal(addrs[i]) {
+			t.Fatalf("wrong address for member %d", i)
+		}
+
+		if !pkIt.GetNext().Equal(pubkeys[i]) {
+			t.Fatalf("wrong public key for member %d", i)
+		}
+	}
+
+	if it.HasNext() || pkIt.HasNext() {
+		t.Fatal("unexpected member")
+	}
+
+	pkIt.Seek(1)
+
+	if !pkIt.GetNext().Equal(pubkeys[2]) {
+		t.Fatal("wrong public key after seek")
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeCA(n int) (internalCA, []mino.Address, []ed25519.PublicKey) {
+	addrs := make([]mino.Address, n)
+	pubkeys := make([]ed25519.PublicKey, n)
+
+	for i := range addrs {
+		addrs[i] = session.NewAddress(fmt.Sprintf("127.0.0.1:%d", 2000+i))
+		pubkeys[i] = ed25519.NewPublicKeyFromPoint(
+			suite.Point().Pick(suite.RandomStream()))
+	}
+
+	return newInternalCA(addrs, pubkeys), addrs, pubkeys
+}
@@ -0,0 +1,379 @@
+// Encryption of the Calypso secrets in the browser. The message is encrypted
+// with the collective public key in the same format as crypto.EncryptHybrid
+// so that the plaintext never leaves the browser: it is ElGamal-encrypted on
+// Ed25519 when it fits in a point, otherwise it is sealed with AES-GCM under a
+// random key that is ElGamal-encrypted instead.
+//
+// The points and the scalars are encoded as in Kyber, which is the encoding of
+// RFC 8032 with the scalars in little endian.
+(function (root) {
+  "use strict";
+
+  // P is the prime of the field and L the order of the base point.
+  const P = 2n ** 255n - 19n;
+  const L = 2n ** 252n + 27742317777372353535851937790883648493n;
+  const D = mod(-121665n * inv(121666n));
+  const SQRT_M1 = pow(2n, (P - 1n) / 4n);
+
+  // EMBED_LEN is the number of bytes that can be embedded in a point.
+  const EMBED_LEN = 29;
+  // KEY_SIZE is the size of the symmetric key of the hybrid encryption.
+  const KEY_SIZE = 16;
+  // NONCE_SIZE is the size of the AES-GCM nonce prepended to the data.
+  const NONCE_SIZE = 12;
+
+  const ARC_RULE_READ = "calypso_read";
+  const ARC_RULE_UPDATE = "calypso_update";
+
+  const IDENTITY = { X: 0n, Y: 1n, Z: 1n, T: 0n };
+  const BASE = decodePoint(fromHex(
+    "5866666666666666666666666666666666666666666666666666666666666666"));
+
+  // ---------------------------------------------------------------------------
+  // Field and scalars
+
+  function mod(a, m = P) {
+    const r = a % m;
+    return r >= 0n ? r : r + m;
+  }
+
+  function pow(b, e, m = P) {
+    let r = 1n;
+    b = mod(b, m);
+    while (e > 0n) {
+      if (e & 1n) {
+        r = mod(r * b, m);
+      }
+      b = mod(b * b, m);
+      e >>= 1n;
+    }
+    return r;
+  }
+
+  function inv(a) {
+    return pow(a, P - 2n);
+  }
+
+  function toInt(bytes) {
+    let n = 0n;
+    for (let i = bytes.length - 1; i >= 0; i--) {
+      n = (n << 8n) | BigInt(bytes[i]);
+    }
+    return n;
+  }
+
+  function toBytes(n, size = 32) {
+    const bytes = new Uint8Array(size);
+    for (let i = 0; i < size; i++) {
+      bytes[i] = Number(n & 0xffn);
+      n >>= 8n;
+    }
+    return bytes;
+  }
+
+  // randomScalar picks a scalar from 64 random bytes so that the reduction
+  // has a negligible bias.
+  function randomScalar(rand) {
+    return mod(toInt(rand(64)), L);
+  }
+
+  // ---------------------------------------------------------------------------
+  // Points, in extended coordinates
+
+  function add(p, q) {
+    const a = mod((p.Y - p.X) * (q.Y - q.X));
+    const b = mod((p.Y + p.X) * (q.Y + q.X));
+    const c = mod(2n * D * p.T * q.T);
+    const d = mod(2n * p.Z * q.Z);
+    const e = b - a;
+    const f = d - c;
+    const g = d + c;
+    const h = b + a;
+    return { X: mod(e * f), Y: mod(g * h), Z: mod(f * g), T: mod(e * h) };
+  }
+
+  function sub(p, q) {
+    return add(p, { X: mod(-q.X), Y: q.Y, Z: q.Z, T: mod(-q.T) });
+  }
+
+  function mul(k, p) {
+    let r = IDENTITY;
+    for (let i = BigInt(k.toString(2).length) - 1n; i >= 0n; i--) {
+      r = add(r, r);
+      if ((k >> i) & 1n) {
+        r = add(r, p);
+      }
+    }
+    return r;
+  }
+
+  function equal(p, q) {
+    return mod(p.X * q.Z - q.X * p.Z) === 0n &&
+      mod(p.Y * q.Z - q.Y * p.Z) === 0n;
+  }
+
+  function encodePoint(p) {
+    const zi = inv(p.Z);
+    const x = mod(p.X * zi);
+    const bytes = toBytes(mod(p.Y * zi));
+    if (x & 1n) {
+      bytes[31] |= 0x80;
+    }
+    return bytes;
+  }
+
+  // decodePoint returns the point of the encoding, or null if it is not on the
+  // curve. As in Kyber, the point is not checked to be in the subgroup.
+  function decodePoint(bytes) {
+    const buf = Uint8Array.from(bytes);
+    const sign = BigInt(buf[31] >> 7);
+    buf[31] &= 0x7f;
+
+    const y = mod(toInt(buf));
+    const u = mod(y * y - 1n);
+    const v = mod(D * y * y + 1n);
+
+    const v3 = mod(v * v * v);
+    let x = mod(u * v3 * pow(u * v3 * v3 * v, (P - 5n) / 8n));
+
+    const vxx = mod(v * x * x);
+    if (vxx !== u) {
+      if (vxx !== mod(-u)) {
+        return null;
+      }
+      x = mod(x * SQRT_M1);
+    }
+
+    if ((x & 1n) !== sign) {
+      x = mod(-x);
+    }
+
+    return { X: x, Y: y, Z: 1n, T: mod(x * y) };
+  }
+
+  // embed returns a point of the subgroup holding the data, which is retried
+  // with new random bytes until one is found, like Kyber.
+  function embed(data, rand) {
+    const dl = Math.min(data.length, EMBED_LEN);
+
+    for (;;) {
+      const bytes = rand(32);
+      bytes[0] = dl;
+      bytes.set(data.subarray(0, dl), 1);
+
+      const p = decodePoint(bytes);
+      if (p !== null && equal(mul(L, p), IDENTITY)) {
+        return p;
+      }
+    }
+  }
+
+  // ---------------------------------------------------------------------------
+  // Encryption
+
+  function defaultRand(n) {
+    return root.crypto.getRandomValues(new Uint8Array(n));
+  }
+
+  // encrypt ElGamal-encrypts the message, which must fit in a point.
+  function encrypt(message, pubkey, rand) {
+    const M = embed(message, rand);
+    const k = randomScalar(rand);
+    const K = mul(k, BASE);
+    const C = add(mul(k, pubkey), M);
+
+    return { k: k, K: K, C: C };
+  }
+
+  // encryptHybrid encrypts the message with the public key. It resolves to the
+  // ephemeral scalar, K and C, and to the sealed message when it doesn't fit in
+  // a point.
+  async function encryptHybrid(message, pubkey, rand = defaultRand) {
+    if (message.length <= EMBED_LEN) {
+      return Object.assign(encrypt(message, pubkey, rand), { data: null });
+    }
+
+    const key = rand(KEY_SIZE);
+    const nonce = rand(NONCE_SIZE);
+
+    const aesKey = await root.crypto.subtle.importKey("raw", key, "AES-GCM",
+      false, ["encrypt"]);
+    const sealed = await root.crypto.subtle.encrypt(
+      { name: "AES-GCM", iv: nonce }, aesKey, message);
+
+    const data = concat(nonce, new Uint8Array(sealed));
+
+    return Object.assign(encrypt(key, pubkey, rand), { data: data });
+  }
+
+  // newWriteProof returns the proof of knowledge of k expected by the write,
+  // bound to C, to the data, to the expiry and to the policy. The data and
+  // the expiry, a Date, may be null. See calypso.NewWriteProof.
+  async function newWriteProof(k, K, C, data, expiry, policy,
+    rand = defaultRand) {
+
+    const r = randomScalar(rand);
+    const R = mul(r, BASE);
+
+    const sealed = data === null ? new Uint8Array(0) : data;
+    const nanos = expiry === null ? 0n : BigInt(expiry.getTime()) * 1000000n;
+
+    const digest = await root.crypto.subtle.digest("SHA-256", concat(
+      encodePoint(K), encodePoint(C), encodePoint(R),
+      uint64(BigInt(sealed.length)), sealed, uint64(nanos),
+      new TextEncoder().encode(policy)));
+
+    const e = mod(toInt(new Uint8Array(digest)), L);
+    const s = mod(r + e * k, L);
+
+    return concat(toBytes(e), toBytes(s));
+  }
+
+  // policy returns the JSON serialization of the access control created by
+  // calypso.NewAccess, with the same identities in the same order.
+  function policy(admin, readers) {
+    const rules = {};
+    rules[ARC_RULE_UPDATE] = [admin];
+    rules[ARC_RULE_READ] = [admin];
+
+    for (const reader of readers) {
+      if (reader !== "" && !rules[ARC_RULE_READ].includes(reader)) {
+        rules[ARC_RULE_READ].push(reader);
+      }
+    }
+
+    const entries = Object.keys(rules).sort().map(function (rule) {
+      return quote(rule) + ":[" + rules[rule].map(quote).join(",") + "]";
+    });
+
+    return "{\"Rules\":{" + entries.join(",") + "}}";
+  }
+
+  // quote quotes the string like encoding/json of Go, which also escapes the
+  // HTML characters.
+  function quote(str) {
+    return JSON.stringify(str).replace(/[<>&\u2028\u2029]/g, function (c) {
+      return "\\u" + c.charCodeAt(0).toString(16).padStart(4, "0");
+    });
+  }
+
+  // normalizeIdentity returns the identity as its text form is marshaled by
+  // the node, which writes the hex in lower case.
+  function normalizeIdentity(identity) {
+    return identity.trim().toLowerCase();
+  }
+
+  // ---------------------------------------------------------------------------
+  // Encoding
+
+  function concat(...arrays) {
+    const out = new Uint8Array(arrays.reduce((n, a) => n + a.length, 0));
+    let offset = 0;
+    for (const a of arrays) {
+      out.set(a, offset);
+      offset += a.length;
+    }
+    return out;
+  }
+
+  // uint64 returns the 8 bytes of the integer in big-endian order, as
+  // encoding/binary of Go.
+  function uint64(n) {
+    return toBytes(BigInt.asUintN(64, n), 8).reverse();
+  }
+
+  function toHex(bytes) {
+    return Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("");
+  }
+
+  function fromHex(str) {
+    if (str.length % 2 !== 0 || !/^[0-9a-fA-F]*$/.test(str)) {
+      throw new Error("invalid hex string");
+    }
+
+    const bytes = new Uint8Array(str.length / 2);
+    for (let i = 0; i < bytes.length; i++) {
+      bytes[i] = parseInt(str.substr(2 * i, 2), 16);
+    }
+    return bytes;
+  }
+
+  // ---------------------------------------------------------------------------
+  // Page
+
+  // bindEncryptForm encrypts the message of the form when it is submitted and
+  // fills the fields of the write. The message and the public key have no
+  // name, so they are never posted.
+  function bindEncryptForm(form) {
+    const status = form.querySelector(".postmessage");
+
+    form.addEventListener("submit", async function (event) {
+      event.preventDefault();
+
+      try {
+        const pubkey = decodePoint(fromHex(form.elements.pubkey.value.trim()));
+        if (pubkey === null) {
+          throw new Error("invalid public key");
+        }
+
+        const admin = normalizeIdentity(form.elements.adminID.value);
+        const readers = Array.from(form.querySelectorAll("[name=readID]"),
+          (input) => normalizeIdentity(input.value));
+
+        const message = new TextEncoder().encode(form.elements.message.value);
+
+        const { k, K, C, data } = await encryptHybrid(message, pubkey);
+        const proof = await newWriteProof(k, K, C, data, null,
+          policy(admin, readers));
+
+        form.elements.adminID.value = admin;
+        form.querySelectorAll("[name=readID]").forEach(function (input, i) {
+          input.value = readers[i];
+        });
+
+        form.elements.k.value = toHex(encodePoint(K));
+        form.elements.c.value = toHex(encodePoint(C));
+        form.elements.data.value = data === null ? "" : toHex(data);
+        form.elements.proof.value = toHex(proof);
+
+        form.submit();
+      } catch (err) {
+        status.textContent = "Failed to encrypt: " + err.message;
+        status.hidden = false;
+      }
+    });
+  }
+
+  const calypso = {
+    BASE: BASE,
+    L: L,
+    add: add,
+    sub: sub,
+    mul: mul,
+    equal: equal,
+    encodePoint: encodePoint,
+    decodePoint: decodePoint,
+    embed: embed,
+    encryptHybrid: encryptHybrid,
+    newWriteProof: newWriteProof,
+    policy: policy,
+    toBytes: toBytes,
+    toInt: toInt,
+    toHex: toHex,
+    fromHex: fromHex,
+  };
+
+  if (typeof module === "object" && module.exports) {
+    module.exports = calypso;
+    return;
+  }
+
+  root.calypso = calypso;
+
+  document.addEventListener("DOMContentLoaded", function () {
+    const form = document.getElementById("encrypt");
+    if (form !== null) {
+      bindEncryptForm(form);
+    }
+  });
+})(typeof self !== "undefined" ? self : globalThis);
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+)
+
+// AuditHandler handles the queries of the audit log of a record. Only the
+// admin of the record can query it: the form holds the ID of the record in
+// hex ("id"), the nonce of a challenge for the record ("nonce"), and the
+// identity of the admin ("identity") with its signature of the audit digest
+// ("signature"). The entries are returned in JSON.
+func (c Ctrl) AuditHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			c.auditPOST(w, r)
+		default:
+			http.Error(w, "only POST request allowed", http.StatusBadRequest)
+		}
+	}
+}
+
+func (c Ctrl) auditPOST(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := hex.DecodeString(r.PostForm.Get("id"))
+	if err != nil || len(id) == 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	auth, err := api.DecodeAuthentication(r.PostForm.Get("nonce"),
+		api.SignedIdentity{
+			Identity:  r.PostForm.Get("identity"),
+			Signature: r.PostForm.Get("signature"),
+		})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := c.caly.Audit(id, auth)
+	if err != nil {
+		http.Error(w, "failed to query: "+err.Error(), api.ErrorCode(err))
+		return
+	}
+
+	js, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		http.Error(w, "failed to marshal entries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// challenge is the challenge displayed by the forms that require the
+// identities to sign the digest of the request.
+type challenge struct {
+	// Nonce is the hex encoded nonce to include in the digest.
+	Nonce string
+	// Expiry is the time after which the nonce is refused, in RFC 3339
+	// format.
+	Expiry string
+}
+
+// newChallenge issues a new challenge for the form of a record.
+func (c Ctrl) newChallenge(id []byte) (challenge, error) {
+	nonce, expiry, err := c.caly.Challenge(id)
+	if err != nil {
+		return challenge{}, xerrors.Errorf("failed to issue challenge: %v", err)
+	}
+
+	ch := challenge{
+		Nonce:  hex.EncodeToString(nonce),
+		Expiry: expiry.Format(time.RFC3339),
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// csrfCookie is the name of the cookie that holds the random value the
+	// CSRF tokens are derived from.
+	csrfCookie = "calypso_csrf"
+
+	// csrfField is the name of the form field of the CSRF token.
+	csrfField = "csrf"
+)
+
+// csrfToken returns the CSRF token to include in the forms of the page. It
+// sets the cookie of the browser if it doesn't have one yet.
+//
+// The token is the HMAC of the cookie with a key of the controller, so that a
+// page of another origin, which can't read the cookie, can't forge it.
+func (c Ctrl) csrfToken(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		value := make([]byte, 32)
+		random.Bytes(value, random.New())
+
+		cookie = &http.Cookie{
+			Name:     csrfCookie,
+			Value:    hex.EncodeToString(value),
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		}
+
+		http.SetCookie(w, cookie)
+	}
+
+	return c.csrfMAC(cookie.Value)
+}
+
+// verifyCSRF returns an error if the token of the form doesn't match the
+// cookie of the request. The form must have been parsed.
+func (c Ctrl) verifyCSRF(r *http.Request) error {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return xerrors.New("missing CSRF cookie")
+	}
+
+	token := r.PostForm.Get(csrfField)
+
+	if !hmac.Equal([]byte(token), []byte(c.csrfMAC(cookie.Value))) {
+		return xerrors.New("invalid CSRF token")
+	}
+
+	return nil
+}
+
+func (c Ctrl) csrfMAC(value string) string {
+	mac := hmac.New(sha256.New, c.csrfKey)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -2,151 +2,48 @@ package controllers
 
 import (
 	"encoding/hex"
-	"fmt"
 	"net/http"
-	"text/template"
-
-	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/util/random"
 )
 
-// EncryptHandler handles the encryption
+// EncryptHandler handles the encryption. The page encrypts the message in the
+// browser and posts only the ciphertext to the write, so that the node never
+// sees the plaintext.
 func (c Ctrl) EncryptHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			c.encryptGET(w, r)
-		case http.MethodPost:
-			c.encryptPOST(w, r)
 		default:
-			c.renderHTTPError(w, "only GET and POST requests allowed", http.StatusBadRequest)
+			c.renderHTTPError(w, "only GET request allowed", http.StatusBadRequest)
 		}
 	}
 }
 
 func (c Ctrl) encryptGET(w http.ResponseWriter, r *http.Request) {
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/encrypt.gohtml"))
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var viewData = struct {
-		Title       string
-		PostMessage string
-	}{
-		"Encrypt a message",
-		"",
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-func (c Ctrl) encryptPOST(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	message := r.PostForm.Get("message")
-	if message == "" {
-		c.renderHTTPError(w, "message empty", http.StatusBadRequest)
-		return
-	}
-
-	pubkeyStr := r.PostForm.Get("pubkey")
-	if pubkeyStr == "" {
-		c.renderHTTPError(w, "pubkey empty", http.StatusBadRequest)
-		return
-	}
-
-	pubkeyBuf, err := hex.DecodeString(pubkeyStr)
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	pubkey := suite.Point()
-	err = pubkey.UnmarshalBinary(pubkeyBuf)
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	kPoint, cPoint, remainder, err := encrypt([]byte(message), pubkey)
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if len(remainder) != 0 {
-		c.renderHTTPError(w, fmt.Sprintf("message too long, %d bytes could not "+
-			"be embedded", len(remainder)), http.StatusBadRequest)
-		return
-	}
-
-	kBuf, err := kPoint.MarshalBinary()
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	cBuf, err := cPoint.MarshalBinary()
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	khex := hex.EncodeToString(kBuf)
-	chex := hex.EncodeToString(cBuf)
+	// The collective key is only a default, as the page can be loaded before
+	// the DKG is setup.
+	var pubkeyHex string
+
+	pubkey, err := c.caly.GetPublicKey()
+	if err == nil {
+		pubkeyBuf, err := pubkey.MarshalBinary()
+		if err != nil {
+			c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/encrypt.gohtml"))
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
+		pubkeyHex = hex.EncodeToString(pubkeyBuf)
 	}
 
-	viewMessage := fmt.Sprintf("Message encrypted!\nPlease save those "+
-		"information:\nK: %s\nC: %s", khex, chex)
-
 	var viewData = struct {
-		Title       string
-		PostMessage string
+		Title  string
+		Pubkey string
+		CSRF   string
 	}{
 		"Encrypt a message",
-		viewMessage,
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-}
-
-func encrypt(message []byte, pubkey kyber.Point) (
-	K kyber.Point, C kyber.Point, remainder []byte, err error) {
-
-	// Embed the message (or as much of it as will fit) into a curve point.
-	M := suite.Point().Embed(message, random.New())
-	max := suite.Point().EmbedLen()
-	if max > len(message) {
-		max = len(message)
+		pubkeyHex,
+		c.csrfToken(w, r),
 	}
-	remainder = message[max:]
-	// ElGamal-encrypt the point to produce ciphertext (K,C).
-	k := suite.Scalar().Pick(random.New()) // ephemeral private key
-	K = suite.Point().Mul(k, nil)          // ephemeral DH public key
-	S := suite.Point().Mul(k, pubkey)      // ephemeral DH shared secret
-	C = S.Add(S, M)                        // message blinded with secret
 
-	return K, C, remainder, nil
+	c.render(w, http.StatusOK, "encrypt", viewData)
 }
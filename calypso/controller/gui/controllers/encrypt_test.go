@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// encryptVector is a ciphertext produced by the encryption of the browser, with
+// the key to decrypt it. See testdata/vectors.js.
+type encryptVector struct {
+	Secret  string
+	Pubkey  string
+	Message string
+	Admin   string
+	Readers []string
+	Scalar  string
+	K       string
+	C       string
+	Data    string
+	// Expiry is the expiry bound to the proof in RFC 3339 format, or empty
+	// if the record never expires.
+	Expiry string
+	Proof  string
+}
+
+func TestEncrypt_BrowserVectors(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("failed to read vectors: %v", err)
+	}
+
+	var vectors []encryptVector
+
+	err = json.Unmarshal(raw, &vectors)
+	if err != nil {
+		t.Fatalf("failed to decode vectors: %v", err)
+	}
+
+	if len(vectors) == 0 {
+		t.Fatal("no vectors")
+	}
+
+	for i, v := range vectors {
+		secret := decodeScalar(t, v.Secret)
+		k := decodeScalar(t, v.Scalar)
+		pubkey := decodePoint(t, v.Pubkey)
+		K := decodePoint(t, v.K)
+		C := decodePoint(t, v.C)
+		data := decodeHex(t, v.Data)
+
+		if !pubkey.Equal(calycrypto.Suite.Point().Mul(secret, nil)) {
+			t.Fatalf("vector %d: wrong public key", i)
+		}
+
+		if !K.Equal(calycrypto.Suite.Point().Mul(k, nil)) {
+			t.Fatalf("vector %d: K doesn't match the scalar", i)
+		}
+
+		// C - secret*K must be a point of the subgroup with the message, or
+		// the key of the data, embedded. The scalar -1 is L-1, so that only
+		// the points of the subgroup are negated by it.
+		M := calycrypto.Suite.Point().Sub(C, calycrypto.Suite.Point().Mul(secret, K))
+
+		minusOne := calycrypto.Suite.Scalar().Neg(calycrypto.Suite.Scalar().One())
+		if !calycrypto.Suite.Point().Mul(minusOne, M).Equal(calycrypto.Suite.Point().Neg(M)) {
+			t.Fatalf("vector %d: embedded point not in the subgroup", i)
+		}
+
+		embedded, err := M.Data()
+		if err != nil {
+			t.Fatalf("vector %d: failed to extract data: %v", i, err)
+		}
+
+		message := embedded
+
+		if len(data) > 0 {
+			message, err = calycrypto.Open(embedded, data)
+			if err != nil {
+				t.Fatalf("vector %d: failed to open data: %v", i, err)
+			}
+		}
+
+		if string(message) != v.Message {
+			t.Fatalf("vector %d: expected %q, got %q", i, v.Message, message)
+		}
+
+		ac, err := newAccess(v.Admin, v.Readers...)
+		if err != nil {
+			t.Fatalf("vector %d: failed to create access: %v", i, err)
+		}
+
+		var expiry time.Time
+		if v.Expiry != "" {
+			expiry, err = time.Parse(time.RFC3339, v.Expiry)
+			if err != nil {
+				t.Fatalf("vector %d: failed to parse expiry: %v", i, err)
+			}
+		}
+
+		proof := decodeHex(t, v.Proof)
+
+		err = calypso.VerifyWriteProof(proof, K, C, data, expiry, ac)
+		if err != nil {
+			t.Fatalf("vector %d: invalid proof: %v", i, err)
+		}
+
+		// The proof binds the data and the expiry, which can't be changed.
+		err = calypso.VerifyWriteProof(proof, K, C, append(data, 0), expiry, ac)
+		if err == nil {
+			t.Fatalf("vector %d: proof accepts other data", i)
+		}
+
+		err = calypso.VerifyWriteProof(proof, K, C, data,
+			expiry.Add(time.Millisecond), ac)
+		if err == nil {
+			t.Fatalf("vector %d: proof accepts another expiry", i)
+		}
+
+		// The Go encryption must take the same path, with data of the same
+		// size.
+		_, env, err := calycrypto.EncryptHybrid([]byte(v.Message), pubkey,
+			random.New())
+		if err != nil {
+			t.Fatalf("vector %d: failed to encrypt: %v", i, err)
+		}
+
+		if len(env.Data) != len(data) {
+			t.Fatalf("vector %d: expected %d bytes of data, got %d", i,
+				len(data), len(env.Data))
+		}
+	}
+}
+
+func decodeHex(t *testing.T, str string) []byte {
+	t.Helper()
+
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		t.Fatalf("failed to decode hex: %v", err)
+	}
+
+	return buf
+}
+
+func decodeScalar(t *testing.T, str string) kyber.Scalar {
+	t.Helper()
+
+	s := calycrypto.Suite.Scalar()
+
+	err := s.UnmarshalBinary(decodeHex(t, str))
+	if err != nil {
+		t.Fatalf("failed to unmarshal scalar: %v", err)
+	}
+
+	return s
+}
+
+func decodePoint(t *testing.T, str string) kyber.Point {
+	t.Helper()
+
+	p := calycrypto.Suite.Point()
+
+	err := p.UnmarshalBinary(decodeHex(t, str))
+	if err != nil {
+		t.Fatalf("failed to unmarshal point: %v", err)
+	}
+
+	return p
+}
@@ -1,9 +1,6 @@
 package controllers
 
-import (
-	"net/http"
-	"text/template"
-)
+import "net/http"
 
 // renderHTTPError is a utility function to render a user-friendly error
 func (c Ctrl) renderHTTPError(w http.ResponseWriter, message string, code int) {
@@ -17,23 +14,5 @@ func (c Ctrl) renderHTTPError(w http.ResponseWriter, message string, code int) {
 		code,
 	}
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/error.gohtml"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-
-	if code > 0 {
-		w.WriteHeader(code)
-	}
+	c.render(w, code, "error", viewData)
 }
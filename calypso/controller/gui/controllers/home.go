@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"net/http"
-	"text/template"
 )
 
 // HomeHandler handles the home page
@@ -24,21 +23,11 @@ func (c Ctrl) homeGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/home.gohtml"))
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	viewData := struct {
 		Title string
 	}{
 		Title: "Welcome",
 	}
 
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	c.render(w, http.StatusOK, "home", viewData)
 }
@@ -1,41 +1,129 @@
 package controllers
 
 import (
-	"log"
-	"path/filepath"
-	"runtime"
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
 
 	"go.dedis.ch/dela-apps/calypso"
-	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/dela-apps/calypso/controller/gui"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
 )
 
-// suite is the Kyber suite for Pedersen.
-var suite = suites.MustFind("Ed25519")
+// views are the names of the pages, which are rendered inside the layout.
+var views = []string{"home", "pubkey", "encrypt", "write", "read", "update", "error"}
 
-// NewCtrl creates a new Ctrl. It gets and stored the current folder path of
-// this file so that we can later reference our statics files.
-func NewCtrl(caly *calypso.Calypso) *Ctrl {
-	_, filename, _, ok := runtime.Caller(1)
-	if !ok {
-		log.Fatal("failed to get current path for Calypso GUI")
+// Option is the type of option to create the controller.
+type Option func(*Ctrl)
+
+// WithFiles is an option to serve the views and the assets from the file
+// system instead of the ones embedded in the binary. It expects the same layout
+// as the gui folder, which is useful to work on the GUI without rebuilding.
+func WithFiles(files fs.FS) Option {
+	return func(c *Ctrl) {
+		c.files = files
 	}
+}
 
-	filename = filepath.Dir(filename)
+// NewCtrl creates a new Ctrl. The views are parsed once and for all.
+func NewCtrl(caly *calypso.Calypso, opts ...Option) (*Ctrl, error) {
+	csrfKey := make([]byte, 32)
+	random.Bytes(csrfKey, random.New())
+
+	ctrl := &Ctrl{
+		caly:    caly,
+		files:   gui.Files,
+		csrfKey: csrfKey,
+	}
 
-	return &Ctrl{
-		path: filename,
-		caly: caly,
+	for _, opt := range opts {
+		opt(ctrl)
 	}
+
+	templates, err := parseViews(ctrl.files)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse views: %v", err)
+	}
+
+	ctrl.templates = templates
+
+	return ctrl, nil
 }
 
 // Ctrl holds all the gui controllers. This struct allows us to share common
 // data to all the controllers.
 type Ctrl struct {
-	path string
-	caly *calypso.Calypso
+	caly      *calypso.Calypso
+	files     fs.FS
+	templates map[string]*template.Template
+	csrfKey   []byte
+}
+
+// AssetsHandler serves the static files of the GUI. It expects the "/assets/"
+// prefix to be part of the path.
+func (c Ctrl) AssetsHandler() http.HandlerFunc {
+	assets, err := fs.Sub(c.files, "assets")
+	if err != nil {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	handler := http.StripPrefix("/assets/", http.FileServer(http.FS(assets)))
+
+	return handler.ServeHTTP
+}
+
+// render renders the view in the layout with the status code. The page is
+// rendered before anything is written so that a failure can still be reported
+// with the right status.
+func (c Ctrl) render(w http.ResponseWriter, code int, view string,
+	data interface{}) {
+
+	t, found := c.templates[view]
+	if !found {
+		http.Error(w, "unknown view "+view, http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	err := t.ExecuteTemplate(&buf, "layout", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
 }
 
-// Abs is a utility to compute the absolute file path
-func (c Ctrl) Abs(path string) string {
-	return filepath.Join(c.path, path)
+// parseViews parses the layout with each of the views of the file system.
+func parseViews(files fs.FS) (map[string]*template.Template, error) {
+	layout, err := template.ParseFS(files, "views/layout.gohtml")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse layout: %v", err)
+	}
+
+	templates := make(map[string]*template.Template, len(views))
+
+	for _, view := range views {
+		t, err := layout.Clone()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to clone layout: %v", err)
+		}
+
+		_, err = t.ParseFS(files, "views/"+view+".gohtml")
+		if err != nil {
+			return nil, xerrors.Errorf("failed to parse %s: %v", view, err)
+		}
+
+		templates[view] = t
+	}
+
+	return templates, nil
 }
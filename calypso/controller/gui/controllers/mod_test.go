@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso"
+)
+
+func TestCtrl_RenderError(t *testing.T) {
+	ctrl := newTestCtrl(t)
+
+	rec := httptest.NewRecorder()
+	ctrl.renderHTTPError(rec, "<script>alert(1)</script>", http.StatusTeapot)
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected content type: %s", rec.Header().Get("Content-Type"))
+	}
+
+	body := rec.Body.String()
+	if strings.Contains(body, "<script>") {
+		t.Fatalf("message not escaped: %s", body)
+	}
+
+	if !strings.Contains(body, "&lt;script&gt;") {
+		t.Fatalf("message missing: %s", body)
+	}
+}
+
+func TestCtrl_Assets(t *testing.T) {
+	// The embedded files and the ones of the folder must be the same.
+	ctrls := []*Ctrl{
+		newTestCtrl(t),
+		newTestCtrl(t, WithFiles(os.DirFS(".."))),
+	}
+
+	for i, ctrl := range ctrls {
+		for _, path := range []string{"stylesheets/base.css", "javascripts/calypso.js"} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/assets/"+path, nil)
+			ctrl.AssetsHandler()(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("ctrl %d: unexpected status for %s: %d", i, path, rec.Code)
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		ctrl.HomeHandler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("ctrl %d: unexpected status: %d", i, rec.Code)
+		}
+	}
+
+	_, err := NewCtrl(calypso.NewCalypso(nil), WithFiles(os.DirFS(".")))
+	if err == nil {
+		t.Fatal("expected an error for a folder without the views")
+	}
+}
+
+func TestCtrl_Encrypt(t *testing.T) {
+	ctrl := newTestCtrl(t)
+
+	rec := httptest.NewRecorder()
+	ctrl.EncryptHandler()(rec, httptest.NewRequest(http.MethodGet, "/encrypt", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+
+	if !strings.Contains(body, `src="/assets/javascripts/calypso.js"`) {
+		t.Fatal("script missing")
+	}
+
+	// The plaintext must never be posted.
+	if strings.Contains(body, `name="message"`) {
+		t.Fatal("message is part of the form")
+	}
+
+	rec = httptest.NewRecorder()
+	ctrl.EncryptHandler()(rec, httptest.NewRequest(http.MethodPost, "/encrypt", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+func TestCtrl_CSRF(t *testing.T) {
+	ctrl := newTestCtrl(t)
+
+	rec := httptest.NewRecorder()
+	ctrl.WriteHandler()(rec, httptest.NewRequest(http.MethodGet, "/write", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookie {
+		t.Fatalf("unexpected cookies: %v", cookies)
+	}
+
+	match := regexp.MustCompile(`name="csrf" value="([0-9a-f]+)"`).
+		FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatal("token not found in the form")
+	}
+
+	// The form is valid up to the missing K, and refused otherwise.
+	codes := []struct {
+		cookie *http.Cookie
+		token  string
+		code   int
+	}{
+		{cookies[0], match[1], http.StatusBadRequest},
+		{cookies[0], "", http.StatusForbidden},
+		{cookies[0], strings.Repeat("0", len(match[1])), http.StatusForbidden},
+		{nil, match[1], http.StatusForbidden},
+		{&http.Cookie{Name: csrfCookie, Value: "other"}, match[1], http.StatusForbidden},
+	}
+
+	for i, c := range codes {
+		form := url.Values{csrfField: {c.token}}
+
+		req := httptest.NewRequest(http.MethodPost, "/write",
+			strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if c.cookie != nil {
+			req.AddCookie(c.cookie)
+		}
+
+		rec = httptest.NewRecorder()
+		ctrl.WriteHandler()(rec, req)
+
+		if rec.Code != c.code {
+			t.Fatalf("case %d: expected %d, got %d", i, c.code, rec.Code)
+		}
+	}
+}
+
+func TestCtrl_ReadChallenge(t *testing.T) {
+	ctrl := newTestCtrl(t)
+
+	nonceRe := regexp.MustCompile(`name="nonce" value="([0-9a-f]+)"`)
+
+	// Loading the form doesn't issue a challenge.
+	rec := httptest.NewRecorder()
+	ctrl.ReadHandler()(rec, httptest.NewRequest(http.MethodGet, "/read", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if nonceRe.MatchString(rec.Body.String()) {
+		t.Fatal("challenge issued on load")
+	}
+
+	cookies := rec.Result().Cookies()
+
+	token := regexp.MustCompile(`name="csrf" value="([0-9a-f]+)"`).
+		FindStringSubmatch(rec.Body.String())
+	if token == nil || len(cookies) != 1 {
+		t.Fatal("token not found")
+	}
+
+	// The challenge is issued once the ID is submitted.
+	form := url.Values{csrfField: {token[1]}, "msgID": {"aef123"}}
+
+	req := httptest.NewRequest(http.MethodPost, "/read",
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookies[0])
+
+	rec = httptest.NewRecorder()
+	ctrl.ReadHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if !nonceRe.MatchString(rec.Body.String()) {
+		t.Fatal("challenge not issued")
+	}
+
+	if !strings.Contains(rec.Body.String(), `value="aef123"`) {
+		t.Fatal("ID not kept in the form")
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func newTestCtrl(t *testing.T, opts ...Option) *Ctrl {
+	t.Helper()
+
+	ctrl, err := NewCtrl(calypso.NewCalypso(nil), opts...)
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	return ctrl
+}
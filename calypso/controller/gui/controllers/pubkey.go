@@ -4,7 +4,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"text/template"
 )
 
 // this file hold the controller for the client execution, as opposed to the
@@ -38,13 +37,6 @@ func (c Ctrl) pubkeyGET(w http.ResponseWriter, r *http.Request) {
 
 	pubkeyHex := hex.EncodeToString(pubkeyBuf)
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/pubkey.gohtml"))
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	var viewData = struct {
 		Title  string
 		Pubkey string
@@ -53,8 +45,5 @@ func (c Ctrl) pubkeyGET(w http.ResponseWriter, r *http.Request) {
 		pubkeyHex,
 	}
 
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	c.render(w, http.StatusOK, "pubkey", viewData)
 }
@@ -4,10 +4,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"text/template"
 
-	"go.dedis.ch/dela-apps/calypso/controller/gui/models"
-	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
 )
 
 // ReadHandler handles the read requests
@@ -25,33 +23,21 @@ func (c Ctrl) ReadHandler() http.HandlerFunc {
 }
 
 func (c Ctrl) readGET(w http.ResponseWriter, r *http.Request) {
+	c.renderRead(w, r, readView{})
+}
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/read.gohtml"))
+// readPOST either issues the challenge of the record, when the form has no
+// nonce yet, or reads the record.
+func (c Ctrl) readPOST(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	var viewData = struct {
-		Title       string
-		PostMessage string
-	}{
-		"Read a message",
-		"",
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-func (c Ctrl) readPOST(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+	err = c.verifyCSRF(r)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
@@ -63,46 +49,66 @@ func (c Ctrl) readPOST(w http.ResponseWriter, r *http.Request) {
 
 	msgIDBuf, err := hex.DecodeString(msgIDStr)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.PostForm.Get("nonce") == "" {
+		ch, err := c.newChallenge(msgIDBuf)
+		if err != nil {
+			c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.renderRead(w, r, readView{ID: msgIDStr, Challenge: ch})
 		return
 	}
 
 	identity := r.PostForm.Get("identity")
 	if identity == "" {
-		c.renderHTTPError(w, "C is empty", http.StatusBadRequest)
+		c.renderHTTPError(w, "identity is empty", http.StatusBadRequest)
 		return
 	}
 
-	foreignID := models.NewIdentity(identity)
-	idents := []access.Identity{foreignID}
-
-	msgBuf, err := c.caly.Read(msgIDBuf, idents...)
+	auth, err := api.DecodeAuthentication(r.PostForm.Get("nonce"),
+		api.SignedIdentity{
+			Identity:  identity,
+			Signature: r.PostForm.Get("signature"),
+		})
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/read.gohtml"))
+	msgBuf, err := c.caly.Read(msgIDBuf, auth)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), api.ErrorCode(err))
 		return
 	}
 
-	viewMessage := fmt.Sprintf("Message fetched!\nMessage: %s", msgBuf)
+	c.renderRead(w, r, readView{
+		PostMessage: fmt.Sprintf("Message fetched!\nMessage: %s", msgBuf),
+	})
+}
+
+// readView is the data of the read form. The challenge is only set once the
+// ID of the record has been submitted.
+type readView struct {
+	PostMessage string
+	ID          string
+	Challenge   challenge
+}
 
+func (c Ctrl) renderRead(w http.ResponseWriter, r *http.Request, view readView) {
 	var viewData = struct {
-		Title       string
-		PostMessage string
+		readView
+		Title string
+		CSRF  string
 	}{
+		view,
 		"Read a message",
-		viewMessage,
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		c.csrfToken(w, r),
 	}
 
+	c.render(w, http.StatusOK, "read", viewData)
 }
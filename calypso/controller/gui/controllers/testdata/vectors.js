@@ -0,0 +1,79 @@
+// Generates the vectors of the browser encryption checked by the tests of the
+// controllers, with a deterministic randomness so that they can be reproduced:
+//
+//   node testdata/vectors.js > testdata/vectors.json
+//
+// Requires Node.js 19 or later for the Web Crypto API.
+"use strict";
+
+const nodeCrypto = require("crypto");
+const calypso = require("../../assets/javascripts/calypso.js");
+
+let counter = 0;
+
+// rand returns the next bytes of SHA-256 in counter mode.
+function rand(n) {
+  const out = new Uint8Array(n);
+  for (let offset = 0; offset < n; offset += 32) {
+    const block = nodeCrypto.createHash("sha256")
+      .update("calypso vectors " + counter++).digest();
+    out.set(block.subarray(0, Math.min(32, n - offset)), offset);
+  }
+  return out;
+}
+
+function scalar() {
+  return calypso.toInt(rand(64)) % calypso.L;
+}
+
+function identity() {
+  const pubkey = calypso.mul(scalar(), calypso.BASE);
+  return "schnorr:" + calypso.toHex(calypso.encodePoint(pubkey));
+}
+
+const messages = [
+  "",
+  "hello",
+  "a message of exactly 29 bytes",
+  "a message of exactly 30 bytes!",
+  "a longer message that is sealed with a symmetric key in the data field",
+];
+
+async function main() {
+  const vectors = [];
+
+  for (const [i, message] of messages.entries()) {
+    const secret = scalar();
+    const pubkey = calypso.mul(secret, calypso.BASE);
+
+    const admin = identity();
+    const readers = [identity(), identity()];
+
+    const { k, K, C, data } = await calypso.encryptHybrid(
+      new TextEncoder().encode(message), pubkey, rand);
+
+    // Every other vector expires, at a fixed time with milliseconds.
+    const expiry = i % 2 === 0 ? null : new Date(Date.UTC(2030, 0, 1) + i * 1001);
+
+    const proof = await calypso.newWriteProof(k, K, C, data, expiry,
+      calypso.policy(admin, readers), rand);
+
+    vectors.push({
+      Secret: calypso.toHex(calypso.toBytes(secret)),
+      Pubkey: calypso.toHex(calypso.encodePoint(pubkey)),
+      Message: message,
+      Admin: admin,
+      Readers: readers,
+      Scalar: calypso.toHex(calypso.toBytes(k)),
+      K: calypso.toHex(calypso.encodePoint(K)),
+      C: calypso.toHex(calypso.encodePoint(C)),
+      Data: data === null ? "" : calypso.toHex(data),
+      Expiry: expiry === null ? "" : expiry.toISOString(),
+      Proof: calypso.toHex(proof),
+    });
+  }
+
+  process.stdout.write(JSON.stringify(vectors, null, 2) + "\n");
+}
+
+main();
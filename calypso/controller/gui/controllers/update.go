@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+)
+
+// UpdateHandler handles the update of the access control of a secret
+func (c Ctrl) UpdateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c.updateGET(w, r)
+		case http.MethodPost:
+			c.updatePOST(w, r)
+		default:
+			c.renderHTTPError(w, "only GET and POST requests allowed", http.StatusBadRequest)
+		}
+	}
+}
+
+func (c Ctrl) updateGET(w http.ResponseWriter, r *http.Request) {
+	c.renderUpdate(w, r, updateView{})
+}
+
+// updatePOST either issues the challenge of the record, when the form has no
+// nonce yet, or updates the access of the record.
+func (c Ctrl) updatePOST(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = c.verifyCSRF(r)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	msgIDStr := r.PostForm.Get("msgID")
+	if msgIDStr == "" {
+		c.renderHTTPError(w, "message ID is empty", http.StatusBadRequest)
+		return
+	}
+
+	msgIDBuf, err := hex.DecodeString(msgIDStr)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.PostForm.Get("nonce") == "" {
+		ch, err := c.newChallenge(msgIDBuf)
+		if err != nil {
+			c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.renderUpdate(w, r, updateView{ID: msgIDStr, Challenge: ch})
+		return
+	}
+
+	identity := r.PostForm.Get("identity")
+	if identity == "" {
+		c.renderHTTPError(w, "identity is empty", http.StatusBadRequest)
+		return
+	}
+
+	adminIdentity := r.PostForm.Get("adminID")
+	if adminIdentity == "" {
+		c.renderHTTPError(w, "Admin identity is empty", http.StatusBadRequest)
+		return
+	}
+
+	ac, err := newAccess(adminIdentity, r.PostForm["readID"]...)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	auth, err := api.DecodeAuthentication(r.PostForm.Get("nonce"),
+		api.SignedIdentity{
+			Identity:  identity,
+			Signature: r.PostForm.Get("signature"),
+		})
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = c.caly.UpdateAccess(msgIDBuf, auth, ac)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), api.ErrorCode(err))
+		return
+	}
+
+	c.renderUpdate(w, r, updateView{
+		PostMessage: fmt.Sprintf("Access updated!\nID: %s", msgIDStr),
+	})
+}
+
+// updateView is the data of the update form. The challenge is only set once
+// the ID of the record has been submitted.
+type updateView struct {
+	PostMessage string
+	ID          string
+	Challenge   challenge
+}
+
+func (c Ctrl) renderUpdate(w http.ResponseWriter, r *http.Request, view updateView) {
+	var viewData = struct {
+		updateView
+		Title string
+		CSRF  string
+	}{
+		view,
+		"Update the access",
+		c.csrfToken(w, r),
+	}
+
+	c.render(w, http.StatusOK, "update", viewData)
+}
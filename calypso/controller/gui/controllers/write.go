@@ -4,9 +4,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"text/template"
 
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
 	"go.dedis.ch/dela-apps/calypso/controller/gui/models"
+	"go.dedis.ch/dela/core/access"
+	"golang.org/x/xerrors"
 )
 
 // WriteHandler handles the write requests
@@ -24,26 +27,7 @@ func (c Ctrl) WriteHandler() http.HandlerFunc {
 }
 
 func (c Ctrl) writeGET(w http.ResponseWriter, r *http.Request) {
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/write.gohtml"))
-	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var viewData = struct {
-		Title       string
-		PostMessage string
-	}{
-		"Write a message",
-		"",
-	}
-
-	err = t.ExecuteTemplate(w, "layout", viewData)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	c.renderWrite(w, r, "")
 }
 
 func (c Ctrl) writePOST(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +37,12 @@ func (c Ctrl) writePOST(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = c.verifyCSRF(r)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	kStr := r.PostForm.Get("k")
 	if kStr == "" {
 		c.renderHTTPError(w, "K is empty", http.StatusBadRequest)
@@ -67,27 +57,48 @@ func (c Ctrl) writePOST(w http.ResponseWriter, r *http.Request) {
 
 	kBuf, err := hex.DecodeString(kStr)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	cBuf, err := hex.DecodeString(cStr)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	kPoint := suite.Point()
-	err = kPoint.UnmarshalBinary(kBuf)
+	kPoint, err := calypso.UnmarshalPoint(kBuf)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, "invalid K: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	cPoint := suite.Point()
-	err = cPoint.UnmarshalBinary(cBuf)
+	cPoint, err := calypso.UnmarshalPoint(cBuf)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, "invalid C: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var data []byte
+
+	dataStr := r.PostForm.Get("data")
+	if dataStr != "" {
+		data, err = hex.DecodeString(dataStr)
+		if err != nil {
+			c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	proofStr := r.PostForm.Get("proof")
+	if proofStr == "" {
+		c.renderHTTPError(w, "proof is empty", http.StatusBadRequest)
+		return
+	}
+
+	proof, err := hex.DecodeString(proofStr)
+	if err != nil {
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -97,59 +108,71 @@ func (c Ctrl) writePOST(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// readIdentities := r.PostForm["readID"]
-
-	// ownerID := models.NewIdentity(adminIdentity)
-	// d := darc.NewService()
-	// d, err = d.Evolve(calypso.ArcRuleUpdate, ownerID)
-	// if err != nil {
-	// 	c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
-
-	// d, err = d.Evolve(calypso.ArcRuleRead, ownerID)
-	// if err != nil {
-	// 	c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
+	readIdentities := r.PostForm["readID"]
 
-	// for _, rid := range readIdentities {
-	// 	d, err = d.Evolve(calypso.ArcRuleRead, models.NewIdentity(rid))
-	// 	if err != nil {
-	// 		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
-	// 		return
-	// 	}
-	// }
-
-	id, err := c.caly.Write(models.NewEncryptedMsg(kPoint, cPoint), nil)
+	ac, err := newAccess(adminIdentity, readIdentities...)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	idHex := hex.EncodeToString(id)
+	em := models.NewEncryptedMsg(kPoint, cPoint, data, proof)
 
-	t, err := template.ParseFiles(c.Abs("gui/views/layout.gohtml"),
-		c.Abs("gui/views/write.gohtml"))
+	id, err := c.caly.Write(em, ac)
 	if err != nil {
-		c.renderHTTPError(w, err.Error(), http.StatusInternalServerError)
+		c.renderHTTPError(w, err.Error(), api.ErrorCode(err))
 		return
 	}
 
+	idHex := hex.EncodeToString(id)
+
 	viewMessage := fmt.Sprintf("Message saved! Please save the ID:\nID: %s", idHex)
 
+	c.renderWrite(w, r, viewMessage)
+}
+
+// renderWrite renders the write form.
+func (c Ctrl) renderWrite(w http.ResponseWriter, r *http.Request, message string) {
 	var viewData = struct {
 		Title       string
 		PostMessage string
+		CSRF        string
 	}{
-		"Encrypt a message",
-		viewMessage,
+		"Write a message",
+		message,
+		c.csrfToken(w, r),
 	}
 
-	err = t.ExecuteTemplate(w, "layout", viewData)
+	c.render(w, http.StatusOK, "write", viewData)
+}
+
+// newAccess returns an access control that allows the owner to update and read
+// the record, and the readers to read it. The identities are public keys in
+// their text form. Empty readers are ignored.
+func newAccess(owner string, readers ...string) (access.Service, error) {
+	ownerID, err := calypso.ParseIdentity(owner)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, xerrors.Errorf("admin: %v", err)
+	}
+
+	readIDs := make([]access.Identity, 0, len(readers))
+	for _, reader := range readers {
+		if reader == "" {
+			continue
+		}
+
+		readID, err := calypso.ParseIdentity(reader)
+		if err != nil {
+			return nil, xerrors.Errorf("reader: %v", err)
+		}
+
+		readIDs = append(readIDs, readID)
+	}
+
+	ac, err := calypso.NewAccess(ownerID, readIDs...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create access: %v", err)
 	}
 
+	return ac, nil
 }
@@ -0,0 +1,11 @@
+// Package gui holds the views and the static assets of the Calypso GUI. They
+// are embedded in the binary so that the GUI can be served from anywhere.
+package gui
+
+import "embed"
+
+// Files is the embedded file system of the GUI, with the templates in "views"
+// and the static files in "assets".
+//
+//go:embed views assets
+var Files embed.FS
@@ -2,11 +2,15 @@ package models
 
 import "go.dedis.ch/kyber/v3"
 
-// NewEncryptedMsg creates a new encrypted message
-func NewEncryptedMsg(k, c kyber.Point) *EncryptedMsg {
+// NewEncryptedMsg creates a new encrypted message. The data is the symmetric
+// ciphertext of the message in the hybrid mode, or nil. The proof is the write
+// proof of the message.
+func NewEncryptedMsg(k, c kyber.Point, data, proof []byte) *EncryptedMsg {
 	return &EncryptedMsg{
-		K: k,
-		C: c,
+		K:     k,
+		C:     c,
+		Data:  data,
+		Proof: proof,
 	}
 }
 
@@ -14,8 +18,10 @@ func NewEncryptedMsg(k, c kyber.Point) *EncryptedMsg {
 //
 // - implements calypso.EncryptedMessage
 type EncryptedMsg struct {
-	K kyber.Point
-	C kyber.Point
+	K     kyber.Point
+	C     kyber.Point
+	Data  []byte
+	Proof []byte
 }
 
 // GetK implements calypso.EncryptedMessage
@@ -27,3 +33,13 @@ func (f EncryptedMsg) GetK() kyber.Point {
 func (f EncryptedMsg) GetC() kyber.Point {
 	return f.C
 }
+
+// GetData implements calypso.EncryptedMessage
+func (f EncryptedMsg) GetData() []byte {
+	return f.Data
+}
+
+// GetProof implements calypso.EncryptedMessage
+func (f EncryptedMsg) GetProof() []byte {
+	return f.Proof
+}
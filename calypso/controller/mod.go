@@ -1,17 +1,53 @@
 package controller
 
 import (
+	"path/filepath"
+	"time"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela-apps/calypso/audit"
+	"go.dedis.ch/dela-apps/calypso/contract"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/disk"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated"
 	"go.dedis.ch/dela/cli"
 	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/core/execution/native"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/mino"
 	"go.dedis.ch/kyber/v3/suites"
+	"golang.org/x/xerrors"
+
+	// Register the JSON formats of the records and their access control.
+	_ "go.dedis.ch/dela-apps/calypso/arc/json"
+	_ "go.dedis.ch/dela-apps/calypso/dkg/pedersen/json"
+	_ "go.dedis.ch/dela-apps/calypso/json"
+)
+
+const (
+	// storageFlag is the name of the start flag to select the storage backend.
+	storageFlag = "calypso-storage"
+	// storageDisk is the backend persisting the records in the config folder.
+	storageDisk = "disk"
+	// storageMemory is the backend keeping the records in memory.
+	storageMemory = "memory"
+	// dbFilename is the name of the database file of the disk backend.
+	dbFilename = "calypso.db"
+	// sweepFlag is the name of the start flag to set the interval between
+	// two deletions of the expired records.
+	sweepFlag = "calypso-sweep-interval"
 )
 
 // suite is the Kyber suite for Pedersen.
 var suite = suites.MustFind("Ed25519")
 
 // NewMinimal returns a new minimal initializer. The static files for the client
-// GUI are not packed in the binairy, so one need to build its own binairy in
-// order to use it.
+// GUI are embedded in the binary. The initializer creates its own DKG, so the
+// DKG initializer of Dela must not be used alongside.
 func NewMinimal() node.Initializer {
 	return minimal{}
 }
@@ -23,16 +59,37 @@ type minimal struct{}
 
 // SetCommands implements node.Initializer
 func (m minimal) SetCommands(builder node.Builder) {
+	builder.SetStartFlags(
+		cli.StringFlag{
+			Name: storageFlag,
+			Usage: "the storage backend of the Calypso records, either " +
+				"'disk' or 'memory'",
+			Value: storageDisk,
+		},
+		cli.DurationFlag{
+			Name:  sweepFlag,
+			Usage: "the interval between two deletions of the expired records",
+			Value: time.Minute,
+		},
+	)
+
 	cb := builder.SetCommand("calypso")
 	cb.SetDescription("Set of commands to administrate Calypso")
 
 	sub := cb.SetSubCommand("listen")
-	sub.SetDescription("starts DKG by listening")
+	sub.SetDescription("starts DKG by listening and starts Calypso")
 	sub.SetAction(builder.MakeAction(listenAction{}))
 
 	sub = cb.SetSubCommand("register")
 	sub.SetDescription("registers the calyso GUI to the dela proxy")
 	sub.SetAction(builder.MakeAction(registerAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name: "gui-dir",
+			Usage: "a folder with the views and the assets of the GUI to " +
+				"use instead of the embedded ones, for development",
+		},
+	)
 
 	sub = cb.SetSubCommand("setup")
 	sub.SetDescription("setup Calypso and create the distributed key. " +
@@ -40,33 +97,478 @@ func (m minimal) SetCommands(builder node.Builder) {
 	sub.SetAction(builder.MakeAction(setupAction{}))
 	sub.SetFlags(
 		cli.StringFlag{
-			Name:     "pubkeys",
-			Usage:    "a list of public keys in hex strings, separated by commas",
-			Required: true,
+			Name: "roster",
+			Usage: "a JSON file with the members, their public key and " +
+				"optionally the threshold",
+		},
+		cli.StringFlag{
+			Name: "pubkeys",
+			Usage: "a list of public keys in hex strings, separated by " +
+				"commas, if no roster is given",
 		},
 		cli.StringFlag{
 			Name: "addrs",
-			Usage: "a list of addresses corresponding to the public keys, " +
-				"separated by commas",
-			Required: true,
+			Usage: "a list of base64 addresses corresponding to the public " +
+				"keys, separated by commas, if no roster is given",
 		},
 		cli.IntFlag{
-			Name:     "threshold",
-			Usage:    "the minimum number of nodes that is needed to decrypt",
+			Name: "threshold",
+			Usage: "the minimum number of nodes that is needed to decrypt, " +
+				"which replaces the one of the roster",
+		},
+	)
+
+	sub = cb.SetSubCommand("reshare")
+	sub.SetDescription("move the distributed key to a new set of members " +
+		"and threshold, keeping the same collective public key. All the " +
+		"current members must be online.")
+	sub.SetAction(builder.MakeAction(reshareAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name: "roster",
+			Usage: "a JSON file with the new members, their public key and " +
+				"optionally the threshold",
+		},
+		cli.StringFlag{
+			Name: "pubkeys",
+			Usage: "a list of public keys in hex strings, separated by " +
+				"commas, if no roster is given",
+		},
+		cli.StringFlag{
+			Name: "addrs",
+			Usage: "a list of base64 addresses corresponding to the public " +
+				"keys, separated by commas, if no roster is given",
+		},
+		cli.IntFlag{
+			Name: "threshold",
+			Usage: "the new minimum number of nodes that is needed to " +
+				"decrypt, which replaces the one of the roster",
+		},
+	)
+
+	sub = cb.SetSubCommand("status")
+	sub.SetDescription("print the phase of the DKG, the committee, the " +
+		"threshold, the fingerprint of the collective public key and the " +
+		"number of records")
+	sub.SetAction(builder.MakeAction(statusAction{}))
+	sub.SetFlags(
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the status in JSON",
+		},
+	)
+
+	sub = cb.SetSubCommand("pubkey")
+	sub.SetDescription("print the collective public key, in hex string")
+	sub.SetAction(builder.MakeAction(pubkeyAction{}))
+
+	sub = cb.SetSubCommand("encrypt")
+	sub.SetDescription("encrypt a message with the collective public key and " +
+		"print it in JSON, as expected by the write command")
+	sub.SetAction(builder.MakeAction(encryptAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "message",
+			Usage:    "the message to encrypt",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "admin",
+			Usage:    "the identity allowed to update and read the record",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:  "readers",
+			Usage: "a list of identities allowed to read, separated by commas",
+		},
+		cli.DurationFlag{
+			Name: "expiry",
+			Usage: "the duration after which the record can't be read " +
+				"anymore, which never expires by default",
+		},
+	)
+
+	sub = cb.SetSubCommand("write")
+	sub.SetDescription("write an encrypted message and print the ID of its " +
+		"record, in hex string")
+	sub.SetAction(builder.MakeAction(writeAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:  "k",
+			Usage: "the ephemeral public key K, in hex string",
+		},
+		cli.StringFlag{
+			Name:  "c",
+			Usage: "the encrypted message C, in hex string",
+		},
+		cli.StringFlag{
+			Name:  "data",
+			Usage: "the sealed message of the hybrid mode, in hex string",
+		},
+		cli.StringFlag{
+			Name:  "proof",
+			Usage: "the write proof, in hex string",
+		},
+		cli.StringFlag{
+			Name:  "admin",
+			Usage: "the identity allowed to update and read the record",
+		},
+		cli.StringFlag{
+			Name:  "readers",
+			Usage: "a list of identities allowed to read, separated by commas",
+		},
+		cli.StringFlag{
+			Name: "file",
+			Usage: "a JSON file with the encrypted message, as printed by " +
+				"the encrypt command, that replaces the other flags",
+		},
+	)
+
+	sub = cb.SetSubCommand("read")
+	sub.SetDescription("decrypt the message of a record and print it in " +
+		"hex string")
+	sub.SetAction(builder.MakeAction(readAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "id",
+			Usage:    "the ID of the record, in hex string",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name: "key",
+			Usage: "the key file of an identity allowed to read the record, " +
+				"or a list separated by commas",
+			Required: true,
+		},
+	)
+
+	sub = cb.SetSubCommand("read-reencrypted")
+	sub.SetDescription("re-encrypt the secret of a record under the public " +
+		"key of a reader, without decrypting it, and print it in JSON")
+	sub.SetAction(builder.MakeAction(readReencryptedAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "id",
+			Usage:    "the ID of the record, in hex string",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name: "reader",
+			Usage: "the key file of the reader, as created by keygen with " +
+				"the reader flag, under which the secret is re-encrypted",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name: "key",
+			Usage: "the key file of an identity allowed to read the record, " +
+				"or a list separated by commas",
+			Required: true,
+		},
+	)
+
+	sub = cb.SetSubCommand("update-access")
+	sub.SetDescription("replace the access control of a record")
+	sub.SetAction(builder.MakeAction(updateAccessAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "id",
+			Usage:    "the ID of the record, in hex string",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "key",
+			Usage:    "the key file of the identity allowed to update the record",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "admin",
+			Usage:    "the new identity allowed to update and read the record",
 			Required: true,
 		},
+		cli.StringFlag{
+			Name:  "readers",
+			Usage: "a list of identities allowed to read, separated by commas",
+		},
 	)
+
+	sub = cb.SetSubCommand("keygen")
+	sub.SetDescription("create the key of a new BLS identity and print the " +
+		"identity, as expected by the access controls")
+	sub.SetAction(builder.MakeAction(keygenAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "out",
+			Usage:    "the file where the key is saved, which must not exist",
+			Required: true,
+		},
+		cli.BoolFlag{
+			Name: "reader",
+			Usage: "create the Ed25519 key of a reader of re-encrypted " +
+				"secrets and print its public key instead",
+		},
+	)
+
+	sub = cb.SetSubCommand("sign")
+	sub.SetDescription("sign the challenge of a read, of an update when " +
+		"the new access control is given, or of an audit query, and print " +
+		"the signature in hex string")
+	sub.SetAction(builder.MakeAction(signAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "key",
+			Usage:    "the key file of the identity",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "nonce",
+			Usage:    "the nonce of the challenge, in hex string",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "id",
+			Usage:    "the ID of the record, in hex string",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:  "admin",
+			Usage: "the new identity allowed to update and read the record",
+		},
+		cli.StringFlag{
+			Name:  "readers",
+			Usage: "a list of new identities allowed to read, separated by commas",
+		},
+		cli.BoolFlag{
+			Name:  "audit",
+			Usage: "sign the query of the audit log of the record",
+		},
+	)
+
+	sub = cb.SetSubCommand("audit")
+	sub.SetDescription("print the audit log of the reads and the updates, " +
+		"one JSON entry per line")
+	sub.SetAction(builder.MakeAction(auditAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "only print the entries of the record, in hex string",
+		},
+		cli.StringFlag{
+			Name:  "identity",
+			Usage: "only print the entries of the identity",
+		},
+	)
+
+	sub = cb.SetSubCommand("ledger-read")
+	sub.SetDescription("print the secret of a committed read transaction " +
+		"of the Calypso contract, re-encrypted under the key of its reader")
+	sub.SetAction(builder.MakeAction(ledgerReadAction{}))
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "tx",
+			Usage:    "the ID of the read transaction, in hex string",
+			Required: true,
+		},
+	)
+}
+
+// OnStart implements node.Initializer. It creates the storage of the records
+// according to the backend selected by the start flag and injects it. The
+// records are replicated on the members of the collective authority. It also
+// creates the DKG, whose key and state are kept in the same backend, and
+// resumes Calypso if the DKG has been setup before the node restarted.
+func (m minimal) OnStart(flags cli.Flags, inj node.Injector) error {
+	var no mino.Mino
+	err := inj.Resolve(&no)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve mino: %v", err)
+	}
+
+	var store storage.KeyValue
+	var auditLog audit.Log
+	var dkgStorage pedersen.Storage
+
+	backend := flags.String(storageFlag)
+
+	switch backend {
+	case storageMemory:
+		store = inmemory.NewInMemory()
+		auditLog = audit.NewInMemory()
+		dkgStorage = pedersen.NewInMemoryStorage()
+	case storageDisk, "":
+		backend = storageDisk
+
+		db, err := kv.New(filepath.Join(flags.String("config"), dbFilename))
+		if err != nil {
+			return xerrors.Errorf("failed to open db: %v", err)
+		}
+
+		store = disk.NewDisk(db, calypso.NewRecordFactory(arc.NewFactory()))
+
+		// The audit log and the DKG are persisted next to the records.
+		auditLog, err = audit.NewDisk(db)
+		if err != nil {
+			return xerrors.Errorf("failed to open audit log: %v", err)
+		}
+
+		dkgStorage = pedersen.NewDiskStorage(db, no.GetAddressFactory())
+	default:
+		return xerrors.Errorf("unknown storage backend '%s'",
+			flags.String(storageFlag))
+	}
+
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+
+	// The members of the DKG are the members of the replication.
+	repl, err := replicated.NewStore(no, store, fac, dkgMembers{dkgStorage},
+		calypso.NewReplicaValidator())
+	if err != nil {
+		return xerrors.Errorf("failed to create replicated storage: %v", err)
+	}
+
+	inj.Inject(repl)
+	inj.Inject(auditLog)
+
+	interval := flags.Duration(sweepFlag)
+	if interval <= 0 {
+		return xerrors.Errorf("invalid sweep interval: %v", interval)
+	}
+
+	sweeper := calypso.NewSweeper(repl, interval)
+	sweeper.Start()
+
+	inj.Inject(sweeper)
+
+	dkg, pubkey, err := pedersen.NewPedersen(no, dkgStorage)
+	if err != nil {
+		return xerrors.Errorf("failed to create dkg: %v", err)
+	}
+
+	inj.Inject(dkg)
+
+	inj.Inject(&nodeStatus{
+		backend: backend,
+		dkg:     dkg,
+		store:   repl,
+		sweeper: sweeper,
+	})
+
+	pubkeyBuf, err := pubkey.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to encode pubkey: %v", err)
+	}
+
+	dela.Logger.Info().Hex("public key", pubkeyBuf).Msg("pedersen public key")
+
+	err = resume(inj, dkg, dkgStorage)
+	if err != nil {
+		return xerrors.Errorf("failed to resume calypso: %v", err)
+	}
+
+	var exec *native.Service
+	err = inj.Resolve(&exec)
+	if err != nil {
+		dela.Logger.Warn().Msgf("calypso contract not registered: %v", err)
+		return nil
+	}
+
+	contract.RegisterContract(exec, contract.NewContract(fac))
+
+	return nil
 }
 
-// Inject implements node.Initializer. This function contains the initialization
-// code run on each node that wants to support Calypso. We create the dkg actor
-// and then use it to create the Calypso, which is then injected as a
-// dependency. We will need this dependency in the setup phase.
-func (m minimal) OnStart(ctx cli.Flags, inj node.Injector) error {
+// resume starts Calypso if the DKG has been setup before the node restarted,
+// so that the node can decrypt again without running the listen command.
+func resume(inj node.Injector, dkg *pedersen.Pedersen,
+	dkgStorage pedersen.Storage) error {
+
+	st, err := dkgStorage.LoadState()
+	if err != nil {
+		return xerrors.Errorf("failed to load dkg state: %v", err)
+	}
+
+	if st == nil {
+		// the setup has not been done yet
+		return nil
+	}
+
+	actor, err := dkg.Listen()
+	if err != nil {
+		return xerrors.Errorf("failed to listen dkg: %v", err)
+	}
+
+	err = startCalypso(inj, actor)
+	if err != nil {
+		return xerrors.Errorf("failed to start calypso: %v", err)
+	}
+
+	dela.Logger.Info().Msg("calypso resumed")
+
 	return nil
 }
 
-// OnStop implements node.Initializer
-func (m minimal) OnStop(node.Injector) error {
+// dkgMembers provides the participants of the DKG of the node as the members
+// of the replication.
+//
+// - implements replicated.Membership
+type dkgMembers struct {
+	storage pedersen.Storage
+}
+
+// GetMembers implements replicated.Membership. The members are not known
+// until the DKG has been setup.
+func (m dkgMembers) GetMembers() ([]mino.Address, error) {
+	st, err := m.storage.LoadState()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load dkg state: %v", err)
+	}
+
+	if st == nil {
+		return nil, nil
+	}
+
+	return st.Participants, nil
+}
+
+// GetThreshold implements replicated.Membership. It returns the threshold of
+// the DKG, or zero if the setup has not been done.
+func (m dkgMembers) GetThreshold() (int, error) {
+	st, err := m.storage.LoadState()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to load dkg state: %v", err)
+	}
+
+	if st == nil {
+		return 0, nil
+	}
+
+	return st.Threshold, nil
+}
+
+// OnStop implements node.Initializer. It closes the storage if it is persisted
+// on disk.
+func (m minimal) OnStop(inj node.Injector) error {
+	var sweeper *calypso.Sweeper
+	err := inj.Resolve(&sweeper)
+	if err == nil {
+		sweeper.Stop()
+	}
+
+	var store storage.KeyValue
+	err = inj.Resolve(&store)
+	if err != nil {
+		return nil
+	}
+
+	closer, ok := store.(interface{ Close() error })
+	if !ok {
+		// the storage is not persisted
+		return nil
+	}
+
+	err = closer.Close()
+	if err != nil {
+		return xerrors.Errorf("failed to close storage: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/mino"
+	"golang.org/x/xerrors"
+)
+
+// rosterFile is the JSON form of the members given to the setup, for example:
+//
+//	{
+//	  "Threshold": 2,
+//	  "Members": [
+//	    {"Address": "RjEyNy4wLjAuMToyMDAx", "PublicKey": "4862...d601"},
+//	    {"Address": "RjEyNy4wLjAuMToyMDAy", "PublicKey": "9a23...4daf"}
+//	  ]
+//	}
+//
+// The address is the text form of the address, encoded in base64, and the
+// public key is the DKG public key printed by the node at start, in hex.
+type rosterFile struct {
+	Threshold int `json:",omitempty"`
+	Members   []rosterMember
+}
+
+// rosterMember is a member of the roster.
+type rosterMember struct {
+	Address   []byte
+	PublicKey string
+}
+
+// readRoster reads the roster from the JSON file.
+func readRoster(path string) (rosterFile, error) {
+	var roster rosterFile
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return roster, xerrors.Errorf("failed to read file: %v", err)
+	}
+
+	err = json.Unmarshal(data, &roster)
+	if err != nil {
+		return roster, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	return roster, nil
+}
+
+// toCA validates the members and returns the collective authority. The
+// addresses and the public keys must be valid and unique. An address is valid
+// when the factory gives it back in the same text form.
+func (r rosterFile) toCA(fac mino.AddressFactory) (internalCA, error) {
+	if len(r.Members) == 0 {
+		return internalCA{}, xerrors.New("roster is empty")
+	}
+
+	addrs := make([]mino.Address, len(r.Members))
+	pubkeys := make([]ed25519.PublicKey, len(r.Members))
+
+	seenAddrs := make(map[string]int)
+	seenKeys := make(map[string]int)
+
+	for i, member := range r.Members {
+		if len(member.Address) == 0 {
+			return internalCA{}, xerrors.Errorf("member %d: address is empty", i)
+		}
+
+		prev, found := seenAddrs[string(member.Address)]
+		if found {
+			return internalCA{}, xerrors.Errorf("member %d: address %q "+
+				"already used by member %d", i, member.Address, prev)
+		}
+
+		seenAddrs[string(member.Address)] = i
+
+		addr := fac.FromText(member.Address)
+
+		text, err := addr.MarshalText()
+		if err != nil || !bytes.Equal(text, member.Address) {
+			return internalCA{}, xerrors.Errorf("member %d: invalid address %q",
+				i, member.Address)
+		}
+
+		keyBuf, err := hex.DecodeString(member.PublicKey)
+		if err != nil {
+			return internalCA{}, xerrors.Errorf("member %d: failed to decode "+
+				"hex key: %v", i, err)
+		}
+
+		prev, found = seenKeys[string(keyBuf)]
+		if found {
+			return internalCA{}, xerrors.Errorf("member %d: public key "+
+				"already used by member %d", i, prev)
+		}
+
+		seenKeys[string(keyBuf)] = i
+
+		point := suite.Point()
+		err = point.UnmarshalBinary(keyBuf)
+		if err != nil {
+			return internalCA{}, xerrors.Errorf("member %d: failed to "+
+				"unmarshal point: %v", i, err)
+		}
+
+		addrs[i] = addr
+		pubkeys[i] = ed25519.NewPublicKeyFromPoint(point)
+	}
+
+	return newInternalCA(addrs, pubkeys), nil
+}
+
+// checkThreshold returns an error if the threshold is not between 1 and the
+// number of members.
+func checkThreshold(threshold, n int) error {
+	if threshold <= 0 {
+		return xerrors.Errorf("threshold wrong or not provided: %d", threshold)
+	}
+
+	if threshold > n {
+		return xerrors.Errorf("threshold %d is higher than the number of "+
+			"members %d", threshold, n)
+	}
+
+	return nil
+}
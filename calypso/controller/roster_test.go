@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.dedis.ch/dela/mino/minogrpc/session"
+)
+
+func TestReadRoster(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "calypso-roster")
+	if err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"valid", `{"Threshold": 2, "Members": [{"Address": "RjEyNy4wLjAuMToyMDAx", ` +
+			`"PublicKey": "ab"}]}`, ""},
+		{"no threshold", `{"Members": []}`, ""},
+		{"bad json", `{"Members": `, "failed to unmarshal"},
+		{"bad address", `{"Members": [{"Address": "not base64"}]}`,
+			"failed to unmarshal"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_"))
+
+			err := ioutil.WriteFile(path, []byte(tc.content), 0600)
+			if err != nil {
+				t.Fatalf("failed to write roster: %v", err)
+			}
+
+			_, err = readRoster(path)
+			checkError(t, err, tc.err)
+		})
+	}
+
+	roster, err := readRoster(filepath.Join(dir, "valid"))
+	if err != nil {
+		t.Fatalf("failed to read roster: %v", err)
+	}
+
+	if roster.Threshold != 2 || len(roster.Members) != 1 ||
+		string(roster.Members[0].Address) != "F127.0.0.1:2001" {
+
+		t.Fatalf("unexpected roster: %+v", roster)
+	}
+
+	_, err = readRoster(filepath.Join(dir, "missing"))
+	checkError(t, err, "failed to read file")
+}
+
+func TestRosterFile_ToCA(t *testing.T) {
+	key1 := makeKey(t)
+	key2 := makeKey(t)
+
+	addr1 := []byte("F127.0.0.1:2001")
+	addr2 := []byte("F127.0.0.1:2002")
+
+	testCases := []struct {
+		name    string
+		members []rosterMember
+		err     string
+	}{
+		{"valid", []rosterMember{{addr1, key1}, {addr2, key2}}, ""},
+		{"empty", nil, "roster is empty"},
+		{"empty address", []rosterMember{{addr1, key1}, {nil, key2}},
+			"member 1: address is empty"},
+		{"duplicate address", []rosterMember{{addr1, key1}, {addr1, key2}},
+			"member 1: address \"F127.0.0.1:2001\" already used by member 0"},
+		{"bad address", []rosterMember{{[]byte("X127.0.0.1:2001"), key1}},
+			"member 0: invalid address \"X127.0.0.1:2001\""},
+		{"bad hex key", []rosterMember{{addr1, "zz"}},
+			"member 0: failed to decode hex key"},
+		{"duplicate key", []rosterMember{{addr1, key1}, {addr2, key1}},
+			"member 1: public key already used by member 0"},
+		{"short key", []rosterMember{{addr1, key1[:10]}},
+			"member 0: failed to unmarshal point"},
+		{"not a point", []rosterMember{{addr1, "02" + strings.Repeat("00", 31)}},
+			"member 0: failed to unmarshal point"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roster := rosterFile{Members: tc.members}
+
+			ca, err := roster.toCA(session.AddressFactory{})
+			checkError(t, err, tc.err)
+
+			if err == nil && ca.Len() != len(tc.members) {
+				t.Fatalf("expected %d members, got %d", len(tc.members), ca.Len())
+			}
+		})
+	}
+}
+
+func TestCheckThreshold(t *testing.T) {
+	testCases := []struct {
+		threshold int
+		n         int
+		err       string
+	}{
+		{1, 1, ""},
+		{2, 3, ""},
+		{3, 3, ""},
+		{0, 3, "threshold wrong or not provided: 0"},
+		{-1, 3, "threshold wrong or not provided: -1"},
+		{4, 3, "threshold 4 is higher than the number of members 3"},
+	}
+
+	for _, tc := range testCases {
+		err := checkThreshold(tc.threshold, tc.n)
+		checkError(t, err, tc.err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// makeKey returns a new DKG public key in hex, as given in the roster.
+func makeKey(t *testing.T) string {
+	t.Helper()
+
+	point := suite.Point().Pick(suite.RandomStream())
+
+	buf, err := point.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal point: %v", err)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+
+	if expected == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", expected)
+	}
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected error '%s', got: %v", expected, err)
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/controller/api"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
+)
+
+// fingerprintLen is the number of bytes of the digest of the public key that
+// are kept in the fingerprint.
+const fingerprintLen = 8
+
+// nodeStatus gathers the status of the node for the status command and
+// endpoint.
+type nodeStatus struct {
+	backend string
+	dkg     *pedersen.Pedersen
+	store   storage.KeyValue
+	sweeper *calypso.Sweeper
+}
+
+// get returns the current status of the node.
+func (s *nodeStatus) get() (api.StatusResponse, error) {
+	phase, st, err := s.dkg.Status()
+	if err != nil {
+		return api.StatusResponse{}, xerrors.Errorf("failed to get dkg status: %v", err)
+	}
+
+	res := api.StatusResponse{
+		Phase:   string(phase),
+		Backend: s.backend,
+	}
+
+	counter, ok := s.store.(storage.Counter)
+	if ok {
+		res.Records, err = counter.Len()
+		if err != nil {
+			return api.StatusResponse{}, xerrors.Errorf("failed to count records: %v", err)
+		}
+	}
+
+	res.Expired, err = s.sweeper.CountExpired()
+	if err != nil {
+		return api.StatusResponse{}, xerrors.Errorf("failed to count expired: %v", err)
+	}
+
+	res.Swept = s.sweeper.GetSwept()
+
+	if st == nil {
+		return res, nil
+	}
+
+	res.Threshold = st.Threshold
+
+	res.Fingerprint, err = fingerprint(st.Commits[0])
+	if err != nil {
+		return api.StatusResponse{}, xerrors.Errorf("failed to fingerprint: %v", err)
+	}
+
+	res.Members = make([]api.StatusMember, len(st.Participants))
+
+	for i, addr := range st.Participants {
+		addrText, err := addr.MarshalText()
+		if err != nil {
+			return api.StatusResponse{}, xerrors.Errorf("failed to marshal address: %v", err)
+		}
+
+		pubkey, err := st.PublicKeys[i].MarshalBinary()
+		if err != nil {
+			return api.StatusResponse{}, xerrors.Errorf("failed to marshal public key: %v", err)
+		}
+
+		res.Members[i] = api.StatusMember{
+			Address:   addrText,
+			PublicKey: hex.EncodeToString(pubkey),
+		}
+	}
+
+	return res, nil
+}
+
+// fingerprint returns the first bytes of the SHA256 digest of the public key,
+// in hex string.
+func fingerprint(pubkey kyber.Point) (string, error) {
+	buf, err := pubkey.MarshalBinary()
+	if err != nil {
+		return "", xerrors.Errorf("failed to marshal public key: %v", err)
+	}
+
+	digest := sha256.Sum256(buf)
+
+	return hex.EncodeToString(digest[:fingerprintLen]), nil
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+// KeySize is the size in bytes of the symmetric keys used by the hybrid
+// encryption. It is small enough to be embedded in a single point so that it
+// can be protected by the collective key.
+const KeySize = 16
+
+// Envelope is a message encrypted by EncryptHybrid. (K, C) holds the message
+// when it fits in a point, otherwise the symmetric key of the data.
+type Envelope struct {
+	K    kyber.Point
+	C    kyber.Point
+	Data []byte
+}
+
+// NewKey returns a random key for the hybrid encryption.
+func NewKey(rand cipher.Stream) []byte {
+	key := make([]byte, KeySize)
+	random.Bytes(key, rand)
+
+	return key
+}
+
+// Seal encrypts and authenticates the message with the symmetric key using
+// AES-GCM. The random nonce is prepended to the ciphertext.
+func Seal(key, message []byte, rand cipher.Stream) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create aead: %v", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	random.Bytes(nonce, rand)
+
+	return aead.Seal(nonce, nonce, message, nil), nil
+}
+
+// Open decrypts and verifies a ciphertext produced by Seal.
+func Open(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create aead: %v", err)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, xerrors.Errorf("ciphertext too short: %d", len(ciphertext))
+	}
+
+	nonce := ciphertext[:aead.NonceSize()]
+
+	message, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open: %v", err)
+	}
+
+	return message, nil
+}
+
+// EncryptHybrid encrypts the message with the public key. The message is
+// embedded in C when it fits in a point. Otherwise it is sealed with a random
+// symmetric key, which is then embedded in C, and the sealed message is the
+// data of the envelope. The ephemeral scalar k is returned so that the write
+// proof can be created.
+func EncryptHybrid(message []byte, pubkey kyber.Point,
+	rand cipher.Stream) (kyber.Scalar, Envelope, error) {
+
+	if len(message) <= EmbedLen() {
+		k, K, C, err := Encrypt(message, pubkey, rand)
+		if err != nil {
+			return nil, Envelope{}, xerrors.Errorf("failed to encrypt: %v", err)
+		}
+
+		return k, Envelope{K: K, C: C}, nil
+	}
+
+	key := NewKey(rand)
+
+	data, err := Seal(key, message, rand)
+	if err != nil {
+		return nil, Envelope{}, xerrors.Errorf("failed to seal message: %v", err)
+	}
+
+	k, K, C, err := Encrypt(key, pubkey, rand)
+	if err != nil {
+		return nil, Envelope{}, xerrors.Errorf("failed to encrypt key: %v", err)
+	}
+
+	return k, Envelope{K: K, C: C, Data: data}, nil
+}
+
+// OpenHybrid returns the message of an envelope from the data embedded in C,
+// which is either the message itself or the symmetric key of the data.
+func OpenHybrid(embedded, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return embedded, nil
+	}
+
+	message, err := Open(embedded, data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt data: %v", err)
+	}
+
+	return message, nil
+}
+
+// DecryptHybrid returns the message of the envelope with the private key of
+// the public key used to encrypt it.
+func DecryptHybrid(secret kyber.Scalar, env Envelope) ([]byte, error) {
+	embedded, err := Decrypt(secret, env.K, env.C)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decrypt: %v", err)
+	}
+
+	message, err := OpenHybrid(embedded, env.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open: %v", err)
+	}
+
+	return message, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, xerrors.Errorf("invalid key size: %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create cipher: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create gcm: %v", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,85 @@
+// Package crypto implements the client side of the Calypso encryption. A
+// message is ElGamal-encrypted with the collective public key of the DKG into
+// (K, C), where C holds the message embedded in a point. Longer messages are
+// sealed with a symmetric key instead, which is the one embedded in C.
+//
+// The points and the scalars are the ones of Ed25519, in the encoding of
+// Kyber. The functions take the source of randomness so that the results can
+// be reproduced, which is what the test vectors of the package rely on.
+package crypto
+
+import (
+	"crypto/cipher"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+	"golang.org/x/xerrors"
+)
+
+// Suite is the Kyber suite of the Calypso keys, which is the one of the DKG.
+var Suite = suites.MustFind("Ed25519")
+
+// EmbedLen returns the maximum number of bytes that can be embedded in a point.
+func EmbedLen() int {
+	return Suite.Point().EmbedLen()
+}
+
+// Embed returns a point of the group holding the data. It returns an error if
+// the data doesn't fit in a point.
+func Embed(data []byte, rand cipher.Stream) (kyber.Point, error) {
+	if len(data) > EmbedLen() {
+		return nil, xerrors.Errorf("data too long to be embedded: %d > %d",
+			len(data), EmbedLen())
+	}
+
+	// Embed picks a random point, unless the data is non-nil.
+	if data == nil {
+		data = []byte{}
+	}
+
+	return Suite.Point().Embed(data, rand), nil
+}
+
+// Extract returns the data embedded in the point.
+func Extract(M kyber.Point) ([]byte, error) {
+	data, err := M.Data()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get embedded data: %v", err)
+	}
+
+	return data, nil
+}
+
+// Encrypt ElGamal-encrypts the message with the public key. The message must
+// fit in a point. The ephemeral scalar k is returned so that the write proof
+// can be created.
+func Encrypt(message []byte, pubkey kyber.Point,
+	rand cipher.Stream) (k kyber.Scalar, K, C kyber.Point, err error) {
+
+	M, err := Embed(message, rand)
+	if err != nil {
+		return nil, nil, nil, xerrors.Errorf("failed to embed message: %v", err)
+	}
+
+	k = Suite.Scalar().Pick(rand) // ephemeral private key
+	K = Suite.Point().Mul(k, nil) // ephemeral DH public key
+	S := Suite.Point().Mul(k, pubkey)
+	C = S.Add(S, M) // message blinded with the shared secret
+
+	return k, K, C, nil
+}
+
+// Decrypt returns the message embedded in C with the private key of the public
+// key used to encrypt it. In Calypso, the private key is distributed and the
+// readers use DecryptReencrypted instead.
+func Decrypt(secret kyber.Scalar, K, C kyber.Point) ([]byte, error) {
+	S := Suite.Point().Mul(secret, K)
+	M := Suite.Point().Sub(C, S)
+
+	message, err := Extract(M)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to extract message: %v", err)
+	}
+
+	return message, nil
+}
@@ -0,0 +1,444 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+var update = flag.Bool("update", false, "write the test vectors")
+
+const vectorsFile = "testdata/vectors.json"
+
+// Vectors are the test vectors of the package. They are generated from a fixed
+// seed, and hold every intermediate value so that other clients can check
+// their implementation step by step. The points and the scalars are hex
+// encoded as in Kyber, and the shares use the 0-based indices of Kyber.
+type Vectors struct {
+	Encrypt   []EncryptVector
+	Reencrypt []ReencryptVector
+}
+
+// EncryptVector is a message encrypted by EncryptHybrid with the public key of
+// Secret. Embedded is the point that holds the message, or the key of the
+// data when the message doesn't fit.
+type EncryptVector struct {
+	Secret   string
+	Pubkey   string
+	Message  string
+	Key      string
+	Embedded string
+	Scalar   string
+	K        string
+	C        string
+	Data     string
+}
+
+// ReencryptVector is a message encrypted with a collective key shared by N
+// members, with a threshold T, and re-encrypted for the reader with the shares
+// of T of them.
+type ReencryptVector struct {
+	T            int
+	N            int
+	Pubkey       string
+	ReaderSecret string
+	ReaderPubkey string
+	Message      string
+	K            string
+	C            string
+	Data         string
+	Shares       []ShareVector
+	XhatEnc      string
+}
+
+// ShareVector is the re-encryption share of a member.
+type ShareVector struct {
+	Index int
+	Value string
+}
+
+func TestVectors(t *testing.T) {
+	vectors := generateVectors(t)
+
+	if *update {
+		data, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatalf("failed to encode vectors: %v", err)
+		}
+
+		err = ioutil.WriteFile(vectorsFile, append(data, '\n'), 0644)
+		if err != nil {
+			t.Fatalf("failed to write vectors: %v", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatalf("failed to read vectors: %v", err)
+	}
+
+	var expected Vectors
+
+	err = json.Unmarshal(data, &expected)
+	if err != nil {
+		t.Fatalf("failed to decode vectors: %v", err)
+	}
+
+	generated, err := json.Marshal(vectors)
+	if err != nil {
+		t.Fatalf("failed to encode vectors: %v", err)
+	}
+
+	stored, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("failed to encode vectors: %v", err)
+	}
+
+	if !bytes.Equal(generated, stored) {
+		t.Fatalf("vectors are outdated, run the test with -update")
+	}
+
+	for i, v := range expected.Encrypt {
+		checkEncryptVector(t, i, v)
+	}
+
+	for i, v := range expected.Reencrypt {
+		checkReencryptVector(t, i, v)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	M, err := Embed([]byte("hello"), random.New())
+	if err != nil {
+		t.Fatalf("failed to embed: %v", err)
+	}
+
+	data, err := Extract(M)
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+
+	if string(data) != "hello" {
+		t.Fatalf("unexpected data: %q", data)
+	}
+
+	_, err = Embed(make([]byte, EmbedLen()+1), random.New())
+	if err == nil {
+		t.Fatal("expected an error for a message too long")
+	}
+
+	_, _, _, err = Encrypt(make([]byte, EmbedLen()+1), Suite.Point().Base(),
+		random.New())
+	if err == nil {
+		t.Fatal("expected an error for a message too long")
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	secret := Suite.Scalar().Pick(random.New())
+	pubkey := Suite.Point().Mul(secret, nil)
+
+	for _, size := range []int{0, 1, EmbedLen(), EmbedLen() + 1, 1000} {
+		message := make([]byte, size)
+		random.Bytes(message, random.New())
+
+		_, env, err := EncryptHybrid(message, pubkey, random.New())
+		if err != nil {
+			t.Fatalf("failed to encrypt %d bytes: %v", size, err)
+		}
+
+		if (size > EmbedLen()) != (len(env.Data) > 0) {
+			t.Fatalf("unexpected data for %d bytes: %d", size, len(env.Data))
+		}
+
+		res, err := DecryptHybrid(secret, env)
+		if err != nil {
+			t.Fatalf("failed to decrypt %d bytes: %v", size, err)
+		}
+
+		if !bytes.Equal(res, message) {
+			t.Fatalf("wrong message for %d bytes", size)
+		}
+	}
+
+	_, err := Open(NewKey(random.New()), make([]byte, 8))
+	if err == nil {
+		t.Fatal("expected an error for a short ciphertext")
+	}
+
+	key := NewKey(random.New())
+
+	data, err := Seal(key, []byte("hello"), random.New())
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+
+	_, err = Open(NewKey(random.New()), data)
+	if err == nil {
+		t.Fatal("expected an error for a wrong key")
+	}
+
+	_, err = Seal(key[1:], []byte("hello"), random.New())
+	if err == nil {
+		t.Fatal("expected an error for a wrong key size")
+	}
+}
+
+func TestCombineReencrypted(t *testing.T) {
+	secret := Suite.Scalar().Pick(random.New())
+	poly := share.NewPriPoly(Suite, 2, secret, random.New())
+
+	K := Suite.Point().Pick(random.New())
+	pubk := Suite.Point().Pick(random.New())
+
+	shares := []*share.PubShare{ReencryptShare(poly.Shares(3)[1], K, pubk)}
+
+	_, err := CombineReencrypted(shares, 2, 3)
+	if err == nil {
+		t.Fatal("expected an error for too few shares")
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// generateVectors returns the vectors of the package, which only depend on the
+// seed of the source of randomness.
+func generateVectors(t *testing.T) Vectors {
+	rand := Suite.XOF([]byte("calypso test vectors"))
+
+	messages := []string{
+		"",
+		"hello",
+		"a message of exactly 29 bytes",
+		"a message of exactly 30 bytes!",
+		"a longer message that is sealed with a symmetric key in the data field",
+	}
+
+	var vectors Vectors
+
+	for _, message := range messages {
+		secret := Suite.Scalar().Pick(rand)
+		pubkey := Suite.Point().Mul(secret, nil)
+
+		k, env, err := EncryptHybrid([]byte(message), pubkey, rand)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+
+		M := Suite.Point().Sub(env.C, Suite.Point().Mul(secret, env.K))
+
+		var key []byte
+		if len(env.Data) > 0 {
+			key, err = Extract(M)
+			if err != nil {
+				t.Fatalf("failed to extract: %v", err)
+			}
+		}
+
+		vectors.Encrypt = append(vectors.Encrypt, EncryptVector{
+			Secret:   encode(t, secret),
+			Pubkey:   encode(t, pubkey),
+			Message:  message,
+			Key:      hex.EncodeToString(key),
+			Embedded: encode(t, M),
+			Scalar:   encode(t, k),
+			K:        encode(t, env.K),
+			C:        encode(t, env.C),
+			Data:     hex.EncodeToString(env.Data),
+		})
+	}
+
+	for _, message := range messages[1:] {
+		const threshold, members = 3, 4
+
+		secret := Suite.Scalar().Pick(rand)
+		pubkey := Suite.Point().Mul(secret, nil)
+		poly := share.NewPriPoly(Suite, threshold, secret, rand)
+
+		readerSecret := Suite.Scalar().Pick(rand)
+		readerPubkey := Suite.Point().Mul(readerSecret, nil)
+
+		_, env, err := EncryptHybrid([]byte(message), pubkey, rand)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+
+		// The first member doesn't take part in the re-encryption.
+		privShares := poly.Shares(members)[1:]
+
+		pubShares := make([]*share.PubShare, len(privShares))
+		shareVectors := make([]ShareVector, len(privShares))
+
+		for i, priv := range privShares {
+			pubShares[i] = ReencryptShare(priv, env.K, readerPubkey)
+
+			shareVectors[i] = ShareVector{
+				Index: pubShares[i].I,
+				Value: encode(t, pubShares[i].V),
+			}
+		}
+
+		xhatEnc, err := CombineReencrypted(pubShares, threshold, members)
+		if err != nil {
+			t.Fatalf("failed to combine: %v", err)
+		}
+
+		vectors.Reencrypt = append(vectors.Reencrypt, ReencryptVector{
+			T:            threshold,
+			N:            members,
+			Pubkey:       encode(t, pubkey),
+			ReaderSecret: encode(t, readerSecret),
+			ReaderPubkey: encode(t, readerPubkey),
+			Message:      message,
+			K:            encode(t, env.K),
+			C:            encode(t, env.C),
+			Data:         hex.EncodeToString(env.Data),
+			Shares:       shareVectors,
+			XhatEnc:      encode(t, xhatEnc),
+		})
+	}
+
+	return vectors
+}
+
+// checkEncryptVector checks the vector as another client would, from the
+// values of the vector only.
+func checkEncryptVector(t *testing.T, i int, v EncryptVector) {
+	secret := decodeScalar(t, v.Secret)
+	k := decodeScalar(t, v.Scalar)
+	M := decodePoint(t, v.Embedded)
+	K := decodePoint(t, v.K)
+	C := decodePoint(t, v.C)
+	pubkey := decodePoint(t, v.Pubkey)
+	data := decodeHex(t, v.Data)
+
+	if !pubkey.Equal(Suite.Point().Mul(secret, nil)) {
+		t.Fatalf("encrypt %d: wrong public key", i)
+	}
+
+	if !K.Equal(Suite.Point().Mul(k, nil)) {
+		t.Fatalf("encrypt %d: wrong K", i)
+	}
+
+	if !C.Equal(Suite.Point().Add(Suite.Point().Mul(k, pubkey), M)) {
+		t.Fatalf("encrypt %d: wrong C", i)
+	}
+
+	embedded, err := Extract(M)
+	if err != nil {
+		t.Fatalf("encrypt %d: failed to extract: %v", i, err)
+	}
+
+	expected := []byte(v.Message)
+	if len(data) > 0 {
+		expected = decodeHex(t, v.Key)
+	}
+
+	if !bytes.Equal(embedded, expected) {
+		t.Fatalf("encrypt %d: wrong embedded data: %x", i, embedded)
+	}
+
+	message, err := DecryptHybrid(secret, Envelope{K: K, C: C, Data: data})
+	if err != nil {
+		t.Fatalf("encrypt %d: failed to decrypt: %v", i, err)
+	}
+
+	if string(message) != v.Message {
+		t.Fatalf("encrypt %d: wrong message: %q", i, message)
+	}
+}
+
+func checkReencryptVector(t *testing.T, i int, v ReencryptVector) {
+	readerSecret := decodeScalar(t, v.ReaderSecret)
+	readerPubkey := decodePoint(t, v.ReaderPubkey)
+
+	if !readerPubkey.Equal(Suite.Point().Mul(readerSecret, nil)) {
+		t.Fatalf("reencrypt %d: wrong reader public key", i)
+	}
+
+	shares := make([]*share.PubShare, len(v.Shares))
+	for j, s := range v.Shares {
+		shares[j] = &share.PubShare{I: s.Index, V: decodePoint(t, s.Value)}
+	}
+
+	xhatEnc, err := CombineReencrypted(shares, v.T, v.N)
+	if err != nil {
+		t.Fatalf("reencrypt %d: failed to combine: %v", i, err)
+	}
+
+	if !xhatEnc.Equal(decodePoint(t, v.XhatEnc)) {
+		t.Fatalf("reencrypt %d: wrong XhatEnc", i)
+	}
+
+	env := Envelope{
+		K:    decodePoint(t, v.K),
+		C:    decodePoint(t, v.C),
+		Data: decodeHex(t, v.Data),
+	}
+
+	message, err := DecryptReencrypted(xhatEnc, env, readerSecret,
+		decodePoint(t, v.Pubkey))
+	if err != nil {
+		t.Fatalf("reencrypt %d: failed to decrypt: %v", i, err)
+	}
+
+	if string(message) != v.Message {
+		t.Fatalf("reencrypt %d: wrong message: %q", i, message)
+	}
+}
+
+func encode(t *testing.T, v interface{ MarshalBinary() ([]byte, error) }) string {
+	t.Helper()
+
+	buf, err := v.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+func decodeHex(t *testing.T, str string) []byte {
+	t.Helper()
+
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		t.Fatalf("failed to decode hex: %v", err)
+	}
+
+	return buf
+}
+
+func decodeScalar(t *testing.T, str string) kyber.Scalar {
+	t.Helper()
+
+	s := Suite.Scalar()
+
+	err := s.UnmarshalBinary(decodeHex(t, str))
+	if err != nil {
+		t.Fatalf("failed to unmarshal scalar: %v", err)
+	}
+
+	return s
+}
+
+func decodePoint(t *testing.T, str string) kyber.Point {
+	t.Helper()
+
+	p := Suite.Point()
+
+	err := p.UnmarshalBinary(decodeHex(t, str))
+	if err != nil {
+		t.Fatalf("failed to unmarshal point: %v", err)
+	}
+
+	return p
+}
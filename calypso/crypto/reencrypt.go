@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"golang.org/x/xerrors"
+)
+
+// ReencryptShare returns the contribution of a share of the collective private
+// key to the re-encryption of K under the public key of the reader, which is
+// xi*(K + pubk).
+func ReencryptShare(priv *share.PriShare, K, pubk kyber.Point) *share.PubShare {
+	V := Suite.Point().Add(K, pubk)
+
+	return &share.PubShare{
+		I: priv.I,
+		V: V.Mul(priv.V, V),
+	}
+}
+
+// CombineReencrypted combines the re-encryption shares of at least t of the n
+// members into XhatEnc = x*K + xc*X, where x is the collective private key, X
+// the collective public key and xc the private key of the reader.
+func CombineReencrypted(shares []*share.PubShare, t, n int) (kyber.Point, error) {
+	xhatEnc, err := share.RecoverCommit(Suite, shares, t, n)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to recover commit: %v", err)
+	}
+
+	return xhatEnc, nil
+}
+
+// DecryptReencrypted is used by the reader to recover the message of an
+// envelope re-encrypted under its public key, with its private key xc and the
+// collective public key X. K is not needed as it is part of XhatEnc.
+func DecryptReencrypted(xhatEnc kyber.Point, env Envelope, xc kyber.Scalar,
+	X kyber.Point) ([]byte, error) {
+
+	// Xhat = XhatEnc - xc*X is the shared secret x*K.
+	xcX := Suite.Point().Mul(xc, X)
+	xhat := Suite.Point().Sub(xhatEnc, xcX)
+
+	M := Suite.Point().Sub(env.C, xhat)
+
+	embedded, err := Extract(M)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to extract: %v", err)
+	}
+
+	message, err := OpenHybrid(embedded, env.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open: %v", err)
+	}
+
+	return message, nil
+}
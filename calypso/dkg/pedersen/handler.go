@@ -0,0 +1,909 @@
+package pedersen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen/types"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	pedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"golang.org/x/xerrors"
+)
+
+// recvResponseTimeout is the maximum time a node will wait for a response
+const recvResponseTimeout = time.Second * 10
+
+// state is a struct contained in a handler that allows an actor to read the
+// state of that handler. The actor should only use the getter functions to read
+// the attributes.
+type state struct {
+	sync.Mutex
+	distrKey     kyber.Point
+	participants []mino.Address
+}
+
+func (s *state) Done() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.distrKey != nil && s.participants != nil
+}
+
+func (s *state) GetDistKey() kyber.Point {
+	s.Lock()
+	defer s.Unlock()
+	return s.distrKey
+}
+
+func (s *state) SetDistKey(key kyber.Point) {
+	s.Lock()
+	s.distrKey = key
+	s.Unlock()
+}
+
+func (s *state) GetParticipants() []mino.Address {
+	s.Lock()
+	defer s.Unlock()
+	return s.participants
+}
+
+func (s *state) SetParticipants(addrs []mino.Address) {
+	s.Lock()
+	s.participants = addrs
+	s.Unlock()
+}
+
+// Handler represents the RPC executed on each node
+//
+// - implements mino.Handler
+type Handler struct {
+	mino.UnsupportedHandler
+	sync.RWMutex
+	dkg       *pedersen.DistKeyGenerator
+	privKey   kyber.Scalar
+	me        mino.Address
+	privShare *share.PriShare
+	startRes  *state
+	storage   Storage
+}
+
+// NewHandler creates a new handler. The result of the setup is stored in the
+// storage.
+func NewHandler(privKey kyber.Scalar, me mino.Address, storage Storage) *Handler {
+	return &Handler{
+		privKey:  privKey,
+		me:       me,
+		startRes: &state{},
+		storage:  storage,
+	}
+}
+
+// restore restores the result of a previous setup, if any.
+func (h *Handler) restore() error {
+	st, err := h.storage.LoadState()
+	if err != nil {
+		return xerrors.Errorf("failed to load: %v", err)
+	}
+
+	if st == nil {
+		return nil
+	}
+
+	h.Lock()
+	h.privShare = st.Share
+	h.Unlock()
+
+	h.startRes.SetParticipants(st.Participants)
+	h.startRes.SetDistKey(st.Commits[0])
+
+	dela.Logger.Info().Msgf("%s restored DKG state with %d participants",
+		h.me, len(st.Participants))
+
+	return nil
+}
+
+// Stream implements mino.Handler. It allows one to stream messages to the
+// players.
+func (h *Handler) Stream(out mino.Sender, in mino.Receiver) error {
+	// Note: one should never assume any synchronous properties on the messages.
+	// For example we can not expect to receive the start message from the
+	// initiator of the DKG protocol first because some node could have received
+	// this start message earlier than us, start their DKG work by sending
+	// messages to the other nodes, and then we might get their messages before
+	// the start message.
+
+	deals := []types.Deal{}
+	responses := []*pedersen.Response{}
+
+mainSwitch:
+	from, msg, err := in.Recv(context.Background())
+	if err != nil {
+		return xerrors.Errorf("failed to receive: %v", err)
+	}
+
+	// We expect a Start message or a decrypt request at first, but we might
+	// receive other messages in the meantime, like a Deal.
+	switch msg := msg.(type) {
+
+	case types.Start:
+		err := h.start(msg, deals, responses, from, out, in)
+		if err != nil {
+			return xerrors.Errorf("failed to start: %v", err)
+		}
+
+	case types.StartResharing:
+		err := h.reshare(msg, deals, responses, from, out, in)
+		if err != nil {
+			return xerrors.Errorf("failed to reshare: %v", err)
+		}
+
+	case types.Deal:
+		// This is a special case where a DKG started, some nodes received the
+		// start signal and started sending their deals but we have not yet
+		// received our start signal. In this case we collect the Deals while
+		// waiting for the start signal.
+		deals = append(deals, msg)
+		goto mainSwitch
+
+	case types.Response:
+		// This is a special case where a DKG started, some nodes received the
+		// start signal and started sending their deals but we have not yet
+		// received our start signal. In this case we collect the Response while
+		// waiting for the start signal.
+		response := &pedersen.Response{
+			Index: msg.GetIndex(),
+			Response: &vss.Response{
+				SessionID: msg.GetResponse().GetSessionID(),
+				Index:     msg.GetResponse().GetIndex(),
+				Status:    msg.GetResponse().GetStatus(),
+				Signature: msg.GetResponse().GetSignature(),
+			},
+		}
+		responses = append(responses, response)
+		goto mainSwitch
+
+	case types.DecryptRequest:
+		if !h.startRes.Done() {
+			return xerrors.Errorf("you must first initialize DKG. Did you " +
+				"call setup() first?")
+		}
+
+		// Only the participants can request a decryption share, as they
+		// verify the access to the records before.
+		if !contains(h.startRes.GetParticipants(), from) {
+			return xerrors.Errorf("'%s' is not a participant", from)
+		}
+
+		h.RLock()
+		S := suite.Point().Mul(h.privShare.V, msg.K)
+		h.RUnlock()
+
+		partial := suite.Point().Sub(msg.C, S)
+
+		h.RLock()
+		decryptReply := types.NewDecryptReply(
+			// TODO: check if using the private index is the same as the public
+			// index.
+			int64(h.privShare.I),
+			partial,
+		)
+		h.RUnlock()
+
+		errs := out.Send(decryptReply, from)
+		err = <-errs
+		if err != nil {
+			return xerrors.Errorf("got an error while sending the decrypt "+
+				"reply: %v", err)
+		}
+
+	case types.ReencryptRequest:
+		err := h.reencrypt(msg, from, out)
+		if err != nil {
+			return xerrors.Errorf("failed to reencrypt: %v", err)
+		}
+
+	default:
+		return xerrors.Errorf("expected Start message, decrypt request or "+
+			"Deal as first message, got: %T", msg)
+	}
+
+	return nil
+}
+
+// reencrypt sends back the re-encryption share of the node. Only the
+// participants can request it, as they verify the access to the records
+// before.
+func (h *Handler) reencrypt(req types.ReencryptRequest, from mino.Address,
+	out mino.Sender) error {
+
+	if !h.startRes.Done() {
+		return xerrors.Errorf("you must first initialize DKG. Did you " +
+			"call setup() first?")
+	}
+
+	if !contains(h.startRes.GetParticipants(), from) {
+		return xerrors.Errorf("'%s' is not a participant", from)
+	}
+
+	h.RLock()
+	pubShare := calycrypto.ReencryptShare(h.privShare, req.GetK(), req.GetPubK())
+	h.RUnlock()
+
+	reply := types.NewReencryptReply(int64(pubShare.I), pubShare.V)
+
+	err := <-out.Send(reply, from)
+	if err != nil {
+		return xerrors.Errorf("got an error while sending the reencrypt "+
+			"reply: %v", err)
+	}
+
+	return nil
+}
+
+// start is called when the node has received its start message. Note that we
+// might have already received some deals from other nodes in the meantime. The
+// function handles the DKG creation protocol.
+func (h *Handler) start(start types.Start, receivedDeals []types.Deal,
+	receivedResps []*pedersen.Response, from mino.Address, out mino.Sender,
+	in mino.Receiver) error {
+
+	if len(start.GetAddresses()) != len(start.GetPublicKeys()) {
+		return xerrors.Errorf("there should be as many players as "+
+			"pubKey: %d := %d", len(start.GetAddresses()), len(start.GetPublicKeys()))
+	}
+
+	// A new setup would replace the share of the node, and therefore the
+	// distributed key that protects the records. The committee can only change
+	// with a resharing.
+	st, err := h.storage.LoadState()
+	if err != nil {
+		return xerrors.Errorf("failed to load state: %v", err)
+	}
+
+	if st != nil {
+		return xerrors.Errorf("%s already holds a share of the DKG", h.me)
+	}
+
+	// 1. Create the DKG
+	d, err := pedersen.NewDistKeyGenerator(suite, h.privKey, start.GetPublicKeys(), start.GetThreshold())
+	if err != nil {
+		return xerrors.Errorf("failed to create new DKG: %v", err)
+	}
+	h.dkg = d
+
+	// 2. Send my Deals to the other nodes
+	deals, err := d.Deals()
+	if err != nil {
+		return xerrors.Errorf("failed to compute the deals: %v", err)
+	}
+
+	// use a waitgroup to send all the deals asynchronously and wait
+	var wg sync.WaitGroup
+	wg.Add(len(deals))
+
+	for i, deal := range deals {
+		dealMsg := types.NewDeal(
+			deal.Index,
+			deal.Signature,
+			types.NewEncryptedDeal(
+				deal.Deal.DHKey,
+				deal.Deal.Signature,
+				deal.Deal.Nonce,
+				deal.Deal.Cipher,
+			),
+		)
+
+		errs := out.Send(dealMsg, start.GetAddresses()[i])
+		go func(errs <-chan error) {
+			err, more := <-errs
+			if more {
+				dela.Logger.Warn().Msgf("got an error while sending deal: %v", err)
+			}
+			wg.Done()
+		}(errs)
+	}
+
+	wg.Wait()
+
+	dela.Logger.Trace().Msgf("%s sent all its deals", h.me)
+
+	numReceivedDeals := 0
+
+	// Process the deals we received before the start message
+	for _, deal := range receivedDeals {
+		err = h.handleDeal(deal, from, start.GetAddresses(), out)
+		if err != nil {
+			dela.Logger.Warn().Msgf("%s failed to handle received deal "+
+				"from %s: %v", h.me, from, err)
+		}
+		numReceivedDeals++
+	}
+
+	// If there are N nodes, then N nodes first send (N-1) Deals. Then each node
+	// send a response to every other nodes. So the number of responses a node
+	// get is (N-1) * (N-1), where (N-1) should equal len(deals).
+	for numReceivedDeals < len(deals) {
+		from, msg, err := in.Recv(context.Background())
+		if err != nil {
+			return xerrors.Errorf("failed to receive after sending deals: %v", err)
+		}
+
+		switch msg := msg.(type) {
+
+		case types.Deal:
+			// 4. Process the Deal and Send the response to all the other nodes
+			err = h.handleDeal(msg, from, start.GetAddresses(), out)
+			if err != nil {
+				dela.Logger.Warn().Msgf("%s failed to handle received deal "+
+					"from %s: %v", h.me, from, err)
+				return xerrors.Errorf("failed to handle deal from '%s': %v", from, err)
+			}
+			numReceivedDeals++
+
+		case types.Response:
+			// 5. Processing responses
+			dela.Logger.Trace().Msgf("%s received response from %s", h.me, from)
+			response := &pedersen.Response{
+				Index: msg.GetIndex(),
+				Response: &vss.Response{
+					SessionID: msg.GetResponse().GetSessionID(),
+					Index:     msg.GetResponse().GetIndex(),
+					Status:    msg.GetResponse().GetStatus(),
+					Signature: msg.GetResponse().GetSignature(),
+				},
+			}
+			receivedResps = append(receivedResps, response)
+
+		default:
+			return xerrors.Errorf("unexpected message: %T", msg)
+		}
+	}
+
+	h.startRes.SetParticipants(start.GetAddresses())
+
+	err = h.certify(start, receivedResps, out, in, from)
+	if err != nil {
+		return xerrors.Errorf("failed to certify: %v", err)
+	}
+
+	return nil
+}
+
+func (h *Handler) certify(start types.Start, resps []*pedersen.Response,
+	out mino.Sender, in mino.Receiver, from mino.Address) error {
+
+	err := h.waitCertified(resps, in)
+	if err != nil {
+		return xerrors.Errorf("failed to certify: %v", err)
+	}
+
+	// 6. Send back the public DKG key
+	distrKey, err := h.dkg.DistKeyShare()
+	if err != nil {
+		return xerrors.Errorf("failed to get distr key: %v", err)
+	}
+
+	// 7. Persist the result so that the node can resume after a restart, and
+	// update the state before sending to acknowledgement to the orchestrator,
+	// so that it can process decrypt requests right away.
+	err = h.storage.StoreState(State{
+		Threshold:    start.GetThreshold(),
+		Participants: start.GetAddresses(),
+		PublicKeys:   start.GetPublicKeys(),
+		Share:        distrKey.PriShare(),
+		Commits:      distrKey.Commitments(),
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to store state: %v", err)
+	}
+
+	h.startRes.SetDistKey(distrKey.Public())
+
+	h.Lock()
+	h.privShare = distrKey.PriShare()
+	h.Unlock()
+
+	done := types.NewStartDone(distrKey.Public())
+	err = <-out.Send(done, from)
+	if err != nil {
+		return xerrors.Errorf("got an error while sending pub key: %v", err)
+	}
+
+	return nil
+}
+
+// waitCertified processes the responses received so far, and then the ones
+// from the stream until the DKG is certified.
+func (h *Handler) waitCertified(resps []*pedersen.Response, in mino.Receiver) error {
+	for _, response := range resps {
+		_, err := h.dkg.ProcessResponse(response)
+		if err != nil {
+			dela.Logger.Warn().Msgf("%s failed to process response: %v", h.me, err)
+		}
+	}
+
+	for !h.dkg.Certified() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			recvResponseTimeout)
+		defer cancel()
+
+		from, msg, err := in.Recv(ctx)
+		if err != nil {
+			return xerrors.Errorf("failed to receive after sending deals: %v", err)
+		}
+
+		switch msg := msg.(type) {
+
+		case types.Response:
+			// 5. Processing responses
+			dela.Logger.Trace().Msgf("%s received response from %s", h.me, from)
+			response := &pedersen.Response{
+				Index: msg.GetIndex(),
+				Response: &vss.Response{
+					SessionID: msg.GetResponse().GetSessionID(),
+					Index:     msg.GetResponse().GetIndex(),
+					Status:    msg.GetResponse().GetStatus(),
+					Signature: msg.GetResponse().GetSignature(),
+				},
+			}
+
+			_, err = h.dkg.ProcessResponse(response)
+			if err != nil {
+				dela.Logger.Warn().Msgf("%s, failed to process response "+
+					"from '%s': %v", h.me, from, err)
+			}
+
+		default:
+			return xerrors.Errorf("expected a response, got: %T", msg)
+		}
+	}
+
+	dela.Logger.Trace().Msgf("%s is certified", h.me)
+
+	return nil
+}
+
+// reshare is called when the node has received its start resharing message.
+// The nodes of the previous committee deal their share to the nodes of the
+// next committee, which then hold a share of the same distributed key. A node
+// that is not part of the next committee deletes its share at the end.
+func (h *Handler) reshare(msg types.StartResharing, receivedDeals []types.Deal,
+	receivedResps []*pedersen.Response, from mino.Address, out mino.Sender,
+	in mino.Receiver) error {
+
+	next := msg.GetNext()
+	previous := msg.GetPrevious()
+
+	if len(next.GetAddresses()) != len(next.GetPublicKeys()) {
+		return xerrors.Errorf("there should be as many players as "+
+			"pubKey: %d := %d", len(next.GetAddresses()), len(next.GetPublicKeys()))
+	}
+
+	if len(previous.GetAddresses()) != len(previous.GetPublicKeys()) {
+		return xerrors.Errorf("there should be as many previous players as "+
+			"pubKey: %d := %d", len(previous.GetAddresses()),
+			len(previous.GetPublicKeys()))
+	}
+
+	pubkey := suite.Point().Mul(h.privKey, nil)
+
+	oldIndex := indexOf(previous.GetPublicKeys(), pubkey)
+	newIndex := indexOf(next.GetPublicKeys(), pubkey)
+
+	st, err := h.storage.LoadState()
+	if err != nil {
+		return xerrors.Errorf("failed to load state: %v", err)
+	}
+
+	if st != nil {
+		// A member of the committee only reshares the key that it holds, when
+		// asked by another member.
+		err = checkResharing(*st, previous, msg.GetCommits(), from)
+		if err != nil {
+			return xerrors.Errorf("invalid resharing: %v", err)
+		}
+	} else {
+		// A node that doesn't hold a share has no state to verify the
+		// previous committee against, so that it joins the one given by the
+		// sender, as long as the sender is part of it.
+		if oldIndex >= 0 {
+			return xerrors.Errorf("%s has no share to reshare", h.me)
+		}
+
+		if !contains(previous.GetAddresses(), from) {
+			return xerrors.Errorf("'%s' is not a previous participant", from)
+		}
+	}
+
+	config := &pedersen.Config{
+		Suite:        suite,
+		Longterm:     h.privKey,
+		OldNodes:     previous.GetPublicKeys(),
+		NewNodes:     next.GetPublicKeys(),
+		Threshold:    next.GetThreshold(),
+		OldThreshold: previous.GetThreshold(),
+	}
+
+	if oldIndex >= 0 {
+		// The share must come from the local state, and match the position of
+		// the node in the previous committee.
+		if st.Share.I != oldIndex {
+			return xerrors.Errorf("share index mismatch: %d != %d",
+				st.Share.I, oldIndex)
+		}
+
+		config.Share = &pedersen.DistKeyShare{
+			Commits: st.Commits,
+			Share:   st.Share,
+		}
+	} else {
+		config.PublicCoeffs = msg.GetCommits()
+	}
+
+	// 1. Create the DKG with the resharing configuration
+	d, err := pedersen.NewDistKeyHandler(config)
+	if err != nil {
+		return xerrors.Errorf("failed to create new DKG: %v", err)
+	}
+	h.dkg = d
+
+	// The responses are sent to both committees, as the nodes of the previous
+	// one wait for the approval of their deal.
+	players := union(previous.GetAddresses(), next.GetAddresses())
+
+	// 2. Send my Deals to the nodes of the next committee
+	deals, err := d.Deals()
+	if err != nil {
+		return xerrors.Errorf("failed to compute the deals: %v", err)
+	}
+
+	for i, deal := range deals {
+		dealMsg := types.NewDeal(
+			deal.Index,
+			deal.Signature,
+			types.NewEncryptedDeal(
+				deal.Deal.DHKey,
+				deal.Deal.Signature,
+				deal.Deal.Nonce,
+				deal.Deal.Cipher,
+			),
+		)
+
+		err = <-out.Send(dealMsg, next.GetAddresses()[i])
+		if err != nil {
+			dela.Logger.Warn().Msgf("got an error while sending deal: %v", err)
+		}
+	}
+
+	dela.Logger.Trace().Msgf("%s sent all its resharing deals", h.me)
+
+	if newIndex < 0 {
+		// Each node of the next committee sends a response for each deal it
+		// receives, which doesn't include its own when it was already part of
+		// the previous committee.
+		expected := 0
+		for _, pk := range next.GetPublicKeys() {
+			expected += len(previous.GetPublicKeys())
+			if indexOf(previous.GetPublicKeys(), pk) >= 0 {
+				expected--
+			}
+		}
+
+		return h.leave(msg, oldIndex, expected, receivedResps, from, out, in)
+	}
+
+	numReceivedDeals := 0
+
+	for _, deal := range receivedDeals {
+		err = h.handleDeal(deal, from, players, out)
+		if err != nil {
+			return xerrors.Errorf("failed to handle deal: %v", err)
+		}
+		numReceivedDeals++
+	}
+
+	for numReceivedDeals < d.ExpectedDeals() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			recvResponseTimeout)
+		defer cancel()
+
+		from, msg, err := in.Recv(ctx)
+		if err != nil {
+			return xerrors.Errorf("failed to receive deals: %v", err)
+		}
+
+		switch msg := msg.(type) {
+
+		case types.Deal:
+			err = h.handleDeal(msg, from, players, out)
+			if err != nil {
+				return xerrors.Errorf("failed to handle deal from '%s': %v", from, err)
+			}
+			numReceivedDeals++
+
+		case types.Response:
+			response := &pedersen.Response{
+				Index: msg.GetIndex(),
+				Response: &vss.Response{
+					SessionID: msg.GetResponse().GetSessionID(),
+					Index:     msg.GetResponse().GetIndex(),
+					Status:    msg.GetResponse().GetStatus(),
+					Signature: msg.GetResponse().GetSignature(),
+				},
+			}
+			receivedResps = append(receivedResps, response)
+
+		default:
+			return xerrors.Errorf("unexpected message: %T", msg)
+		}
+	}
+
+	err = h.waitCertified(receivedResps, in)
+	if err != nil {
+		return xerrors.Errorf("failed to certify: %v", err)
+	}
+
+	distrKey, err := h.dkg.DistKeyShare()
+	if err != nil {
+		return xerrors.Errorf("failed to get distr key: %v", err)
+	}
+
+	err = h.storage.StoreState(State{
+		Threshold:    next.GetThreshold(),
+		Participants: next.GetAddresses(),
+		PublicKeys:   next.GetPublicKeys(),
+		Share:        distrKey.PriShare(),
+		Commits:      distrKey.Commitments(),
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to store state: %v", err)
+	}
+
+	h.startRes.SetParticipants(next.GetAddresses())
+	h.startRes.SetDistKey(distrKey.Public())
+
+	h.Lock()
+	h.privShare = distrKey.PriShare()
+	h.Unlock()
+
+	dela.Logger.Info().Msgf("%s holds a new share of the DKG", h.me)
+
+	done := types.NewStartDone(distrKey.Public())
+	err = <-out.Send(done, from)
+	if err != nil {
+		return xerrors.Errorf("got an error while sending pub key: %v", err)
+	}
+
+	return nil
+}
+
+// leave waits for the responses of the nodes of the next committee, and then
+// deletes the share of the node, as it is not part of the committee anymore.
+// All the deals of the node must have been approved.
+func (h *Handler) leave(msg types.StartResharing, oldIndex, expected int,
+	resps []*pedersen.Response, from mino.Address, out mino.Sender,
+	in mino.Receiver) error {
+
+	for len(resps) < expected {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			recvResponseTimeout)
+		defer cancel()
+
+		_, m, err := in.Recv(ctx)
+		if err != nil {
+			return xerrors.Errorf("failed to receive responses: %v", err)
+		}
+
+		resp, ok := m.(types.Response)
+		if !ok {
+			return xerrors.Errorf("expected a response, got: %T", m)
+		}
+
+		resps = append(resps, &pedersen.Response{
+			Index: resp.GetIndex(),
+			Response: &vss.Response{
+				SessionID: resp.GetResponse().GetSessionID(),
+				Index:     resp.GetResponse().GetIndex(),
+				Status:    resp.GetResponse().GetStatus(),
+				Signature: resp.GetResponse().GetSignature(),
+			},
+		})
+	}
+
+	approvals := 0
+
+	for _, response := range resps {
+		if response.Index != uint32(oldIndex) {
+			// Responses to the deals of the other nodes are only of interest
+			// to the next committee.
+			continue
+		}
+
+		justification, err := h.dkg.ProcessResponse(response)
+		if err != nil {
+			return xerrors.Errorf("failed to process response: %v", err)
+		}
+
+		if justification != nil || !response.Response.Status {
+			return xerrors.Errorf("deal has been rejected by %d",
+				response.Response.Index)
+		}
+
+		approvals++
+	}
+
+	if approvals != len(msg.GetNext().GetAddresses()) {
+		return xerrors.Errorf("deal approved by %d nodes out of %d", approvals,
+			len(msg.GetNext().GetAddresses()))
+	}
+
+	err := h.storage.DeleteState()
+	if err != nil {
+		return xerrors.Errorf("failed to delete state: %v", err)
+	}
+
+	h.startRes.SetParticipants(nil)
+	h.startRes.SetDistKey(nil)
+
+	h.Lock()
+	h.privShare = nil
+	h.Unlock()
+
+	dela.Logger.Info().Msgf("%s left the DKG committee", h.me)
+
+	done := types.NewStartDone(msg.GetCommits()[0])
+	err = <-out.Send(done, from)
+	if err != nil {
+		return xerrors.Errorf("got an error while sending pub key: %v", err)
+	}
+
+	return nil
+}
+
+// handleDeal process the Deal and send the responses to the other nodes.
+func (h *Handler) handleDeal(msg types.Deal, from mino.Address, addrs []mino.Address,
+	out mino.Sender) error {
+
+	dela.Logger.Trace().Msgf("%s received deal from %s", h.me, from)
+
+	deal := &pedersen.Deal{
+		Index: msg.GetIndex(),
+		Deal: &vss.EncryptedDeal{
+			DHKey:     msg.GetEncryptedDeal().GetDHKey(),
+			Signature: msg.GetEncryptedDeal().GetSignature(),
+			Nonce:     msg.GetEncryptedDeal().GetNonce(),
+			Cipher:    msg.GetEncryptedDeal().GetCipher(),
+		},
+		Signature: msg.GetSignature(),
+	}
+
+	response, err := h.dkg.ProcessDeal(deal)
+	if err != nil {
+		return xerrors.Errorf("failed to process deal from %s: %v",
+			h.me, err)
+	}
+
+	resp := types.NewResponse(
+		response.Index,
+		types.NewDealerResponse(
+			response.Response.Index,
+			response.Response.Status,
+			response.Response.SessionID,
+			response.Response.Signature,
+		),
+	)
+
+	for _, addr := range addrs {
+		if addr.Equal(h.me) {
+			continue
+		}
+
+		errs := out.Send(resp, addr)
+		err = <-errs
+		if err != nil {
+			dela.Logger.Warn().Msgf("got an error while sending "+
+				"response: %v", err)
+			return xerrors.Errorf("failed to send response to '%s': %v", addr, err)
+		}
+
+	}
+
+	return nil
+}
+
+// checkResharing returns an error if the resharing is not requested by a
+// participant, or if the previous committee and the commitments are not the
+// ones of the state.
+func checkResharing(st State, previous types.Start, commits []kyber.Point,
+	from mino.Address) error {
+
+	if !contains(st.Participants, from) {
+		return xerrors.Errorf("'%s' is not a participant", from)
+	}
+
+	if previous.GetThreshold() != st.Threshold {
+		return xerrors.Errorf("threshold mismatch: %d != %d",
+			previous.GetThreshold(), st.Threshold)
+	}
+
+	addrs := previous.GetAddresses()
+
+	if len(addrs) != len(st.Participants) {
+		return xerrors.Errorf("participants mismatch: %d != %d", len(addrs),
+			len(st.Participants))
+	}
+
+	for i, addr := range addrs {
+		if !addr.Equal(st.Participants[i]) {
+			return xerrors.Errorf("participant %d mismatch: %s != %s", i, addr,
+				st.Participants[i])
+		}
+	}
+
+	if !equalPoints(previous.GetPublicKeys(), st.PublicKeys) {
+		return xerrors.New("public keys mismatch")
+	}
+
+	if !equalPoints(commits, st.Commits) {
+		return xerrors.New("commits mismatch")
+	}
+
+	return nil
+}
+
+// equalPoints returns true if both lists have the same points in the same
+// order.
+func equalPoints(a, b []kyber.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, point := range a {
+		if !point.Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// indexOf returns the index of the public key in the list, or -1 if it is not
+// found.
+func indexOf(pubkeys []kyber.Point, pubkey kyber.Point) int {
+	for i, pk := range pubkeys {
+		if pk.Equal(pubkey) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// union returns the addresses of both lists, without duplicates.
+func union(a, b []mino.Address) []mino.Address {
+	res := append([]mino.Address{}, a...)
+
+	for _, addr := range b {
+		if !contains(res, addr) {
+			res = append(res, addr)
+		}
+	}
+
+	return res
+}
+
+// contains returns true if the address is in the list.
+func contains(addrs []mino.Address, addr mino.Address) bool {
+	for _, other := range addrs {
+		if other.Equal(addr) {
+			return true
+		}
+	}
+
+	return false
+}
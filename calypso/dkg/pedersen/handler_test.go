@@ -0,0 +1,561 @@
+package pedersen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen/types"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	pedersen "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"golang.org/x/xerrors"
+)
+
+func TestHandler_Stream(t *testing.T) {
+	h := NewHandler(suite.Scalar().Pick(suite.RandomStream()), fakeAddress(0),
+		NewInMemoryStorage())
+
+	err := h.Stream(fakeSender{}, newBadReceiver())
+	checkError(t, err, "failed to receive: fake error")
+
+	receiver := newReceiver(
+		fakeRecv{from: fakeAddress(0), msg: types.Deal{}},
+		fakeRecv{from: fakeAddress(0), msg: types.DecryptRequest{}},
+	)
+	err = h.Stream(fakeSender{}, receiver)
+	checkError(t, err, "you must first initialize DKG. Did you call setup() first?")
+
+	h.startRes.SetDistKey(suite.Point())
+	h.startRes.SetParticipants([]mino.Address{fakeAddress(0)})
+	h.privShare = &share.PriShare{I: 0, V: suite.Scalar()}
+
+	receiver = newReceiver(
+		fakeRecv{from: fakeAddress(0), msg: types.DecryptRequest{C: suite.Point()}},
+	)
+	err = h.Stream(fakeSender{err: fakeErr}, receiver)
+	checkError(t, err, "got an error while sending the decrypt reply: fake error")
+
+	receiver = newReceiver(
+		fakeRecv{from: fakeAddress(1), msg: types.DecryptRequest{C: suite.Point()}},
+	)
+	err = h.Stream(fakeSender{}, receiver)
+	checkError(t, err, "'fake1' is not a participant")
+
+	receiver = newReceiver(fakeRecv{from: fakeAddress(0), msg: fakeMessage{}})
+	err = h.Stream(fakeSender{}, receiver)
+	checkError(t, err, "expected Start message, decrypt request or Deal as "+
+		"first message, got: pedersen.fakeMessage")
+}
+
+func TestHandler_Start(t *testing.T) {
+	privKey := suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(privKey, nil)
+
+	h := NewHandler(privKey, fakeAddress(0), NewInMemoryStorage())
+
+	start := types.NewStart(
+		0,
+		[]mino.Address{fakeAddress(0)},
+		[]kyber.Point{},
+	)
+	err := h.start(start, []types.Deal{}, []*pedersen.Response{}, nil, nil, nil)
+	checkError(t, err, "there should be as many players as pubKey: 1 := 0")
+
+	start = types.NewStart(
+		2,
+		[]mino.Address{fakeAddress(0), fakeAddress(1)},
+		[]kyber.Point{pubKey, suite.Point()},
+	)
+	err = h.start(start, []types.Deal{}, []*pedersen.Response{}, nil,
+		fakeSender{}, newBadReceiver())
+	checkError(t, err, "failed to receive after sending deals: fake error")
+
+	receiver := newReceiver(
+		fakeRecv{from: fakeAddress(1), msg: types.Deal{}},
+		fakeRecv{from: fakeAddress(1), msg: nil},
+	)
+	err = h.start(start, []types.Deal{}, []*pedersen.Response{}, nil,
+		fakeSender{}, receiver)
+	checkError(t, err, "failed to handle deal from 'fake1': failed to process deal")
+
+	err = h.start(start, []types.Deal{}, []*pedersen.Response{}, nil,
+		fakeSender{}, newReceiver())
+	checkError(t, err, "unexpected message: <nil>")
+
+	// We check when there is already something in the slice of Deals.
+	err = h.start(start, []types.Deal{{}}, []*pedersen.Response{}, nil,
+		fakeSender{err: fakeErr}, newReceiver())
+	checkError(t, err, "failed to certify: expected a response, got: <nil>")
+}
+
+func TestHandler_Start_AlreadyDone(t *testing.T) {
+	privKey := suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(privKey, nil)
+
+	storage := NewInMemoryStorage()
+
+	st := makeState(fakeAddress(0), pubKey)
+	err := storage.StoreState(st)
+	if err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	h := NewHandler(privKey, fakeAddress(0), storage)
+
+	start := types.NewStart(
+		2,
+		[]mino.Address{fakeAddress(0), fakeAddress(1)},
+		[]kyber.Point{pubKey, suite.Point()},
+	)
+
+	// Anyone can send a start message, which must not replace the share of a
+	// node that has already done the setup.
+	err = h.start(start, []types.Deal{}, []*pedersen.Response{}, fakeAddress(1),
+		fakeSender{}, newReceiver())
+	checkError(t, err, "fake0 already holds a share of the DKG")
+
+	res, err := storage.LoadState()
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	if res.Share.I != st.Share.I || !res.Share.V.Equal(st.Share.V) {
+		t.Fatal("share has been replaced")
+	}
+
+	if h.dkg != nil {
+		t.Fatal("DKG should not have been created")
+	}
+
+	err = NewHandler(privKey, fakeAddress(0), badStorage{}).start(start,
+		[]types.Deal{}, []*pedersen.Response{}, nil, fakeSender{}, newReceiver())
+	checkError(t, err, "failed to load state: fake error")
+}
+
+func TestHandler_Certify(t *testing.T) {
+	privKey := suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(privKey, nil)
+
+	dkg, err := pedersen.NewDistKeyGenerator(suite, privKey,
+		[]kyber.Point{pubKey, suite.Point()}, 2)
+	if err != nil {
+		t.Fatalf("failed to create DKG: %v", err)
+	}
+
+	storage := NewInMemoryStorage()
+
+	h := NewHandler(privKey, fakeAddress(0), storage)
+	h.dkg = dkg
+
+	start := types.NewStart(
+		2,
+		[]mino.Address{fakeAddress(0), fakeAddress(1)},
+		[]kyber.Point{pubKey, suite.Point()},
+	)
+
+	responses := []*pedersen.Response{{Response: &vss.Response{}}}
+
+	err = h.certify(start, responses, fakeSender{}, newBadReceiver(), nil)
+	checkError(t, err, "failed to receive after sending deals: fake error")
+
+	h.dkg = getCertified(t)
+
+	// The state is persisted before the acknowledgement is sent.
+	err = h.certify(start, responses, fakeSender{err: fakeErr}, newReceiver(), nil)
+	checkError(t, err, "got an error while sending pub key: fake error")
+
+	st, err := storage.LoadState()
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	if st == nil || st.Threshold != 2 || len(st.Participants) != 2 {
+		t.Fatalf("unexpected state: %v", st)
+	}
+
+	if !st.Commits[0].Equal(h.startRes.GetDistKey()) {
+		t.Fatal("handler should hold the distributed key")
+	}
+
+	h = NewHandler(privKey, fakeAddress(0), badStorage{})
+	h.dkg = getCertified(t)
+
+	err = h.certify(start, responses, fakeSender{}, newReceiver(), nil)
+	checkError(t, err, "failed to store state: fake error")
+
+	if h.startRes.GetDistKey() != nil {
+		t.Fatal("handler should not hold a key that is not stored")
+	}
+}
+
+func TestHandler_Reshare(t *testing.T) {
+	privKey := suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(privKey, nil)
+	otherKey := suite.Point().Pick(suite.RandomStream())
+
+	storage := NewInMemoryStorage()
+
+	st := makeState(fakeAddress(0), pubKey)
+	err := storage.StoreState(st)
+	if err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	h := NewHandler(privKey, fakeAddress(0), storage)
+
+	previous := types.NewStart(st.Threshold, st.Participants, st.PublicKeys)
+	next := types.NewStart(2, []mino.Address{fakeAddress(1), fakeAddress(2)},
+		[]kyber.Point{otherKey, suite.Point().Pick(suite.RandomStream())})
+
+	reshare := func(previous types.Start, commits []kyber.Point,
+		from mino.Address) error {
+
+		msg := types.NewStartResharing(next, previous, commits)
+
+		return h.reshare(msg, []types.Deal{}, []*pedersen.Response{}, from,
+			fakeSender{}, newBadReceiver())
+	}
+
+	err = reshare(previous, st.Commits, fakeAddress(1))
+	checkError(t, err, "invalid resharing: 'fake1' is not a participant")
+
+	err = reshare(types.NewStart(2, st.Participants, st.PublicKeys), st.Commits,
+		fakeAddress(0))
+	checkError(t, err, "invalid resharing: threshold mismatch: 2 != 1")
+
+	err = reshare(types.NewStart(1, []mino.Address{fakeAddress(1)}, st.PublicKeys),
+		st.Commits, fakeAddress(0))
+	checkError(t, err, "invalid resharing: participant 0 mismatch: fake1 != fake0")
+
+	err = reshare(types.NewStart(1, []mino.Address{fakeAddress(0), fakeAddress(1)},
+		[]kyber.Point{pubKey, otherKey}), st.Commits, fakeAddress(0))
+	checkError(t, err, "invalid resharing: participants mismatch: 2 != 1")
+
+	err = reshare(types.NewStart(1, st.Participants, []kyber.Point{otherKey}),
+		st.Commits, fakeAddress(0))
+	checkError(t, err, "invalid resharing: public keys mismatch")
+
+	err = reshare(previous, []kyber.Point{otherKey}, fakeAddress(0))
+	checkError(t, err, "invalid resharing: commits mismatch")
+
+	// None of the rejected messages can change the share of the node.
+	res, err := storage.LoadState()
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	if res.Share.I != st.Share.I || !res.Share.V.Equal(st.Share.V) {
+		t.Fatal("share has been replaced")
+	}
+
+	if h.dkg != nil {
+		t.Fatal("DKG should not have been created")
+	}
+
+	// The message is valid, so that the node deals its share and then waits
+	// for the responses.
+	err = reshare(previous, st.Commits, fakeAddress(0))
+	checkError(t, err, "failed to receive responses: fake error")
+
+	// A node without a share only joins the committee of a previous member.
+	err = storage.DeleteState()
+	if err != nil {
+		t.Fatalf("failed to delete state: %v", err)
+	}
+
+	err = reshare(previous, st.Commits, fakeAddress(0))
+	checkError(t, err, "fake0 has no share to reshare")
+
+	h = NewHandler(suite.Scalar().Pick(suite.RandomStream()), fakeAddress(1),
+		NewInMemoryStorage())
+
+	err = reshare(previous, st.Commits, fakeAddress(3))
+	checkError(t, err, "'fake3' is not a previous participant")
+
+	h = NewHandler(privKey, fakeAddress(0), badStorage{})
+
+	err = reshare(previous, st.Commits, fakeAddress(0))
+	checkError(t, err, "failed to load state: fake error")
+}
+
+func TestHandler_HandleDeal(t *testing.T) {
+	privKey1 := suite.Scalar().Pick(suite.RandomStream())
+	pubKey1 := suite.Point().Mul(privKey1, nil)
+	privKey2 := suite.Scalar().Pick(suite.RandomStream())
+	pubKey2 := suite.Point().Mul(privKey2, nil)
+
+	dkg1, err := pedersen.NewDistKeyGenerator(suite, privKey1,
+		[]kyber.Point{pubKey1, pubKey2}, 2)
+	if err != nil {
+		t.Fatalf("failed to create DKG: %v", err)
+	}
+
+	dkg2, err := pedersen.NewDistKeyGenerator(suite, privKey2,
+		[]kyber.Point{pubKey1, pubKey2}, 2)
+	if err != nil {
+		t.Fatalf("failed to create DKG: %v", err)
+	}
+
+	deals, err := dkg2.Deals()
+	if err != nil {
+		t.Fatalf("failed to compute deals: %v", err)
+	}
+
+	if len(deals) != 1 {
+		t.Fatalf("expected 1 deal, got %d", len(deals))
+	}
+
+	var deal *pedersen.Deal
+	for _, d := range deals {
+		deal = d
+	}
+
+	dealMsg := types.NewDeal(
+		deal.Index,
+		deal.Signature,
+		types.NewEncryptedDeal(
+			deal.Deal.DHKey,
+			deal.Deal.Signature,
+			deal.Deal.Nonce,
+			deal.Deal.Cipher,
+		),
+	)
+
+	h := NewHandler(privKey1, fakeAddress(1), NewInMemoryStorage())
+	h.dkg = dkg1
+
+	err = h.handleDeal(dealMsg, nil, []mino.Address{fakeAddress(0)},
+		fakeSender{err: fakeErr})
+	checkError(t, err, "failed to send response to 'fake0': fake error")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+var fakeErr = xerrors.New("fake error")
+
+func checkError(t *testing.T, err error, substr string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", substr)
+	}
+
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// getCertified returns a DKG generator of two nodes that is certified.
+func getCertified(t *testing.T) *pedersen.DistKeyGenerator {
+	t.Helper()
+
+	privKey1 := suite.Scalar().Pick(suite.RandomStream())
+	pubKey1 := suite.Point().Mul(privKey1, nil)
+
+	privKey2 := suite.Scalar().Pick(suite.RandomStream())
+	pubKey2 := suite.Point().Mul(privKey2, nil)
+
+	dkg1, err := pedersen.NewDistKeyGenerator(suite, privKey1,
+		[]kyber.Point{pubKey1, pubKey2}, 2)
+	if err != nil {
+		t.Fatalf("failed to create DKG: %v", err)
+	}
+
+	dkg2, err := pedersen.NewDistKeyGenerator(suite, privKey2,
+		[]kyber.Point{pubKey1, pubKey2}, 2)
+	if err != nil {
+		t.Fatalf("failed to create DKG: %v", err)
+	}
+
+	deals1, err := dkg1.Deals()
+	if err != nil || len(deals1) != 1 {
+		t.Fatalf("expected 1 deal, got %d: %v", len(deals1), err)
+	}
+
+	deals2, err := dkg2.Deals()
+	if err != nil || len(deals2) != 1 {
+		t.Fatalf("expected 1 deal, got %d: %v", len(deals2), err)
+	}
+
+	var resp1 *pedersen.Response
+	var resp2 *pedersen.Response
+
+	for _, deal := range deals2 {
+		resp1, err = dkg1.ProcessDeal(deal)
+		if err != nil {
+			t.Fatalf("failed to process deal: %v", err)
+		}
+	}
+
+	for _, deal := range deals1 {
+		resp2, err = dkg2.ProcessDeal(deal)
+		if err != nil {
+			t.Fatalf("failed to process deal: %v", err)
+		}
+	}
+
+	_, err = dkg1.ProcessResponse(resp2)
+	if err != nil {
+		t.Fatalf("failed to process response: %v", err)
+	}
+
+	_, err = dkg2.ProcessResponse(resp1)
+	if err != nil {
+		t.Fatalf("failed to process response: %v", err)
+	}
+
+	if !dkg1.Certified() || !dkg2.Certified() {
+		t.Fatal("DKG should be certified")
+	}
+
+	return dkg1
+}
+
+// makeState returns the state of a node that holds a share of a DKG with a
+// single participant.
+func makeState(addr mino.Address, pubkey kyber.Point) State {
+	secret := suite.Scalar().Pick(suite.RandomStream())
+
+	return State{
+		Threshold:    1,
+		Participants: []mino.Address{addr},
+		PublicKeys:   []kyber.Point{pubkey},
+		Share:        &share.PriShare{I: 0, V: secret},
+		Commits:      []kyber.Point{suite.Point().Mul(secret, nil)},
+	}
+}
+
+// fakeAddress is the address of a fake node.
+//
+// - implements mino.Address
+type fakeAddress int
+
+func (a fakeAddress) Equal(other mino.Address) bool {
+	addr, ok := other.(fakeAddress)
+	return ok && addr == a
+}
+
+func (a fakeAddress) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a fakeAddress) String() string {
+	return fmt.Sprintf("fake%d", int(a))
+}
+
+// fakeMessage is a message that the handler doesn't expect.
+//
+// - implements serde.Message
+type fakeMessage struct{}
+
+func (m fakeMessage) Serialize(serde.Context) ([]byte, error) {
+	return nil, nil
+}
+
+// fakeSender is a sender that returns the error for each message, if any.
+//
+// - implements mino.Sender
+type fakeSender struct {
+	err error
+}
+
+func (s fakeSender) Send(serde.Message, ...mino.Address) <-chan error {
+	errs := make(chan error, 1)
+	if s.err != nil {
+		errs <- s.err
+	}
+	close(errs)
+
+	return errs
+}
+
+// fakeRecv is a message received from an address.
+type fakeRecv struct {
+	from mino.Address
+	msg  serde.Message
+}
+
+// fakeReceiver is a receiver that returns the messages in order, and then
+// either the error or empty messages.
+//
+// - implements mino.Receiver
+type fakeReceiver struct {
+	msgs []fakeRecv
+	err  error
+}
+
+func newReceiver(msgs ...fakeRecv) *fakeReceiver {
+	return &fakeReceiver{msgs: msgs}
+}
+
+func newBadReceiver() *fakeReceiver {
+	return &fakeReceiver{err: fakeErr}
+}
+
+func (r *fakeReceiver) Recv(context.Context) (mino.Address, serde.Message, error) {
+	if len(r.msgs) == 0 {
+		return nil, nil, r.err
+	}
+
+	next := r.msgs[0]
+	r.msgs = r.msgs[1:]
+
+	return next.from, next.msg, nil
+}
+
+// fakeRPC is an RPC that opens a stream with the sender and the receiver, or
+// returns the error.
+//
+// - implements mino.RPC
+type fakeRPC struct {
+	sender   mino.Sender
+	receiver mino.Receiver
+	err      error
+}
+
+func (rpc fakeRPC) Call(context.Context, serde.Message,
+	mino.Players) (<-chan mino.Response, error) {
+
+	return nil, xerrors.New("not implemented")
+}
+
+func (rpc fakeRPC) Stream(context.Context, mino.Players) (mino.Sender,
+	mino.Receiver, error) {
+
+	if rpc.err != nil {
+		return nil, nil, rpc.err
+	}
+
+	return rpc.sender, rpc.receiver, nil
+}
+
+// badStorage is a storage that always returns an error.
+//
+// - implements pedersen.Storage
+type badStorage struct{}
+
+func (s badStorage) LoadKey() (kyber.Scalar, error) {
+	return nil, fakeErr
+}
+
+func (s badStorage) StoreKey(kyber.Scalar) error {
+	return fakeErr
+}
+
+func (s badStorage) LoadState() (*State, error) {
+	return nil, fakeErr
+}
+
+func (s badStorage) StoreState(State) error {
+	return fakeErr
+}
+
+func (s badStorage) DeleteState() error {
+	return fakeErr
+}
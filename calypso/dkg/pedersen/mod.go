@@ -0,0 +1,487 @@
+// Package pedersen implements the Pedersen DKG used by Calypso. It is derived
+// from the implementation of Dela, with the difference that the private key of
+// the node and the result of the setup are kept in a storage, so that a node
+// can resume after a restart.
+package pedersen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen/types"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/dkg"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+// suite is the Kyber suite for Pedersen.
+var suite = suites.MustFind("Ed25519")
+
+const (
+	setupTimeout   = time.Second * 300
+	decryptTimeout = time.Second * 100
+)
+
+// Phase is the phase of the DKG on a node.
+type Phase string
+
+const (
+	// PhaseCreated is the phase before the node listens for the DKG.
+	PhaseCreated Phase = "created"
+
+	// PhaseListening is the phase when the node listens but doesn't hold a
+	// share, either because the setup has not been done yet, or because the
+	// node has been removed from the committee.
+	PhaseListening Phase = "listening"
+
+	// PhaseReady is the phase when the node holds a share of the distributed
+	// key.
+	PhaseReady Phase = "ready"
+)
+
+// Pedersen allows one to initialize a new DKG protocol.
+//
+// - implements dkg.DKG
+type Pedersen struct {
+	sync.Mutex
+	privKey kyber.Scalar
+	mino    mino.Mino
+	factory serde.Factory
+	storage Storage
+	actor   *Actor
+}
+
+// NewPedersen returns a new DKG Pedersen factory. The private key of the node
+// is read from the storage, or created and stored if it doesn't exist yet.
+func NewPedersen(m mino.Mino, storage Storage) (*Pedersen, kyber.Point, error) {
+	factory := types.NewMessageFactory(m.GetAddressFactory())
+
+	privkey, err := storage.LoadKey()
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to load key: %v", err)
+	}
+
+	if privkey == nil {
+		privkey = suite.Scalar().Pick(suite.RandomStream())
+
+		err = storage.StoreKey(privkey)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to store key: %v", err)
+		}
+	}
+
+	pubkey := suite.Point().Mul(privkey, nil)
+
+	p := &Pedersen{
+		privKey: privkey,
+		mino:    m,
+		factory: factory,
+		storage: storage,
+	}
+
+	return p, pubkey, nil
+}
+
+// Listen implements dkg.DKG. It must be called on each node that participates
+// in the DKG. Creates the RPC the first time, and then returns the same actor.
+// The state of a previous setup is restored from the storage.
+func (s *Pedersen) Listen() (dkg.Actor, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.actor != nil {
+		return s.actor, nil
+	}
+
+	h := NewHandler(s.privKey, s.mino.GetAddress(), s.storage)
+
+	err := h.restore()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to restore state: %v", err)
+	}
+
+	rpc, err := s.mino.CreateRPC("dkg", h, s.factory)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create rpc: %v", err)
+	}
+
+	s.actor = &Actor{
+		rpc:      rpc,
+		factory:  s.factory,
+		startRes: h.startRes,
+		storage:  s.storage,
+	}
+
+	return s.actor, nil
+}
+
+// Status returns the phase of the DKG on the node, and the state of the setup
+// when the node holds a share.
+func (s *Pedersen) Status() (Phase, *State, error) {
+	s.Lock()
+	actor := s.actor
+	s.Unlock()
+
+	if actor == nil {
+		return PhaseCreated, nil, nil
+	}
+
+	if !actor.startRes.Done() {
+		return PhaseListening, nil, nil
+	}
+
+	st, err := s.storage.LoadState()
+	if err != nil {
+		return "", nil, xerrors.Errorf("failed to load state: %v", err)
+	}
+
+	if st == nil {
+		return PhaseListening, nil, nil
+	}
+
+	return PhaseReady, st, nil
+}
+
+// Actor allows one to perform DKG operations like encrypt/decrypt a message
+//
+// - implements dkg.Actor
+// - implements calypso.Reencrypter
+type Actor struct {
+	rpc      mino.RPC
+	factory  serde.Factory
+	startRes *state
+	storage  Storage
+}
+
+// Setup implement dkg.Actor. It initializes the DKG.
+func (a *Actor) Setup(co crypto.CollectiveAuthority, threshold int) (kyber.Point, error) {
+
+	if a.startRes.Done() {
+		return nil, xerrors.Errorf("startRes is already done, only one setup call is allowed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	sender, receiver, err := a.rpc.Stream(ctx, co)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to stream: %v", err)
+	}
+
+	addrs, pubkeys, err := readAuthority(co)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read authority: %v", err)
+	}
+
+	message := types.NewStart(threshold, addrs, pubkeys)
+
+	errs := sender.Send(message, addrs...)
+	err = <-errs
+	if err != nil {
+		return nil, xerrors.Errorf("failed to send start: %v", err)
+	}
+
+	dkgPubKeys := make([]kyber.Point, len(addrs))
+
+	for i := 0; i < len(addrs); i++ {
+
+		addr, msg, err := receiver.Recv(context.Background())
+		if err != nil {
+			return nil, xerrors.Errorf("got an error from '%s' while "+
+				"receiving: %v", addr, err)
+		}
+
+		doneMsg, ok := msg.(types.StartDone)
+		if !ok {
+			return nil, xerrors.Errorf("expected to receive a Done message, but "+
+				"go the following: %T", msg)
+		}
+
+		dkgPubKeys[i] = doneMsg.GetPublicKey()
+
+		// this is a simple check that every node sends back the same DKG pub
+		// key.
+		// TODO: handle the situation where a pub key is not the same
+		if i != 0 && !dkgPubKeys[i-1].Equal(doneMsg.GetPublicKey()) {
+			return nil, xerrors.Errorf("the public keys does not match: %v", dkgPubKeys)
+		}
+	}
+
+	return dkgPubKeys[0], nil
+}
+
+// GetPublicKey implements dkg.Actor
+func (a *Actor) GetPublicKey() (kyber.Point, error) {
+	if !a.startRes.Done() {
+		return nil, xerrors.Errorf("DKG has not been initialized")
+	}
+
+	return a.startRes.GetDistKey(), nil
+}
+
+// Encrypt implements dkg.Actor. It uses the DKG public key to encrypt a
+// message.
+func (a *Actor) Encrypt(message []byte) (K, C kyber.Point, remainder []byte,
+	err error) {
+
+	if !a.startRes.Done() {
+		return nil, nil, nil, xerrors.Errorf("you must first initialize DKG. " +
+			"Did you call setup() first?")
+	}
+
+	// Embed the message (or as much of it as will fit) into a curve point.
+	M := suite.Point().Embed(message, random.New())
+	max := suite.Point().EmbedLen()
+	if max > len(message) {
+		max = len(message)
+	}
+	remainder = message[max:]
+	// ElGamal-encrypt the point to produce ciphertext (K,C).
+	k := suite.Scalar().Pick(random.New())             // ephemeral private key
+	K = suite.Point().Mul(k, nil)                      // ephemeral DH public key
+	S := suite.Point().Mul(k, a.startRes.GetDistKey()) // ephemeral DH shared secret
+	C = S.Add(S, M)                                    // message blinded with secret
+
+	return K, C, remainder, nil
+}
+
+// Decrypt implements dkg.Actor. It gets the private shares of the nodes and
+// decrypt the  message.
+// TODO: perform a re-encryption instead of gathering the private shares, which
+// should never happen.
+func (a *Actor) Decrypt(K, C kyber.Point) ([]byte, error) {
+
+	if !a.startRes.Done() {
+		return nil, xerrors.Errorf("you must first initialize DKG. " +
+			"Did you call setup() first?")
+	}
+
+	players := mino.NewAddresses(a.startRes.GetParticipants()...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), decryptTimeout)
+	defer cancel()
+
+	sender, receiver, err := a.rpc.Stream(ctx, players)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create stream: %v", err)
+	}
+
+	players = mino.NewAddresses(a.startRes.GetParticipants()...)
+	iterator := players.AddressIterator()
+
+	addrs := make([]mino.Address, 0, players.Len())
+	for iterator.HasNext() {
+		addrs = append(addrs, iterator.GetNext())
+	}
+
+	message := types.NewDecryptRequest(K, C)
+
+	err = <-sender.Send(message, addrs...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to send decrypt request: %v", err)
+	}
+
+	pubShares := make([]*share.PubShare, len(addrs))
+
+	for i := 0; i < len(addrs); i++ {
+		_, message, err := receiver.Recv(ctx)
+		if err != nil {
+			return []byte{}, xerrors.Errorf("stream stopped unexpectedly: %v", err)
+		}
+
+		decryptReply, ok := message.(types.DecryptReply)
+		if !ok {
+			return []byte{}, xerrors.Errorf("got unexpected reply, expected "+
+				"%T but got: %T", decryptReply, message)
+		}
+
+		pubShares[i] = &share.PubShare{
+			I: int(decryptReply.I),
+			V: decryptReply.V,
+		}
+	}
+
+	res, err := share.RecoverCommit(suite, pubShares, len(addrs), len(addrs))
+	if err != nil {
+		return []byte{}, xerrors.Errorf("failed to recover commit: %v", err)
+	}
+
+	decryptedMessage, err := res.Data()
+	if err != nil {
+		return []byte{}, xerrors.Errorf("failed to get embeded data: %v", err)
+	}
+
+	return decryptedMessage, nil
+}
+
+// Reencrypt implements calypso.Reencrypter. Each participant returns the
+// re-encryption share of K under the public key of the reader, and at least a
+// threshold of them are combined into XhatEnc = x*K + xc*X, so that the secret
+// is never revealed to the node.
+func (a *Actor) Reencrypt(K, pubk kyber.Point) (kyber.Point, error) {
+	if !a.startRes.Done() {
+		return nil, xerrors.Errorf("you must first initialize DKG. " +
+			"Did you call setup() first?")
+	}
+
+	st, err := a.storage.LoadState()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load state: %v", err)
+	}
+
+	if st == nil {
+		return nil, xerrors.Errorf("this node is not part of the committee")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), decryptTimeout)
+	defer cancel()
+
+	sender, receiver, err := a.rpc.Stream(ctx, mino.NewAddresses(st.Participants...))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create stream: %v", err)
+	}
+
+	message := types.NewReencryptRequest(K, pubk)
+
+	err = <-sender.Send(message, st.Participants...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to send reencrypt request: %v", err)
+	}
+
+	pubShares := make([]*share.PubShare, len(st.Participants))
+
+	for i := range st.Participants {
+		_, message, err := receiver.Recv(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("stream stopped unexpectedly: %v", err)
+		}
+
+		reply, ok := message.(types.ReencryptReply)
+		if !ok {
+			return nil, xerrors.Errorf("got unexpected reply, expected "+
+				"%T but got: %T", reply, message)
+		}
+
+		pubShares[i] = &share.PubShare{
+			I: int(reply.GetI()),
+			V: reply.GetV(),
+		}
+	}
+
+	xhatEnc, err := calycrypto.CombineReencrypted(pubShares, st.Threshold,
+		len(st.Participants))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to combine: %v", err)
+	}
+
+	return xhatEnc, nil
+}
+
+// Reshare implements dkg.Actor. The new committee can't be given through this
+// interface, so ReshareTo must be used instead.
+func (a *Actor) Reshare() error {
+	return xerrors.New("not supported, use ReshareTo")
+}
+
+// ReshareTo moves the distributed key to a new committee with a new threshold.
+// The nodes of the current committee deal their share to the nodes of the new
+// one, so that the collective public key doesn't change and the messages
+// encrypted before can still be decrypted. All the nodes of the current
+// committee must be online. The nodes that are not part of the new committee
+// delete their share.
+func (a *Actor) ReshareTo(co crypto.CollectiveAuthority, threshold int) (kyber.Point, error) {
+	if !a.startRes.Done() {
+		return nil, xerrors.Errorf("you must first initialize DKG. " +
+			"Did you call setup() first?")
+	}
+
+	st, err := a.storage.LoadState()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load state: %v", err)
+	}
+
+	if st == nil {
+		return nil, xerrors.Errorf("this node is not part of the committee")
+	}
+
+	addrs, pubkeys, err := readAuthority(co)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read authority: %v", err)
+	}
+
+	previous := types.NewStart(st.Threshold, st.Participants, st.PublicKeys)
+	next := types.NewStart(threshold, addrs, pubkeys)
+
+	players := union(st.Participants, addrs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	sender, receiver, err := a.rpc.Stream(ctx, mino.NewAddresses(players...))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to stream: %v", err)
+	}
+
+	message := types.NewStartResharing(next, previous, st.Commits)
+
+	err = <-sender.Send(message, players...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to send start resharing: %v", err)
+	}
+
+	pubkey := st.Commits[0]
+
+	for i := 0; i < len(players); i++ {
+		addr, msg, err := receiver.Recv(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("got an error from '%s' while "+
+				"receiving: %v", addr, err)
+		}
+
+		doneMsg, ok := msg.(types.StartDone)
+		if !ok {
+			return nil, xerrors.Errorf("expected to receive a Done message, but "+
+				"go the following: %T", msg)
+		}
+
+		if !pubkey.Equal(doneMsg.GetPublicKey()) {
+			return nil, xerrors.Errorf("public key of '%s' has changed: %v != %v",
+				addr, doneMsg.GetPublicKey(), pubkey)
+		}
+	}
+
+	return pubkey, nil
+}
+
+// readAuthority returns the addresses and the DKG public keys of the
+// collective authority.
+func readAuthority(co crypto.CollectiveAuthority) ([]mino.Address, []kyber.Point, error) {
+	addrs := make([]mino.Address, 0, co.Len())
+	pubkeys := make([]kyber.Point, 0, co.Len())
+
+	addrIter := co.AddressIterator()
+	pubkeyIter := co.PublicKeyIterator()
+
+	for addrIter.HasNext() && pubkeyIter.HasNext() {
+		addrs = append(addrs, addrIter.GetNext())
+
+		pubkey := pubkeyIter.GetNext()
+		edKey, ok := pubkey.(ed25519.PublicKey)
+		if !ok {
+			return nil, nil, xerrors.Errorf("expected ed25519.PublicKey, got '%T'", pubkey)
+		}
+
+		pubkeys = append(pubkeys, edKey.GetPoint())
+	}
+
+	return addrs, pubkeys, nil
+}
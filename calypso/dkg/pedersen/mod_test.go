@@ -0,0 +1,481 @@
+package pedersen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso/dkg/pedersen/types"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/dkg"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minoch"
+
+	// The messages of the DKG are sent in JSON through minoch.
+	_ "go.dedis.ch/dela-apps/calypso/dkg/pedersen/json"
+)
+
+func TestPedersen_Listen(t *testing.T) {
+	manager := minoch.NewManager()
+	storage := NewInMemoryStorage()
+
+	p, pubkey, err := NewPedersen(minoch.MustCreate(manager, "node"), storage)
+	if err != nil {
+		t.Fatalf("failed to create pedersen: %v", err)
+	}
+
+	phase, _, err := p.Status()
+	if err != nil || phase != PhaseCreated {
+		t.Fatalf("expected phase %s, got %s: %v", PhaseCreated, phase, err)
+	}
+
+	actor, err := p.Listen()
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	other, err := p.Listen()
+	if err != nil || other != actor {
+		t.Fatalf("expected the same actor: %v", err)
+	}
+
+	phase, _, err = p.Status()
+	if err != nil || phase != PhaseListening {
+		t.Fatalf("expected phase %s, got %s: %v", PhaseListening, phase, err)
+	}
+
+	// The private key is kept in the storage.
+	_, samePubkey, err := NewPedersen(minoch.MustCreate(manager, "other"), storage)
+	if err != nil {
+		t.Fatalf("failed to create pedersen: %v", err)
+	}
+
+	if !samePubkey.Equal(pubkey) {
+		t.Fatal("expected the same public key")
+	}
+
+	_, _, err = NewPedersen(minoch.MustCreate(manager, "bad"), badStorage{})
+	checkError(t, err, "failed to load key: fake error")
+}
+
+func TestPedersen_Setup(t *testing.T) {
+	actor := Actor{
+		rpc:      fakeRPC{err: fakeErr},
+		startRes: &state{},
+	}
+
+	badAuthority := authority.New(
+		[]mino.Address{fakeAddress(0)},
+		[]crypto.PublicKey{bls.NewSigner().GetPublicKey()},
+	)
+
+	_, err := actor.Setup(badAuthority, 0)
+	checkError(t, err, "failed to stream: fake error")
+
+	actor.rpc = fakeRPC{sender: fakeSender{err: fakeErr}, receiver: newReceiver()}
+
+	_, err = actor.Setup(badAuthority, 0)
+	checkError(t, err, "expected ed25519.PublicKey, got 'bls.PublicKey'")
+
+	actor.rpc = fakeRPC{sender: fakeSender{err: fakeErr}, receiver: newReceiver()}
+
+	co := authority.New(
+		[]mino.Address{fakeAddress(0), fakeAddress(1)},
+		[]crypto.PublicKey{
+			ed25519.NewSigner().GetPublicKey(),
+			ed25519.NewSigner().GetPublicKey(),
+		},
+	)
+
+	_, err = actor.Setup(co, 1)
+	checkError(t, err, "failed to send start: fake error")
+
+	actor.rpc = fakeRPC{sender: fakeSender{}, receiver: newBadReceiver()}
+
+	_, err = actor.Setup(co, 1)
+	checkError(t, err, "while receiving: fake error")
+
+	actor.rpc = fakeRPC{
+		sender:   fakeSender{},
+		receiver: newReceiver(fakeRecv{from: fakeAddress(0), msg: nil}),
+	}
+
+	_, err = actor.Setup(co, 1)
+	checkError(t, err, "expected to receive a Done message, but go the following: <nil>")
+
+	actor.rpc = fakeRPC{
+		sender: fakeSender{},
+		receiver: newReceiver(
+			fakeRecv{from: fakeAddress(0), msg: types.NewStartDone(suite.Point())},
+			fakeRecv{from: fakeAddress(1), msg: types.NewStartDone(
+				suite.Point().Pick(suite.RandomStream()))},
+		),
+	}
+
+	_, err = actor.Setup(co, 1)
+	checkError(t, err, "the public keys does not match:")
+}
+
+func TestPedersen_GetPublicKey(t *testing.T) {
+	actor := Actor{
+		startRes: &state{},
+	}
+
+	_, err := actor.GetPublicKey()
+	checkError(t, err, "DKG has not been initialized")
+
+	actor.startRes = &state{
+		participants: []mino.Address{fakeAddress(0)},
+		distrKey:     suite.Point(),
+	}
+
+	_, err = actor.GetPublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+}
+
+func TestPedersen_Decrypt(t *testing.T) {
+	actor := Actor{
+		rpc: fakeRPC{err: fakeErr},
+		startRes: &state{
+			participants: []mino.Address{fakeAddress(0)},
+			distrKey:     suite.Point(),
+		},
+	}
+
+	_, err := actor.Decrypt(suite.Point(), suite.Point())
+	checkError(t, err, "failed to create stream: fake error")
+
+	actor.rpc = fakeRPC{sender: fakeSender{err: fakeErr}, receiver: newBadReceiver()}
+
+	_, err = actor.Decrypt(suite.Point(), suite.Point())
+	checkError(t, err, "failed to send decrypt request: fake error")
+
+	actor.rpc = fakeRPC{
+		sender:   fakeSender{},
+		receiver: newReceiver(fakeRecv{from: fakeAddress(0), msg: nil}),
+	}
+
+	_, err = actor.Decrypt(suite.Point(), suite.Point())
+	checkError(t, err, "got unexpected reply, expected types.DecryptReply but got: <nil>")
+
+	actor.rpc = fakeRPC{
+		sender: fakeSender{},
+		receiver: newReceiver(fakeRecv{
+			from: fakeAddress(0),
+			msg:  types.DecryptReply{I: -1, V: suite.Point()},
+		}),
+	}
+
+	_, err = actor.Decrypt(suite.Point(), suite.Point())
+	checkError(t, err, "failed to recover commit: share: not enough "+
+		"good public shares to reconstruct secret commitment")
+
+	actor.rpc = fakeRPC{
+		sender: fakeSender{},
+		receiver: newReceiver(fakeRecv{
+			from: fakeAddress(0),
+			msg:  types.DecryptReply{I: 1, V: suite.Point()},
+		}),
+	}
+
+	_, err = actor.Decrypt(suite.Point(), suite.Point())
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+}
+
+func TestPedersen_Reshare(t *testing.T) {
+	actor := Actor{}
+
+	err := actor.Reshare()
+	checkError(t, err, "not supported, use ReshareTo")
+}
+
+func TestPedersen_Scenario(t *testing.T) {
+	n := 5
+
+	storages := make([]Storage, n)
+	for i := range storages {
+		storages[i] = NewInMemoryStorage()
+	}
+
+	dkgs, co := makeDKGs(t, minoch.NewManager(), storages)
+
+	message := []byte("Hello world")
+	actors := make([]dkg.Actor, n)
+
+	for i := 0; i < n; i++ {
+		actor, err := dkgs[i].Listen()
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+
+		actors[i] = actor
+	}
+
+	// trying to call a decrypt/encrypt before a setup
+	_, _, _, err := actors[0].Encrypt(message)
+	checkError(t, err, "you must first initialize DKG. Did you call setup() first?")
+
+	_, err = actors[0].Decrypt(nil, nil)
+	checkError(t, err, "you must first initialize DKG. Did you call setup() first?")
+
+	_, err = actors[0].Setup(co, n)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+
+	_, err = actors[0].Setup(co, n)
+	checkError(t, err, "startRes is already done, only one setup call is allowed")
+
+	// every node should be able to encrypt/decrypt
+	for i := 0; i < n; i++ {
+		K, C, remainder, err := actors[i].Encrypt(message)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+
+		if len(remainder) != 0 {
+			t.Fatalf("unexpected remainder: %v", remainder)
+		}
+
+		decrypted, err := actors[i].Decrypt(K, C)
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+
+		if !bytes.Equal(decrypted, message) {
+			t.Fatalf("wrong message: %q", decrypted)
+		}
+
+		phase, st, err := dkgs[i].Status()
+		if err != nil || phase != PhaseReady {
+			t.Fatalf("expected phase %s, got %s: %v", PhaseReady, phase, err)
+		}
+
+		if st.Threshold != n || len(st.Participants) != n {
+			t.Fatalf("unexpected state: %v", st)
+		}
+	}
+}
+
+func TestPedersen_ReshareTo(t *testing.T) {
+	n := 4
+
+	storages := make([]Storage, n)
+	for i := range storages {
+		storages[i] = NewInMemoryStorage()
+	}
+
+	dkgs, co := makeDKGs(t, minoch.NewManager(), storages)
+
+	actors := make([]*Actor, n)
+
+	for i, p := range dkgs {
+		actor, err := p.Listen()
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+
+		actors[i] = actor.(*Actor)
+	}
+
+	previous := co.Take(mino.RangeFilter(0, 3)).(crypto.CollectiveAuthority)
+	next := co.Take(mino.RangeFilter(1, 4)).(crypto.CollectiveAuthority)
+
+	pubkey, err := actors[0].Setup(previous, 2)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+
+	message := []byte("Hello world")
+
+	K, C, _, err := actors[0].Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	_, err = actors[3].ReshareTo(next, 2)
+	checkError(t, err, "you must first initialize DKG. Did you call setup() first?")
+
+	res, err := actors[0].ReshareTo(next, 2)
+	if err != nil {
+		t.Fatalf("failed to reshare: %v", err)
+	}
+
+	if !res.Equal(pubkey) {
+		t.Fatal("public key has changed")
+	}
+
+	phase, _, err := dkgs[0].Status()
+	if err != nil || phase != PhaseListening {
+		t.Fatalf("expected phase %s, got %s: %v", PhaseListening, phase, err)
+	}
+
+	// The new member decrypts the messages encrypted for the previous
+	// committee.
+	decrypted, err := actors[3].Decrypt(K, C)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+
+	if !bytes.Equal(decrypted, message) {
+		t.Fatalf("wrong message: %q", decrypted)
+	}
+
+	phase, st, err := dkgs[3].Status()
+	if err != nil || phase != PhaseReady {
+		t.Fatalf("expected phase %s, got %s: %v", PhaseReady, phase, err)
+	}
+
+	if !st.Commits[0].Equal(pubkey) || len(st.Participants) != 3 {
+		t.Fatalf("unexpected state: %v", st)
+	}
+}
+
+func TestPedersen_Resume(t *testing.T) {
+	n := 3
+
+	dir, paths := tempPaths(t, n)
+	defer os.RemoveAll(dir)
+
+	dbs := make([]kv.DB, n)
+	storages := make([]Storage, n)
+
+	// The address factory of minoch doesn't depend on the manager.
+	addrFac := minoch.MustCreate(minoch.NewManager(), "fac").GetAddressFactory()
+
+	for i := range storages {
+		dbs[i] = openDB(t, paths[i])
+		storages[i] = NewDiskStorage(dbs[i], addrFac)
+	}
+
+	dkgs, co := makeDKGs(t, minoch.NewManager(), storages)
+
+	actor, err := dkgs[0].Listen()
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	for _, p := range dkgs[1:] {
+		_, err = p.Listen()
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+	}
+
+	pubkey, err := actor.Setup(co, 2)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+
+	message := []byte("Hello world")
+
+	K, C, _, err := actor.Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	// The nodes restart with the same addresses and the same databases.
+	for i := range dbs {
+		err = dbs[i].Close()
+		if err != nil {
+			t.Fatalf("failed to close db: %v", err)
+		}
+
+		dbs[i] = openDB(t, paths[i])
+		storages[i] = NewDiskStorage(dbs[i], addrFac)
+	}
+
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+
+	resumed, sameCo := makeDKGs(t, minoch.NewManager(), storages)
+
+	actors := make([]dkg.Actor, n)
+
+	for i, p := range resumed {
+		phase, _, err := p.Status()
+		if err != nil || phase != PhaseCreated {
+			t.Fatalf("expected phase %s, got %s: %v", PhaseCreated, phase, err)
+		}
+
+		actors[i], err = p.Listen()
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+
+		phase, st, err := p.Status()
+		if err != nil || phase != PhaseReady {
+			t.Fatalf("expected phase %s, got %s: %v", PhaseReady, phase, err)
+		}
+
+		if !st.Commits[0].Equal(pubkey) || len(st.Participants) != n {
+			t.Fatalf("unexpected state: %v", st)
+		}
+	}
+
+	for i, actor := range actors {
+		res, err := actor.GetPublicKey()
+		if err != nil {
+			t.Fatalf("failed to get public key: %v", err)
+		}
+
+		if !res.Equal(pubkey) {
+			t.Fatalf("node %d: public key has changed", i)
+		}
+
+		decrypted, err := actor.Decrypt(K, C)
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+
+		if !bytes.Equal(decrypted, message) {
+			t.Fatalf("wrong message: %q", decrypted)
+		}
+	}
+
+	_, err = actors[0].Setup(sameCo, 2)
+	checkError(t, err, "startRes is already done, only one setup call is allowed")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// makeDKGs creates a DKG on a minoch instance for each storage, and returns
+// them with the collective authority of the nodes. The instances are named
+// after their index so that a node keeps its address with another manager.
+func makeDKGs(t *testing.T, manager *minoch.Manager,
+	storages []Storage) ([]*Pedersen, crypto.CollectiveAuthority) {
+
+	t.Helper()
+
+	dkgs := make([]*Pedersen, len(storages))
+	addrs := make([]mino.Address, len(storages))
+	pubkeys := make([]crypto.PublicKey, len(storages))
+
+	for i, storage := range storages {
+		m := minoch.MustCreate(manager, fmt.Sprintf("node%d", i))
+
+		p, pubkey, err := NewPedersen(m, storage)
+		if err != nil {
+			t.Fatalf("failed to create pedersen: %v", err)
+		}
+
+		dkgs[i] = p
+		addrs[i] = m.GetAddress()
+		pubkeys[i] = ed25519.NewPublicKeyFromPoint(pubkey)
+	}
+
+	return dkgs, authority.New(addrs, pubkeys)
+}
@@ -0,0 +1,348 @@
+package pedersen
+
+import (
+	"encoding/json"
+	"sync"
+
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"golang.org/x/xerrors"
+)
+
+// State is the result of the setup of the DKG on a node.
+type State struct {
+	Threshold int
+	// Participants are the addresses of the nodes that hold a share.
+	Participants []mino.Address
+	// PublicKeys are the DKG public keys of the participants.
+	PublicKeys []kyber.Point
+	// Share is the private share of the node.
+	Share *share.PriShare
+	// Commits are the public commitments of the distributed key, the first
+	// one being the collective public key.
+	Commits []kyber.Point
+}
+
+// Storage persists the private key of the node and the state of the DKG.
+type Storage interface {
+	// LoadKey returns the private key of the node, or nil if it has not been
+	// stored yet.
+	LoadKey() (kyber.Scalar, error)
+
+	StoreKey(key kyber.Scalar) error
+
+	// LoadState returns the state of the DKG, or nil if the setup has not
+	// been done yet.
+	LoadState() (*State, error)
+
+	StoreState(st State) error
+
+	// DeleteState removes the state of the DKG, when the node is not part of
+	// the committee anymore.
+	DeleteState() error
+}
+
+// InMemoryStorage is a storage that keeps the key and the state in memory,
+// which is lost when the node stops.
+//
+// - implements pedersen.Storage
+type InMemoryStorage struct {
+	sync.Mutex
+	key   kyber.Scalar
+	state *State
+}
+
+// NewInMemoryStorage returns a new empty in-memory storage.
+func NewInMemoryStorage() *InMemoryStorage {
+	return &InMemoryStorage{}
+}
+
+// LoadKey implements pedersen.Storage.
+func (s *InMemoryStorage) LoadKey() (kyber.Scalar, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.key, nil
+}
+
+// StoreKey implements pedersen.Storage.
+func (s *InMemoryStorage) StoreKey(key kyber.Scalar) error {
+	s.Lock()
+	s.key = key
+	s.Unlock()
+
+	return nil
+}
+
+// LoadState implements pedersen.Storage.
+func (s *InMemoryStorage) LoadState() (*State, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.state, nil
+}
+
+// StoreState implements pedersen.Storage.
+func (s *InMemoryStorage) StoreState(st State) error {
+	s.Lock()
+	s.state = &st
+	s.Unlock()
+
+	return nil
+}
+
+// DeleteState implements pedersen.Storage.
+func (s *InMemoryStorage) DeleteState() error {
+	s.Lock()
+	s.state = nil
+	s.Unlock()
+
+	return nil
+}
+
+var (
+	// bucketName is the name of the bucket where the key and the state are
+	// stored.
+	bucketName = []byte("calypso-dkg")
+	keyKey     = []byte("key")
+	stateKey   = []byte("state")
+)
+
+// stateJSON is the JSON form of the state in the database.
+type stateJSON struct {
+	Threshold    int
+	Participants [][]byte
+	PublicKeys   [][]byte
+	Index        int
+	Share        []byte
+	Commits      [][]byte
+}
+
+// DiskStorage is a storage that persists the key and the state in a
+// key/value database.
+//
+// - implements pedersen.Storage
+type DiskStorage struct {
+	db      kv.DB
+	addrFac mino.AddressFactory
+}
+
+// NewDiskStorage returns a new storage on top of the database. The address
+// factory is used to decode the addresses of the participants.
+func NewDiskStorage(db kv.DB, addrFac mino.AddressFactory) DiskStorage {
+	return DiskStorage{
+		db:      db,
+		addrFac: addrFac,
+	}
+}
+
+// LoadKey implements pedersen.Storage.
+func (s DiskStorage) LoadKey() (kyber.Scalar, error) {
+	data, err := s.get(keyKey)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read key: %v", err)
+	}
+
+	if data == nil {
+		return nil, nil
+	}
+
+	key := suite.Scalar()
+	err = key.UnmarshalBinary(data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+
+	return key, nil
+}
+
+// StoreKey implements pedersen.Storage.
+func (s DiskStorage) StoreKey(key kyber.Scalar) error {
+	data, err := key.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal key: %v", err)
+	}
+
+	return s.set(keyKey, data)
+}
+
+// LoadState implements pedersen.Storage.
+func (s DiskStorage) LoadState() (*State, error) {
+	data, err := s.get(stateKey)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read state: %v", err)
+	}
+
+	if data == nil {
+		return nil, nil
+	}
+
+	var m stateJSON
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal state: %v", err)
+	}
+
+	st := State{
+		Threshold:    m.Threshold,
+		Participants: make([]mino.Address, len(m.Participants)),
+	}
+
+	for i, addr := range m.Participants {
+		st.Participants[i] = s.addrFac.FromText(addr)
+	}
+
+	st.PublicKeys, err = decodePoints(m.PublicKeys)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode public keys: %v", err)
+	}
+
+	st.Commits, err = decodePoints(m.Commits)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode commits: %v", err)
+	}
+
+	if len(st.Commits) == 0 {
+		return nil, xerrors.New("state has no commit")
+	}
+
+	v := suite.Scalar()
+	err = v.UnmarshalBinary(m.Share)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal share: %v", err)
+	}
+
+	st.Share = &share.PriShare{I: m.Index, V: v}
+
+	return &st, nil
+}
+
+// StoreState implements pedersen.Storage.
+func (s DiskStorage) StoreState(st State) error {
+	m := stateJSON{
+		Threshold:    st.Threshold,
+		Participants: make([][]byte, len(st.Participants)),
+		Index:        st.Share.I,
+	}
+
+	var err error
+
+	for i, addr := range st.Participants {
+		m.Participants[i], err = addr.MarshalText()
+		if err != nil {
+			return xerrors.Errorf("failed to marshal address: %v", err)
+		}
+	}
+
+	m.PublicKeys, err = encodePoints(st.PublicKeys)
+	if err != nil {
+		return xerrors.Errorf("failed to encode public keys: %v", err)
+	}
+
+	m.Commits, err = encodePoints(st.Commits)
+	if err != nil {
+		return xerrors.Errorf("failed to encode commits: %v", err)
+	}
+
+	m.Share, err = st.Share.V.MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("failed to marshal share: %v", err)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal state: %v", err)
+	}
+
+	return s.set(stateKey, data)
+}
+
+// DeleteState implements pedersen.Storage.
+func (s DiskStorage) DeleteState() error {
+	err := s.db.Update(func(txn kv.WritableTx) error {
+		bucket := txn.GetBucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete(stateKey)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to write db: %v", err)
+	}
+
+	return nil
+}
+
+func (s DiskStorage) get(key []byte) ([]byte, error) {
+	var data []byte
+
+	err := s.db.View(func(txn kv.ReadableTx) error {
+		bucket := txn.GetBucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		value := bucket.Get(key)
+		if value != nil {
+			data = append([]byte{}, value...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read db: %v", err)
+	}
+
+	return data, nil
+}
+
+func (s DiskStorage) set(key, value []byte) error {
+	err := s.db.Update(func(txn kv.WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate(bucketName)
+		if err != nil {
+			return xerrors.Errorf("failed to get bucket: %v", err)
+		}
+
+		return bucket.Set(key, value)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to write db: %v", err)
+	}
+
+	return nil
+}
+
+func encodePoints(points []kyber.Point) ([][]byte, error) {
+	res := make([][]byte, len(points))
+
+	for i, point := range points {
+		data, err := point.MarshalBinary()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to marshal point: %v", err)
+		}
+
+		res[i] = data
+	}
+
+	return res, nil
+}
+
+func decodePoints(data [][]byte) ([]kyber.Point, error) {
+	res := make([]kyber.Point, len(data))
+
+	for i, buf := range data {
+		point := suite.Point()
+
+		err := point.UnmarshalBinary(buf)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to unmarshal point: %v", err)
+		}
+
+		res[i] = point
+	}
+
+	return res, nil
+}
@@ -0,0 +1,189 @@
+package pedersen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/mino/minoch"
+)
+
+func TestInMemoryStorage(t *testing.T) {
+	checkStorage(t, NewInMemoryStorage())
+}
+
+func TestDiskStorage(t *testing.T) {
+	dir, paths := tempPaths(t, 1)
+	defer os.RemoveAll(dir)
+
+	m := minoch.MustCreate(minoch.NewManager(), "node")
+
+	db := openDB(t, paths[0])
+	storage := NewDiskStorage(db, m.GetAddressFactory())
+
+	checkStorage(t, storage)
+
+	// The key and the state survive a restart of the node.
+	key, err := storage.LoadKey()
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+
+	st := makeState(m.GetAddress(), suite.Point().Mul(key, nil))
+
+	err = storage.StoreState(st)
+	if err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("failed to close db: %v", err)
+	}
+
+	db = openDB(t, paths[0])
+	defer db.Close()
+
+	storage = NewDiskStorage(db, m.GetAddressFactory())
+
+	res, err := storage.LoadKey()
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+
+	if !res.Equal(key) {
+		t.Fatal("key has changed")
+	}
+
+	checkState(t, storage, st)
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// checkStorage verifies that the storage returns what has been stored, and
+// nothing before.
+func checkStorage(t *testing.T, storage Storage) {
+	t.Helper()
+
+	key, err := storage.LoadKey()
+	if err != nil || key != nil {
+		t.Fatalf("expected no key, got %v: %v", key, err)
+	}
+
+	st, err := storage.LoadState()
+	if err != nil || st != nil {
+		t.Fatalf("expected no state, got %v: %v", st, err)
+	}
+
+	key = suite.Scalar().Pick(suite.RandomStream())
+
+	err = storage.StoreKey(key)
+	if err != nil {
+		t.Fatalf("failed to store key: %v", err)
+	}
+
+	res, err := storage.LoadKey()
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+
+	if !res.Equal(key) {
+		t.Fatal("wrong key")
+	}
+
+	// The address must be decoded by the address factory of the disk storage.
+	addr := minoch.MustCreate(minoch.NewManager(), "node").GetAddress()
+	expected := makeState(addr, suite.Point().Mul(key, nil))
+
+	err = storage.StoreState(expected)
+	if err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	checkState(t, storage, expected)
+
+	err = storage.DeleteState()
+	if err != nil {
+		t.Fatalf("failed to delete state: %v", err)
+	}
+
+	st, err = storage.LoadState()
+	if err != nil || st != nil {
+		t.Fatalf("expected no state, got %v: %v", st, err)
+	}
+}
+
+func checkState(t *testing.T, storage Storage, expected State) {
+	t.Helper()
+
+	st, err := storage.LoadState()
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	if st == nil {
+		t.Fatal("expected a state")
+	}
+
+	if st.Threshold != expected.Threshold {
+		t.Fatalf("wrong threshold: %d != %d", st.Threshold, expected.Threshold)
+	}
+
+	if len(st.Participants) != len(expected.Participants) ||
+		len(st.PublicKeys) != len(expected.PublicKeys) ||
+		len(st.Commits) != len(expected.Commits) {
+
+		t.Fatalf("unexpected state: %v", st)
+	}
+
+	for i, addr := range st.Participants {
+		if !addr.Equal(expected.Participants[i]) ||
+			!st.PublicKeys[i].Equal(expected.PublicKeys[i]) {
+
+			t.Fatalf("participant %d: expected %v, got %v", i,
+				expected.Participants[i], addr)
+		}
+	}
+
+	for i, commit := range st.Commits {
+		if !commit.Equal(expected.Commits[i]) {
+			t.Fatalf("commit %d has changed", i)
+		}
+	}
+
+	if st.Share.I != expected.Share.I || !st.Share.V.Equal(expected.Share.V) {
+		t.Fatal("share has changed")
+	}
+}
+
+// tempPaths returns a temporary directory and the paths of n databases in it.
+func tempPaths(t *testing.T, n int) (string, []string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "calypso-dkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("dkg%d.db", i))
+	}
+
+	return dir, paths
+}
+
+func openDB(t *testing.T, path string) kv.DB {
+	t.Helper()
+
+	db, err := kv.New(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+
+	return db
+}
@@ -0,0 +1,305 @@
+package calypso
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/crypto/ed25519"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// expirySize is the size in bytes of the expiry of the nonces.
+	expirySize = 8
+
+	// nonceSize is the size in bytes of the random part of the nonces.
+	nonceSize = 16
+
+	// nonceLen is the size in bytes of the nonces, which end with their MAC.
+	nonceLen = expirySize + nonceSize + sha256.Size
+
+	// auditTag separates the digests of the audit queries from the ones of
+	// the updates, which are signed by the same identities.
+	auditTag = "calypso_audit"
+)
+
+// SignedIdentity is the public key of an identity alongside its signature of
+// the digest of a request, which proves that the identity sent it.
+type SignedIdentity struct {
+	PublicKey crypto.PublicKey
+	Signature crypto.Signature
+}
+
+// Authentication proves that the identities sent a request. Each of them signs
+// the digest of the request, which includes the nonce of a challenge issued by
+// the node so that the signatures can't be replayed.
+type Authentication struct {
+	Nonce      []byte
+	Identities []SignedIdentity
+}
+
+// NewAuthentication signs the digest with each of the signers and returns the
+// authentication of the request.
+func NewAuthentication(nonce, digest []byte,
+	signers ...crypto.Signer) (Authentication, error) {
+
+	auth := Authentication{
+		Nonce:      nonce,
+		Identities: make([]SignedIdentity, len(signers)),
+	}
+
+	for i, signer := range signers {
+		sig, err := signer.Sign(digest)
+		if err != nil {
+			return auth, xerrors.Errorf("failed to sign: %v", err)
+		}
+
+		auth.Identities[i] = SignedIdentity{
+			PublicKey: signer.GetPublicKey(),
+			Signature: sig,
+		}
+	}
+
+	return auth, nil
+}
+
+// ReadDigest returns the digest that the readers sign to read the record.
+func ReadDigest(nonce, id []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(ArcRuleRead))
+	h.Write(nonce)
+	h.Write(id)
+
+	return h.Sum(nil)
+}
+
+// AuditDigest returns the digest that the admin signs to query the audit log
+// of the record.
+func AuditDigest(nonce, id []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(auditTag))
+	h.Write(nonce)
+	h.Write(id)
+
+	return h.Sum(nil)
+}
+
+// ReencryptDigest returns the digest that the readers sign to read the record
+// re-encrypted under the public key, alongside the proof of knowledge of its
+// private key. See NewReaderKeyProof.
+func ReencryptDigest(nonce, id []byte, pubk kyber.Point,
+	keyProof []byte) ([]byte, error) {
+
+	h := sha256.New()
+	h.Write([]byte(ArcRuleRead))
+	h.Write(nonce)
+	h.Write(id)
+
+	_, err := pubk.MarshalTo(h)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal public key: %v", err)
+	}
+
+	h.Write(keyProof)
+
+	return h.Sum(nil), nil
+}
+
+// UpdateDigest returns the digest that the admin signs to replace the access
+// control of the record by the new one. The access control is bound through
+// its JSON serialization, as for the write proof.
+func UpdateDigest(nonce, id []byte, ac access.Service) ([]byte, error) {
+	msg, ok := ac.(serde.Message)
+	if !ok {
+		return nil, xerrors.Errorf("access '%T' is not serializable", ac)
+	}
+
+	policy, err := msg.Serialize(json.NewContext())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize access: %v", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(ArcRuleUpdate))
+	h.Write(nonce)
+	h.Write(id)
+	h.Write(policy)
+
+	return h.Sum(nil), nil
+}
+
+// ParseIdentity returns the public key of an identity in its text form, which
+// is either "schnorr:<hex>" for Ed25519 or "bls:<hex>" for BLS, as printed by
+// the public keys of Dela.
+func ParseIdentity(text string) (crypto.PublicKey, error) {
+	sep := strings.Index(text, ":")
+	if sep < 0 {
+		return nil, xerrors.Errorf("identity '%s' has no algorithm", text)
+	}
+
+	data, err := hex.DecodeString(text[sep+1:])
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode identity: %v", err)
+	}
+
+	var pubkey crypto.PublicKey
+
+	switch text[:sep] {
+	case "schnorr":
+		pubkey, err = ed25519.NewPublicKey(data)
+	case "bls":
+		pubkey, err = bls.NewPublicKey(data)
+	default:
+		return nil, xerrors.Errorf("unknown algorithm '%s'", text[:sep])
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("invalid identity: %v", err)
+	}
+
+	return pubkey, nil
+}
+
+// ParseSignature returns the signature of the data for the algorithm of the
+// public key.
+func ParseSignature(pubkey crypto.PublicKey, data []byte) (crypto.Signature, error) {
+	switch pubkey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.NewSignature(data), nil
+	case bls.PublicKey:
+		return bls.NewSignature(data), nil
+	default:
+		return nil, xerrors.Errorf("unsupported public key '%T'", pubkey)
+	}
+}
+
+// VerifyIdentities verifies that each identity of the authentication signed
+// the digest, and returns their public keys. It returns an error wrapping
+// ErrUnauthenticated otherwise.
+func VerifyIdentities(auth Authentication, digest []byte) ([]access.Identity, error) {
+	if len(auth.Identities) == 0 {
+		return nil, xerrors.Errorf("no identity: %w", ErrUnauthenticated)
+	}
+
+	idents := make([]access.Identity, len(auth.Identities))
+
+	for i, ident := range auth.Identities {
+		if ident.PublicKey == nil || ident.Signature == nil {
+			return nil, xerrors.Errorf("identity %d is incomplete: %w", i,
+				ErrUnauthenticated)
+		}
+
+		err := ident.PublicKey.Verify(digest, ident.Signature)
+		if err != nil {
+			return nil, xerrors.Errorf("identity %v: %v: %w", ident.PublicKey,
+				err, ErrUnauthenticated)
+		}
+
+		idents[i] = ident.PublicKey
+	}
+
+	return idents, nil
+}
+
+// challenges issues the nonces without keeping them. A nonce holds its expiry,
+// a random part and a MAC of both and of the record ID, so that the node only
+// verifies it. The nonces that have been used are remembered until they
+// expire, so that they can't be used again.
+type challenges struct {
+	sync.Mutex
+	ttl  time.Duration
+	key  []byte
+	used map[string]time.Time
+}
+
+func newChallenges(ttl time.Duration) *challenges {
+	key := make([]byte, sha256.Size)
+	random.Bytes(key, random.New())
+
+	return &challenges{
+		ttl:  ttl,
+		key:  key,
+		used: make(map[string]time.Time),
+	}
+}
+
+// issue returns a new nonce for the record and the time after which it is
+// refused.
+func (c *challenges) issue(id []byte) ([]byte, time.Time) {
+	expiry := time.Now().Add(c.ttl)
+
+	nonce := make([]byte, expirySize+nonceSize, nonceLen)
+	binary.BigEndian.PutUint64(nonce, uint64(expiry.UnixNano()))
+	random.Bytes(nonce[expirySize:], random.New())
+
+	return append(nonce, c.mac(nonce, id)...), expiry
+}
+
+// verify returns an error if the nonce has not been issued by this node for
+// the record, or if it has expired.
+func (c *challenges) verify(nonce, id []byte) error {
+	if len(nonce) != nonceLen {
+		return xerrors.Errorf("invalid nonce size %d", len(nonce))
+	}
+
+	prefix, mac := nonce[:expirySize+nonceSize], nonce[expirySize+nonceSize:]
+
+	if !hmac.Equal(mac, c.mac(prefix, id)) {
+		return xerrors.Errorf("nonce %#x was not issued for %#x", nonce, id)
+	}
+
+	if time.Now().After(nonceExpiry(nonce)) {
+		return xerrors.Errorf("nonce %#x has expired", nonce)
+	}
+
+	return nil
+}
+
+// consume remembers the nonce so that it can't be used again. It returns an
+// error if it has already been used. The nonce must have been verified.
+func (c *challenges) consume(nonce []byte) error {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+
+	for key, expiry := range c.used {
+		if now.After(expiry) {
+			delete(c.used, key)
+		}
+	}
+
+	_, found := c.used[string(nonce)]
+	if found {
+		return xerrors.Errorf("nonce %#x has already been used", nonce)
+	}
+
+	c.used[string(nonce)] = nonceExpiry(nonce)
+
+	return nil
+}
+
+func (c *challenges) mac(prefix, id []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(prefix)
+	h.Write(id)
+
+	return h.Sum(nil)
+}
+
+// nonceExpiry returns the expiry written at the beginning of the nonce.
+func nonceExpiry(nonce []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(nonce)))
+}
@@ -2,8 +2,10 @@ package json
 
 import (
 	"encoding/json"
+	"time"
 
 	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/kyber/v3/suites"
 	"golang.org/x/xerrors"
@@ -15,9 +17,23 @@ func init() {
 
 // Record is a JSON record
 type Record struct {
-	K  []byte
-	C  []byte
+	K     []byte
+	C     []byte
+	Data  []byte
+	Proof []byte
+	// AC is the access control of the write, which is replaced by the one of
+	// the last update.
 	AC json.RawMessage
+	// Expiry is the expiry of the record in nanoseconds since the Unix epoch,
+	// or zero if it never expires.
+	Expiry  int64          `json:",omitempty"`
+	Updates []AccessUpdate `json:",omitempty"`
+}
+
+// AccessUpdate is a JSON update of the access control of a record.
+type AccessUpdate struct {
+	AC    json.RawMessage
+	Proof []byte
 }
 
 type recordFormat struct {
@@ -47,8 +63,31 @@ func (f recordFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, erro
 	}
 
 	m := Record{
-		K: kBuf,
-		C: cBuf,
+		K:     kBuf,
+		C:     cBuf,
+		Data:  record.GetData(),
+		Proof: record.GetProof(),
+	}
+
+	if !record.GetExpiry().IsZero() {
+		m.Expiry = record.GetExpiry().UnixNano()
+	}
+
+	m.AC, err = encodeAccess(ctx, record.GetWriteAccess())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range record.GetUpdates() {
+		ac, err := encodeAccess(ctx, update.GetAccess())
+		if err != nil {
+			return nil, xerrors.Errorf("update: %v", err)
+		}
+
+		m.Updates = append(m.Updates, AccessUpdate{
+			AC:    ac,
+			Proof: update.GetProof(),
+		})
 	}
 
 	data, err := ctx.Marshal(m)
@@ -78,7 +117,74 @@ func (f recordFormat) Decode(ctx serde.Context, data []byte) (serde.Message, err
 		return nil, xerrors.Errorf("failed to unmarshal C: %v", err)
 	}
 
-	r := calypso.NewRecord(K, C, nil)
+	ac, err := decodeAccess(ctx, m.AC)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []calypso.AccessUpdate
+
+	for _, update := range m.Updates {
+		updateAc, err := decodeAccess(ctx, update.AC)
+		if err != nil {
+			return nil, xerrors.Errorf("update: %v", err)
+		}
+
+		updates = append(updates, calypso.NewAccessUpdate(updateAc, update.Proof))
+	}
+
+	opts := []calypso.RecordOption{
+		calypso.WithData(m.Data),
+		calypso.WithProof(m.Proof),
+		calypso.WithUpdates(updates...),
+	}
+
+	if m.Expiry != 0 {
+		opts = append(opts, calypso.WithExpiry(time.Unix(0, m.Expiry)))
+	}
+
+	r := calypso.NewRecord(K, C, ac, opts...)
 
 	return r, nil
 }
+
+// encodeAccess returns the serialized access control, or nil if there is none.
+func encodeAccess(ctx serde.Context, ac access.Service) (json.RawMessage, error) {
+	if ac == nil {
+		return nil, nil
+	}
+
+	msg, ok := ac.(serde.Message)
+	if !ok {
+		return nil, xerrors.Errorf("access '%T' is not serializable", ac)
+	}
+
+	data, err := msg.Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize access: %v", err)
+	}
+
+	return data, nil
+}
+
+// decodeAccess returns the access control of the data, or nil if it is empty.
+func decodeAccess(ctx serde.Context, data json.RawMessage) (access.Service, error) {
+	// A record without access control is encoded with a null access.
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	fac := ctx.GetFactory(calypso.AccessKeyFac{})
+
+	accessFac, ok := fac.(calypso.AccessFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid access factory '%T'", fac)
+	}
+
+	ac, err := accessFac.AccessOf(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode access: %v", err)
+	}
+
+	return ac, nil
+}
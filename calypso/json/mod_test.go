@@ -0,0 +1,204 @@
+package json
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/serde"
+	sjson "go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	_ "go.dedis.ch/dela-apps/calypso/arc/json"
+)
+
+func TestRecordFormat_RoundTrip(t *testing.T) {
+	ctx := sjson.NewContext()
+	fac := calypso.NewRecordFactory(arc.NewFactory())
+
+	ac := arc.NewService(
+		arc.WithRule(calypso.ArcRuleUpdate, "bls:aa"),
+		arc.WithRule(calypso.ArcRuleRead, "bls:aa", "schnorr:bb"),
+	)
+
+	expiry := time.Unix(0, 1700000000123456789)
+
+	bobAc := arc.NewService(arc.WithRule(calypso.ArcRuleUpdate, "bls:bb"))
+
+	testCases := []struct {
+		name   string
+		record calypso.Record
+	}{
+		{"access", calypso.NewRecord(point(), point(), ac)},
+		{"no access", calypso.NewRecord(point(), point(), nil)},
+		{"all fields", calypso.NewRecord(point(), point(), ac,
+			calypso.WithData([]byte("data")), calypso.WithProof([]byte("proof")),
+			calypso.WithExpiry(expiry))},
+		{"updates", calypso.NewRecord(point(), point(), ac,
+			calypso.WithUpdates(calypso.NewAccessUpdate(bobAc, []byte("first")),
+				calypso.NewAccessUpdate(ac, []byte("second"))))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.record.Serialize(ctx)
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+
+			msg, err := fac.Deserialize(ctx, data)
+			if err != nil {
+				t.Fatalf("failed to deserialize: %v", err)
+			}
+
+			record := msg.(calypso.Record)
+			expected := tc.record
+
+			if !record.GetK().Equal(expected.GetK()) ||
+				!record.GetC().Equal(expected.GetC()) ||
+				string(record.GetData()) != string(expected.GetData()) ||
+				string(record.GetProof()) != string(expected.GetProof()) ||
+				!record.GetExpiry().Equal(expected.GetExpiry()) {
+
+				t.Fatalf("expected %v, got %v", expected, record)
+			}
+
+			if record.GetVersion() != expected.GetVersion() {
+				t.Fatalf("expected version %d, got %d", expected.GetVersion(),
+					record.GetVersion())
+			}
+
+			for i, update := range record.GetUpdates() {
+				if string(update.GetProof()) != string(expected.GetUpdates()[i].GetProof()) {
+					t.Fatalf("update %d: unexpected proof %q", i, update.GetProof())
+				}
+			}
+
+			if expected.GetAccess() == nil {
+				if record.GetAccess() != nil {
+					t.Fatalf("unexpected access %v", record.GetAccess())
+				}
+
+				return
+			}
+
+			rules := record.GetAccess().(*arc.Service).GetRules()
+			if !reflect.DeepEqual(rules, ac.GetRules()) {
+				t.Fatalf("expected rules %v, got %v", ac.GetRules(), rules)
+			}
+		})
+	}
+}
+
+func TestRecordFormat_Encode(t *testing.T) {
+	format := newRecordFormat()
+	ctx := sjson.NewContext()
+
+	_, err := format.Encode(ctx, fakeMessage{})
+	checkError(t, err, "unsupported message of type 'json.fakeMessage'")
+
+	record := calypso.NewRecord(point(), point(), fakeAccess{})
+
+	_, err = format.Encode(ctx, record)
+	checkError(t, err, "access 'json.fakeAccess' is not serializable")
+
+	record = calypso.NewRecord(point(), point(), nil,
+		calypso.WithUpdates(calypso.NewAccessUpdate(fakeAccess{}, nil)))
+
+	_, err = format.Encode(ctx, record)
+	checkError(t, err, "update: access 'json.fakeAccess' is not serializable")
+}
+
+func TestRecordFormat_Decode(t *testing.T) {
+	format := newRecordFormat()
+	ctx := serde.WithFactory(sjson.NewContext(), calypso.AccessKeyFac{},
+		arc.NewFactory())
+
+	K := marshal(t, point())
+	C := marshal(t, point())
+
+	testCases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"truncated", `{"K":`, "couldn't unmarshal record"},
+		{"bad K", `{"K":"AA==","C":"` + C + `"}`, "failed to unmarshal K"},
+		{"bad C", `{"K":"` + K + `","C":"AA=="}`, "failed to unmarshal C"},
+		{"access not an object", `{"K":"` + K + `","C":"` + C + `","AC":[]}`,
+			"failed to decode access"},
+		{"rules not a map", `{"K":"` + K + `","C":"` + C + `","AC":{"Rules":5}}`,
+			"failed to decode access"},
+		{"identity not a string",
+			`{"K":"` + K + `","C":"` + C + `","AC":{"Rules":{"read":[5]}}}`,
+			"failed to decode access"},
+		{"bad update", `{"K":"` + K + `","C":"` + C + `","Updates":[{"AC":[]}]}`,
+			"update: failed to decode access"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := format.Decode(ctx, []byte(tc.data))
+			checkError(t, err, tc.err)
+		})
+	}
+
+	// The access can't be decoded without its factory.
+	data := `{"K":"` + K + `","C":"` + C + `","AC":{"Rules":{}}}`
+
+	_, err := format.Decode(sjson.NewContext(), []byte(data))
+	checkError(t, err, "invalid access factory '<nil>'")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func point() kyber.Point {
+	return newRecordFormat().suite.Point().Pick(random.New())
+}
+
+// marshal returns the point as encoded in the JSON of a record.
+func marshal(t *testing.T, p kyber.Point) string {
+	t.Helper()
+
+	buf, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal point: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func checkError(t *testing.T, err error, substr string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", substr)
+	}
+
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type fakeMessage struct{}
+
+func (fakeMessage) Serialize(serde.Context) ([]byte, error) {
+	return nil, nil
+}
+
+// fakeAccess is an access service that can't be serialized.
+type fakeAccess struct {
+	access.Service
+}
+
+func (fakeAccess) Match(store.Readable, access.Credential, ...access.Identity) error {
+	return nil
+}
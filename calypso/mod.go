@@ -1,11 +1,39 @@
 package calypso
 
 import (
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso/audit"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
 )
 
+// ErrNotFound is returned when no record exists for a given ID.
+var ErrNotFound = xerrors.New("record not found")
+
+// ErrExpired is returned when a record exists but its expiry has passed.
+var ErrExpired = xerrors.New("record expired")
+
+// ErrAlreadyExists is returned when a record with the same ID already exists.
+var ErrAlreadyExists = xerrors.New("record already exists")
+
+// ErrReencryptNotSupported is returned when the DKG actor cannot re-encrypt.
+var ErrReencryptNotSupported = xerrors.New("re-encryption not supported")
+
+// ErrReshareNotSupported is returned when the DKG actor cannot reshare.
+var ErrReshareNotSupported = xerrors.New("resharing not supported")
+
+// ErrUnauthenticated is returned when the signatures of a request don't prove
+// that it comes from the identities it claims.
+var ErrUnauthenticated = xerrors.New("identity not authenticated")
+
+// ErrAccessDenied is returned when the access control of a record doesn't
+// allow the identities to perform an operation.
+var ErrAccessDenied = xerrors.New("access denied")
+
 // PrivateStorage defines the primitives to run a Calypso-like app. It is mainly
 // a wrapper arround DKG that provides a storage and authorization layer.
 type PrivateStorage interface {
@@ -13,13 +41,90 @@ type PrivateStorage interface {
 	// sharing
 	Setup(ca crypto.CollectiveAuthority, threshold int) (pubKey kyber.Point, err error)
 
+	// Reshare moves the secret sharing to a new collective authority and
+	// threshold. The collective public key stays the same so that the records
+	// written before can still be read.
+	Reshare(ca crypto.CollectiveAuthority, threshold int) (pubKey kyber.Point, err error)
+
 	// GetPublicKey returns the collective public key. Returns an error if the
 	// setup has not been done.
 	GetPublicKey() (kyber.Point, error)
 
-	Write(message EncryptedMessage, ac access.Service) (ID []byte, err error)
-	Read(ID []byte, idents ...access.Identity) (msg []byte, err error)
-	UpdateAccess(ID []byte, ident access.Identity, ac access.Service) error
+	// Write stores the message with its access control and returns the ID of
+	// the record. The proof of the message must verify against the access
+	// control, otherwise an error wrapping ErrInvalidProof is returned. The
+	// record can be given an expiry with the ExpireAt option.
+	Write(message EncryptedMessage, ac access.Service,
+		opts ...WriteOption) (ID []byte, err error)
+
+	// Challenge returns a nonce that the identities must include in the
+	// digest they sign to authenticate a request on the record, and the time
+	// after which it is refused. A nonce can be used only once, and only for
+	// the record it has been issued for.
+	Challenge(ID []byte) (nonce []byte, expiry time.Time, err error)
+
+	// Read returns the decrypted message of the record if the identities
+	// signed the ReadDigest of the record and one of them is allowed by the
+	// ArcRuleRead rule of its access control. Returns an error wrapping
+	// ErrUnauthenticated, ErrNotFound, ErrExpired or ErrAccessDenied
+	// accordingly.
+	Read(ID []byte, auth Authentication) (msg []byte, err error)
+
+	// UpdateAccess replaces the access control of the record if the
+	// identities signed the UpdateDigest of the new one and one of them is
+	// allowed by the ArcRuleUpdate rule of the current one. Returns an error
+	// wrapping ErrUnauthenticated, ErrNotFound or ErrAccessDenied
+	// accordingly. The authentication is kept in the record, so that the
+	// members holding a replica can verify the update.
+	UpdateAccess(ID []byte, auth Authentication, ac access.Service) error
+
+	// ReadReencrypted returns the secret of the record re-encrypted under the
+	// public key of the reader, which can then be decrypted with
+	// DecryptReencrypted. The reader proves that it knows the private key of
+	// pubk with NewReaderKeyProof, the identities sign the ReencryptDigest
+	// and the access is verified as in Read. Returns an error wrapping
+	// ErrInvalidReaderKey if the key or its proof is invalid.
+	ReadReencrypted(ID []byte, pubk kyber.Point, keyProof []byte,
+		auth Authentication) (Reencrypted, error)
+
+	// Audit returns the entries of the audit log of the record if the
+	// identities signed the AuditDigest of the record and one of them is
+	// allowed by the ArcRuleUpdate rule of its access control, that is the
+	// admin of the record. Returns an error wrapping ErrUnauthenticated,
+	// ErrNotFound, ErrExpired or ErrAccessDenied accordingly.
+	Audit(ID []byte, auth Authentication) ([]audit.Entry, error)
+}
+
+// Ledger anchors the records and their accesses in a ledger, where they are
+// ordered by the consensus and leave a trace. Each method returns once the
+// transaction has been committed, or an error if it has been refused.
+type Ledger interface {
+	// Write anchors the record written under the ID.
+	Write(ID []byte, record Record) error
+
+	// Update anchors the record whose access control has been updated.
+	Update(ID []byte, record Record) error
+
+	// Read anchors the read of the record authenticated by auth. The reader
+	// key and its proof are nil for a read of the message itself, otherwise
+	// the read releases the secret re-encrypted under the key only.
+	Read(ID []byte, auth Authentication, pubk kyber.Point, keyProof []byte) error
+}
+
+// Reencrypter is the capability of a DKG actor to re-encrypt the shared secret
+// of (K, C) under the public key of a reader. Each member of the collective
+// contributes a re-encryption share so that no node learns the secret. It
+// returns XhatEnc = x*K + xc*X where x is the collective private key, X the
+// collective public key and xc the private key of the reader.
+type Reencrypter interface {
+	Reencrypt(K kyber.Point, pubk kyber.Point) (XhatEnc kyber.Point, err error)
+}
+
+// Resharer is the capability of a DKG actor to move the distributed key to a
+// new committee. The members of the current committee deal their share to the
+// new one, so that the collective public key doesn't change.
+type Resharer interface {
+	ReshareTo(ca crypto.CollectiveAuthority, threshold int) (kyber.Point, error)
 }
 
 // EncryptedMessage wraps the K, C arguments needed to decrypt a message. K is
@@ -28,4 +133,22 @@ type PrivateStorage interface {
 type EncryptedMessage interface {
 	GetK() kyber.Point
 	GetC() kyber.Point
+
+	// GetData returns the ciphertext of the message sealed with a symmetric
+	// key, in which case (K, C) protects that key. It returns nil when the
+	// message is directly embedded in C.
+	GetData() []byte
+
+	// GetProof returns the proof of knowledge of the ephemeral scalar of K,
+	// bound to the access control. See NewWriteProof.
+	GetProof() []byte
+}
+
+// AccessFactory is the factory to deserialize the access control of the
+// records. It is available in the serde context with the AccessKeyFac key when
+// a record is decoded.
+type AccessFactory interface {
+	serde.Factory
+
+	AccessOf(ctx serde.Context, data []byte) (access.Service, error)
 }
@@ -0,0 +1,226 @@
+package calypso
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+
+	// The policy is bound to the proof through its JSON serialization.
+	_ "go.dedis.ch/dela-apps/calypso/arc/json"
+)
+
+// ErrInvalidProof is returned when the write proof of a message doesn't
+// verify.
+var ErrInvalidProof = xerrors.New("invalid write proof")
+
+// ErrInvalidReaderKey is returned when the public key of a re-encrypted read
+// is degenerate, or when the reader doesn't prove that it knows its private
+// key.
+var ErrInvalidReaderKey = xerrors.New("invalid reader key")
+
+// readerKeyTag separates the proofs of the reader keys from the write proofs.
+const readerKeyTag = "calypso_reader_key"
+
+// NewWriteProof returns a non-interactive Schnorr proof of knowledge of the
+// ephemeral scalar k such that K = k*G. The proof is bound to C, to the data
+// of the hybrid mode, to the expiry and to the access control so that (K, C)
+// can't be written again with different data, expiry or policy by someone who
+// doesn't know k. A zero expiry means that the record never expires.
+func NewWriteProof(k kyber.Scalar, K, C kyber.Point, data []byte,
+	expiry time.Time, ac access.Service) ([]byte, error) {
+
+	return prove(k, func(R kyber.Point) (kyber.Scalar, error) {
+		return proofChallenge(K, C, R, data, expiry, ac)
+	})
+}
+
+// VerifyWriteProof verifies a proof created by NewWriteProof. It returns an
+// error wrapping ErrInvalidProof if the proof doesn't match.
+func VerifyWriteProof(proof []byte, K, C kyber.Point, data []byte,
+	expiry time.Time, ac access.Service) error {
+
+	err := verify(proof, K, func(R kyber.Point) (kyber.Scalar, error) {
+		return proofChallenge(K, C, R, data, expiry, ac)
+	})
+	if err != nil {
+		return xerrors.Errorf("%v: %w", err, ErrInvalidProof)
+	}
+
+	return nil
+}
+
+// NewReaderKeyProof returns a non-interactive Schnorr proof of knowledge of the
+// private key xc of the public key of a reader, pubk = xc*G. The proof is bound
+// to the nonce of the challenge and to the record, so that it can't be used
+// for another request.
+func NewReaderKeyProof(xc kyber.Scalar, pubk kyber.Point, nonce,
+	id []byte) ([]byte, error) {
+
+	return prove(xc, func(R kyber.Point) (kyber.Scalar, error) {
+		return readerKeyChallenge(pubk, R, nonce, id)
+	})
+}
+
+// VerifyReaderKey returns an error wrapping ErrInvalidReaderKey if the public
+// key of the reader is not a point of the prime order subgroup other than the
+// identity, or if the proof created by NewReaderKeyProof doesn't match.
+//
+// The members re-encrypt K with xi*(K + pubk), so that a reader who chooses
+// pubk = K' - K gets the secret of K' instead. The proof ensures that pubk is
+// the key of the reader, whose private key can't be derived from K.
+func VerifyReaderKey(proof []byte, pubk kyber.Point, nonce, id []byte) error {
+	err := validatePoint(pubk)
+	if err != nil {
+		return xerrors.Errorf("%v: %w", err, ErrInvalidReaderKey)
+	}
+
+	err = verify(proof, pubk, func(R kyber.Point) (kyber.Scalar, error) {
+		return readerKeyChallenge(pubk, R, nonce, id)
+	})
+	if err != nil {
+		return xerrors.Errorf("%v: %w", err, ErrInvalidReaderKey)
+	}
+
+	return nil
+}
+
+// prove returns the proof (e, s) of knowledge of the scalar x of P = x*G,
+// where e is the challenge of the commitment R.
+func prove(x kyber.Scalar,
+	challenge func(R kyber.Point) (kyber.Scalar, error)) ([]byte, error) {
+
+	r := suite.Scalar().Pick(random.New())
+	R := suite.Point().Mul(r, nil)
+
+	e, err := challenge(R)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute challenge: %v", err)
+	}
+
+	// s = r + e*x
+	s := suite.Scalar().Add(r, suite.Scalar().Mul(e, x))
+
+	var buf bytes.Buffer
+
+	_, err = e.MarshalTo(&buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal e: %v", err)
+	}
+
+	_, err = s.MarshalTo(&buf)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal s: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// verify returns an error if the proof is not a proof of knowledge of the
+// scalar of P for the challenge.
+func verify(proof []byte, P kyber.Point,
+	challenge func(R kyber.Point) (kyber.Scalar, error)) error {
+
+	size := suite.Scalar().MarshalSize()
+	if len(proof) != 2*size {
+		return xerrors.Errorf("wrong proof size %d", len(proof))
+	}
+
+	e := suite.Scalar()
+	err := e.UnmarshalBinary(proof[:size])
+	if err != nil {
+		return xerrors.Errorf("failed to unmarshal e: %v", err)
+	}
+
+	s := suite.Scalar()
+	err = s.UnmarshalBinary(proof[size:])
+	if err != nil {
+		return xerrors.Errorf("failed to unmarshal s: %v", err)
+	}
+
+	// R = s*G - e*P
+	R := suite.Point().Sub(suite.Point().Mul(s, nil), suite.Point().Mul(e, P))
+
+	expected, err := challenge(R)
+	if err != nil {
+		return xerrors.Errorf("failed to compute challenge: %v", err)
+	}
+
+	if !expected.Equal(e) {
+		return xerrors.New("challenge mismatch")
+	}
+
+	return nil
+}
+
+// proofChallenge computes e = H(K || C || R || len(data) || data || expiry ||
+// policy) where the length of the data and the expiry, in nanoseconds since
+// the Unix epoch or zero, are 8 bytes in big-endian order, and the policy is
+// the JSON serialization of the access control.
+func proofChallenge(K, C, R kyber.Point, data []byte, expiry time.Time,
+	ac access.Service) (kyber.Scalar, error) {
+
+	msg, ok := ac.(serde.Message)
+	if !ok {
+		return nil, xerrors.Errorf("access '%T' is not serializable", ac)
+	}
+
+	policy, err := msg.Serialize(json.NewContext())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize access: %v", err)
+	}
+
+	h := sha256.New()
+
+	for _, point := range []kyber.Point{K, C, R} {
+		_, err = point.MarshalTo(h)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to marshal point: %v", err)
+		}
+	}
+
+	var nanos int64
+	if !expiry.IsZero() {
+		nanos = expiry.UnixNano()
+	}
+
+	buf := make([]byte, 8)
+
+	binary.BigEndian.PutUint64(buf, uint64(len(data)))
+	h.Write(buf)
+	h.Write(data)
+
+	binary.BigEndian.PutUint64(buf, uint64(nanos))
+	h.Write(buf)
+
+	h.Write(policy)
+
+	return suite.Scalar().SetBytes(h.Sum(nil)), nil
+}
+
+// readerKeyChallenge computes e = H(tag || pubk || R || nonce || id).
+func readerKeyChallenge(pubk, R kyber.Point, nonce,
+	id []byte) (kyber.Scalar, error) {
+
+	h := sha256.New()
+	h.Write([]byte(readerKeyTag))
+
+	for _, point := range []kyber.Point{pubk, R} {
+		_, err := point.MarshalTo(h)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to marshal point: %v", err)
+		}
+	}
+
+	h.Write(nonce)
+	h.Write(id)
+
+	return suite.Scalar().SetBytes(h.Sum(nil)), nil
+}
@@ -0,0 +1,318 @@
+package calypso
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// ReplicaValidator verifies the records that a node receives from the other
+// members when the records are replicated. A member is not trusted to store a
+// record that the node itself would have refused, or to change the access
+// control of a record without the authentication of its admin.
+//
+// - implements replicated.Validator
+type ReplicaValidator struct{}
+
+// NewReplicaValidator returns a new validator of the replicated records.
+func NewReplicaValidator() ReplicaValidator {
+	return ReplicaValidator{}
+}
+
+// ValidateRecord implements replicated.Validator. It returns an error if the
+// message is not a record that Write accepts under the key, followed by valid
+// updates of its access control.
+func (v ReplicaValidator) ValidateRecord(key []byte, msg serde.Message) error {
+	record, ok := msg.(Record)
+	if !ok {
+		return xerrors.Errorf("expected '%T' but got '%T'", record, msg)
+	}
+
+	return ValidateRecord(key, record)
+}
+
+// ValidateUpdate implements replicated.Validator. It returns an error if the
+// next record doesn't hold the same secret as the previous one, if its updates
+// are not valid, or if it is not a newer version.
+func (v ReplicaValidator) ValidateUpdate(key []byte, prev, next serde.Message) error {
+	prevRecord, ok := prev.(Record)
+	if !ok {
+		return xerrors.Errorf("expected '%T' but got '%T'", prevRecord, prev)
+	}
+
+	nextRecord, ok := next.(Record)
+	if !ok {
+		return xerrors.Errorf("expected '%T' but got '%T'", nextRecord, next)
+	}
+
+	if !sameSecret(prevRecord, nextRecord) {
+		return xerrors.New("update changes more than the access control")
+	}
+
+	err := ValidateRecord(key, nextRecord)
+	if err != nil {
+		return err
+	}
+
+	if nextRecord.GetVersion() <= prevRecord.GetVersion() {
+		return xerrors.Errorf("version %d is not newer than %d",
+			nextRecord.GetVersion(), prevRecord.GetVersion())
+	}
+
+	return nil
+}
+
+// ValidateRecord returns an error if the record can't be written under the
+// key, as verified by Write: the key must be the ID of the ciphertext, which
+// must be valid, and the write proof must bind the access control of the
+// write. Each update of the access control must then be authenticated by an
+// admin of the access control it replaces.
+func ValidateRecord(key []byte, record Record) error {
+	err := ValidateCiphertext(record.k, record.c)
+	if err != nil {
+		return xerrors.Errorf("failed to validate ciphertext: %w", err)
+	}
+
+	id, err := RecordID(record.k, record.c)
+	if err != nil {
+		return xerrors.Errorf("failed to compute ID: %v", err)
+	}
+
+	if !bytes.Equal(id, key) {
+		return xerrors.Errorf("record %#x stored under %#x", id, key)
+	}
+
+	if record.access == nil {
+		return xerrors.New("record has no access control")
+	}
+
+	err = VerifyWriteProof(record.proof, record.k, record.c, record.data,
+		record.expiry, record.access)
+	if err != nil {
+		return xerrors.Errorf("failed to verify proof: %w", err)
+	}
+
+	prev := record.access
+	var last time.Time
+
+	for i, update := range record.updates {
+		last, err = verifyUpdate(key, prev, update, last)
+		if err != nil {
+			return xerrors.Errorf("update %d: %w", i, err)
+		}
+
+		prev = update.access
+	}
+
+	return nil
+}
+
+// AccessUpdate is an update of the access control of a record, with the
+// authentication of the update by an admin of the access control it replaces.
+type AccessUpdate struct {
+	access access.Service
+	proof  []byte
+}
+
+// NewAccessUpdate creates a new update to the access control, authenticated by
+// the proof.
+func NewAccessUpdate(ac access.Service, proof []byte) AccessUpdate {
+	return AccessUpdate{
+		access: ac,
+		proof:  proof,
+	}
+}
+
+// GetAccess returns the new access control.
+func (u AccessUpdate) GetAccess() access.Service {
+	return u.access
+}
+
+// GetProof returns the authentication of the update.
+func (u AccessUpdate) GetProof() []byte {
+	return append([]byte{}, u.proof...)
+}
+
+// withUpdate returns the record with its access control replaced by the one
+// authenticated by auth. The nonce of an update must expire after the one of
+// the previous update, so that the updates of the record can't be replayed or
+// reordered.
+func (r Record) withUpdate(ac access.Service, auth Authentication) (Record, error) {
+	if len(r.updates) > 0 {
+		prev, err := DecodeAuthentication(r.updates[len(r.updates)-1].proof)
+		if err != nil {
+			return r, xerrors.Errorf("failed to decode last update: %v", err)
+		}
+
+		if !nonceExpiry(auth.Nonce).After(nonceExpiry(prev.Nonce)) {
+			return r, xerrors.Errorf("nonce %#x is older than the last update: %w",
+				auth.Nonce, ErrUnauthenticated)
+		}
+	}
+
+	proof, err := EncodeAuthentication(auth)
+	if err != nil {
+		return r, xerrors.Errorf("failed to encode proof: %v", err)
+	}
+
+	updates := make([]AccessUpdate, len(r.updates), len(r.updates)+1)
+	copy(updates, r.updates)
+
+	r.updates = append(updates, NewAccessUpdate(ac, proof))
+
+	return r, nil
+}
+
+// verifyUpdate returns an error if the update is not authenticated by an admin
+// of the previous access control, or if its nonce doesn't expire after the
+// given time. It returns the expiry of the nonce of the update.
+//
+// The nonces have been issued by other members and have usually expired, so
+// that only their order can be verified.
+func verifyUpdate(key []byte, prev access.Service, update AccessUpdate,
+	after time.Time) (time.Time, error) {
+
+	if update.access == nil {
+		return after, xerrors.New("new access control is nil")
+	}
+
+	auth, err := DecodeAuthentication(update.proof)
+	if err != nil {
+		return after, xerrors.Errorf("failed to decode proof: %v", err)
+	}
+
+	if len(auth.Nonce) != nonceLen {
+		return after, xerrors.Errorf("invalid nonce size %d: %w",
+			len(auth.Nonce), ErrUnauthenticated)
+	}
+
+	expiry := nonceExpiry(auth.Nonce)
+	if !expiry.After(after) {
+		return after, xerrors.Errorf("nonce %#x is older than the previous "+
+			"update: %w", auth.Nonce, ErrUnauthenticated)
+	}
+
+	digest, err := UpdateDigest(auth.Nonce, key, update.access)
+	if err != nil {
+		return after, xerrors.Errorf("failed to compute digest: %v", err)
+	}
+
+	idents, err := VerifyIdentities(auth, digest)
+	if err != nil {
+		return after, err
+	}
+
+	err = prev.Match(nil, NewCredential(key, ArcRuleUpdate), idents...)
+	if err != nil {
+		return after, xerrors.Errorf("%v: %w", err, ErrAccessDenied)
+	}
+
+	return expiry, nil
+}
+
+// sameVersion returns true if both records have the same updates.
+func sameVersion(a, b Record) bool {
+	if len(a.updates) != len(b.updates) {
+		return false
+	}
+
+	for i := range a.updates {
+		if !bytes.Equal(a.updates[i].proof, b.updates[i].proof) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameSecret returns true if both records hold the same secret, which may be
+// protected by different access controls. The write proof binds the access
+// control of the write.
+func sameSecret(a, b Record) bool {
+	return a.k.Equal(b.k) && a.c.Equal(b.c) && bytes.Equal(a.data, b.data) &&
+		bytes.Equal(a.proof, b.proof) && a.expiry.Equal(b.expiry)
+}
+
+// authenticationJSON is the JSON form of an authentication, which is kept in
+// the records for their updates and sent in the transactions of the contract,
+// so that it can be verified by the other members.
+type authenticationJSON struct {
+	Nonce      []byte
+	Identities []signedIdentityJSON
+}
+
+type signedIdentityJSON struct {
+	Identity  string
+	Signature []byte
+}
+
+// EncodeAuthentication returns the JSON form of the authentication.
+func EncodeAuthentication(auth Authentication) ([]byte, error) {
+	proof := authenticationJSON{
+		Nonce:      auth.Nonce,
+		Identities: make([]signedIdentityJSON, len(auth.Identities)),
+	}
+
+	for i, ident := range auth.Identities {
+		text, err := ident.PublicKey.MarshalText()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to marshal identity: %v", err)
+		}
+
+		sig, err := ident.Signature.MarshalBinary()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to marshal signature: %v", err)
+		}
+
+		proof.Identities[i] = signedIdentityJSON{
+			Identity:  string(text),
+			Signature: sig,
+		}
+	}
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal proof: %v", err)
+	}
+
+	return data, nil
+}
+
+// DecodeAuthentication returns the authentication of its JSON form. The
+// signatures are not verified.
+func DecodeAuthentication(data []byte) (Authentication, error) {
+	var proof authenticationJSON
+
+	err := json.Unmarshal(data, &proof)
+	if err != nil {
+		return Authentication{}, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	auth := Authentication{
+		Nonce:      proof.Nonce,
+		Identities: make([]SignedIdentity, len(proof.Identities)),
+	}
+
+	for i, ident := range proof.Identities {
+		pubkey, err := ParseIdentity(ident.Identity)
+		if err != nil {
+			return auth, xerrors.Errorf("identity %d: %v", i, err)
+		}
+
+		sig, err := ParseSignature(pubkey, ident.Signature)
+		if err != nil {
+			return auth, xerrors.Errorf("identity %d: %v", i, err)
+		}
+
+		auth.Identities[i] = SignedIdentity{
+			PublicKey: pubkey,
+			Signature: sig,
+		}
+	}
+
+	return auth, nil
+}
@@ -0,0 +1,269 @@
+package calypso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+func TestReplicaValidator_ValidateRecord(t *testing.T) {
+	alice := bls.NewSigner()
+
+	v := NewReplicaValidator()
+
+	msg, ac := makeMessage(t, alice)
+	record := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof))
+
+	id, err := RecordID(msg.K, msg.C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	err = v.ValidateRecord(id, record)
+	if err != nil {
+		t.Fatalf("expected a valid record, got: %v", err)
+	}
+
+	// The key must be the ID of the record.
+	err = v.ValidateRecord([]byte{0xaa}, record)
+	if err == nil {
+		t.Fatal("expected a record under another key to be refused")
+	}
+
+	// The proof binds the access control, so that a member can't give the
+	// record to another identity.
+	eveAc, err := NewAccess(bls.NewSigner().GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	err = v.ValidateRecord(id, NewRecord(msg.K, msg.C, eveAc, WithProof(msg.proof)))
+	if !xerrors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected an invalid proof, got: %v", err)
+	}
+
+	err = v.ValidateRecord(id, NewRecord(msg.K, msg.C, ac))
+	if !xerrors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected an invalid proof, got: %v", err)
+	}
+
+	invalid := suite.Point().Null()
+
+	err = v.ValidateRecord(id, NewRecord(invalid, msg.C, ac, WithProof(msg.proof)))
+	if !xerrors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected an invalid ciphertext, got: %v", err)
+	}
+
+	err = v.ValidateRecord(id, ac.(serde.Message))
+	if err == nil {
+		t.Fatal("expected a message that is not a record to be refused")
+	}
+}
+
+func TestReplicaValidator_ValidateRecord_Updates(t *testing.T) {
+	alice := bls.NewSigner()
+	bob := bls.NewSigner()
+
+	// The nonces are issued by the member where the update is done.
+	caly := NewCalypso(nil)
+
+	msg, ac := makeMessage(t, alice)
+	record := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof))
+
+	id, err := RecordID(msg.K, msg.C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	bobAc, err := NewAccess(bob.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	v := NewReplicaValidator()
+
+	// A record whose access control has been updated is still valid, for
+	// instance when it is repaired on a member added by a reshare.
+	toBob := update(t, record, bobAc, authenticateUpdate(t, caly, id, bobAc, alice))
+
+	err = v.ValidateRecord(id, toBob)
+	if err != nil {
+		t.Fatalf("expected a valid record, got: %v", err)
+	}
+
+	if toBob.GetVersion() != 1 || toBob.GetAccess() != bobAc ||
+		toBob.GetWriteAccess() != ac {
+		t.Fatal("unexpected updated record")
+	}
+
+	toAlice := update(t, toBob, ac, authenticateUpdate(t, caly, id, ac, bob))
+
+	err = v.ValidateRecord(id, toAlice)
+	if err != nil {
+		t.Fatalf("expected a valid record, got: %v", err)
+	}
+
+	// Only the admin of the previous access control can update it.
+	forged := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof),
+		WithUpdates(toAlice.GetUpdates()[1]))
+
+	err = v.ValidateRecord(id, forged)
+	if !xerrors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected access denied, got: %v", err)
+	}
+
+	// The update of alice to bob can't be replayed once bob gave the record
+	// back to alice.
+	replayed := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof),
+		WithUpdates(append(toAlice.GetUpdates(), toBob.GetUpdates()[0])...))
+
+	err = v.ValidateRecord(id, replayed)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a replay to be refused, got: %v", err)
+	}
+
+	_, err = toAlice.withUpdate(bobAc, updateAuth(t, toBob.GetUpdates()[0]))
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a replay to be refused, got: %v", err)
+	}
+
+	// The admin signed another access control than the one of the update.
+	eveAc, err := NewAccess(bls.NewSigner().GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	forged = NewRecord(msg.K, msg.C, ac, WithProof(msg.proof),
+		WithUpdates(NewAccessUpdate(eveAc, toBob.GetUpdates()[0].GetProof())))
+
+	err = v.ValidateRecord(id, forged)
+	if !xerrors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, got: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		update AccessUpdate
+		err    string
+	}{
+		{"nil access", NewAccessUpdate(nil, toBob.GetUpdates()[0].GetProof()),
+			"new access control is nil"},
+		{"malformed proof", NewAccessUpdate(bobAc, []byte("zz")),
+			"failed to decode proof"},
+		{"empty proof", NewAccessUpdate(bobAc, []byte("{}")), "invalid nonce size"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			record := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof),
+				WithUpdates(tc.update))
+
+			err := v.ValidateRecord(id, record)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error '%s', got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestReplicaValidator_ValidateUpdate(t *testing.T) {
+	alice := bls.NewSigner()
+	bob := bls.NewSigner()
+
+	caly := NewCalypso(nil)
+
+	msg, ac := makeMessage(t, alice)
+	prev := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof))
+
+	id, err := RecordID(msg.K, msg.C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	bobAc, err := NewAccess(bob.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	next := update(t, prev, bobAc, authenticateUpdate(t, caly, id, bobAc, alice))
+
+	v := NewReplicaValidator()
+
+	err = v.ValidateUpdate(id, prev, next)
+	if err != nil {
+		t.Fatalf("expected a valid update, got: %v", err)
+	}
+
+	// A member can't bring back an older version, or replace a record by the
+	// same version.
+	err = v.ValidateUpdate(id, next, prev)
+	if err == nil || !strings.Contains(err.Error(), "version 0 is not newer than 1") {
+		t.Fatalf("expected an older version to be refused, got: %v", err)
+	}
+
+	err = v.ValidateUpdate(id, next, next)
+	if err == nil || !strings.Contains(err.Error(), "version 1 is not newer than 1") {
+		t.Fatalf("expected the same version to be refused, got: %v", err)
+	}
+
+	// Only the admin of the previous record can update it.
+	forged := update(t, prev, bobAc, authenticateUpdate(t, caly, id, bobAc, bob))
+
+	err = v.ValidateUpdate(id, prev, forged)
+	if !xerrors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected access denied, got: %v", err)
+	}
+
+	// An update only replaces the access control.
+	extended := NewRecord(msg.K, msg.C, ac, WithProof(msg.proof),
+		WithExpiry(time.Now().Add(time.Hour)), WithUpdates(next.GetUpdates()...))
+
+	err = v.ValidateUpdate(id, prev, extended)
+	if err == nil {
+		t.Fatal("expected an update of the expiry to be refused")
+	}
+
+	err = v.ValidateUpdate(id, ac.(serde.Message), next)
+	if err == nil {
+		t.Fatal("expected a message that is not a record to be refused")
+	}
+
+	err = v.ValidateUpdate(id, prev, ac.(serde.Message))
+	if err == nil {
+		t.Fatal("expected a message that is not a record to be refused")
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// update returns the record with its access control updated by auth.
+func update(t *testing.T, record Record, ac access.Service,
+	auth Authentication) Record {
+
+	t.Helper()
+
+	record, err := record.withUpdate(ac, auth)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	return record
+}
+
+// updateAuth returns the authentication of the update.
+func updateAuth(t *testing.T, u AccessUpdate) Authentication {
+	t.Helper()
+
+	auth, err := DecodeAuthentication(u.proof)
+	if err != nil {
+		t.Fatalf("failed to decode proof: %v", err)
+	}
+
+	return auth
+}
@@ -0,0 +1,210 @@
+package disk
+
+import (
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"golang.org/x/xerrors"
+)
+
+// bucketName is the name of the bucket where the values are stored.
+var bucketName = []byte("calypso-records")
+
+// NewDisk returns a new storage that persists the values in the database. The
+// factory is used to deserialize the values when they are read. The storage
+// takes the ownership of the database, which is closed with the storage.
+func NewDisk(db kv.DB, fac serde.Factory) *Disk {
+	return &Disk{
+		db:      db,
+		fac:     fac,
+		context: json.NewContext(),
+	}
+}
+
+// Disk implements a key value storage on top of a key/value database. The
+// values are stored in their serialized form. The batches and the snapshots
+// are transactions of the database.
+//
+// implements storage.KeyValue
+type Disk struct {
+	db      kv.DB
+	fac     serde.Factory
+	context serde.Context
+}
+
+// Store implements storage.KeyValue
+func (d *Disk) Store(key []byte, value serde.Message) error {
+	err := d.Batch(func(tx storage.Transaction) error {
+		return tx.Store(key, value)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to store value: %v", err)
+	}
+
+	return nil
+}
+
+// Read implements storage.KeyValue
+func (d *Disk) Read(key []byte) (serde.Message, error) {
+	var msg serde.Message
+
+	err := d.Snapshot(func(r storage.Reader) error {
+		var err error
+		msg, err = r.Read(key)
+
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read value: %w", err)
+	}
+
+	return msg, nil
+}
+
+// Delete implements storage.KeyValue
+func (d *Disk) Delete(key []byte) error {
+	err := d.Batch(func(tx storage.Transaction) error {
+		return tx.Delete(key)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to delete value: %v", err)
+	}
+
+	return nil
+}
+
+// Scan implements storage.KeyValue
+func (d *Disk) Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error {
+	return d.Snapshot(func(r storage.Reader) error {
+		return r.Scan(prefix, fn)
+	})
+}
+
+// Batch implements storage.KeyValue. The operations are done in a single
+// transaction of the database.
+func (d *Disk) Batch(fn func(tx storage.Transaction) error) error {
+	return d.db.Update(func(txn kv.WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate(bucketName)
+		if err != nil {
+			return xerrors.Errorf("failed to get bucket: %v", err)
+		}
+
+		return fn(writer{disk: d, bucket: bucket})
+	})
+}
+
+// Snapshot implements storage.KeyValue. The reads are done in a single
+// read-only transaction of the database.
+func (d *Disk) Snapshot(fn func(r storage.Reader) error) error {
+	return d.db.View(func(txn kv.ReadableTx) error {
+		return fn(reader{disk: d, bucket: txn.GetBucket(bucketName)})
+	})
+}
+
+// Len implements storage.Counter
+func (d *Disk) Len() (int, error) {
+	n := 0
+
+	err := d.db.View(func(txn kv.ReadableTx) error {
+		bucket := txn.GetBucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			n++
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("failed to count values: %v", err)
+	}
+
+	return n, nil
+}
+
+// Close closes the underlying database.
+func (d *Disk) Close() error {
+	err := d.db.Close()
+	if err != nil {
+		return xerrors.Errorf("failed to close db: %v", err)
+	}
+
+	return nil
+}
+
+// reader reads the values in a transaction. The bucket is nil when nothing
+// has been stored yet.
+//
+// - implements storage.Reader
+type reader struct {
+	disk   *Disk
+	bucket kv.Bucket
+}
+
+// Read implements storage.Reader
+func (r reader) Read(key []byte) (serde.Message, error) {
+	var data []byte
+	if r.bucket != nil {
+		data = r.bucket.Get(key)
+	}
+
+	if len(data) == 0 {
+		return nil, xerrors.Errorf("failed to read %#x: %w", key,
+			storage.ErrNotFound)
+	}
+
+	msg, err := r.disk.fac.Deserialize(r.disk.context, data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to deserialize value: %v", err)
+	}
+
+	return msg, nil
+}
+
+// Scan implements storage.Reader
+func (r reader) Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error {
+	if r.bucket == nil {
+		return nil
+	}
+
+	return r.bucket.Scan(prefix, func(k, v []byte) error {
+		msg, err := r.disk.fac.Deserialize(r.disk.context, v)
+		if err != nil {
+			return xerrors.Errorf("failed to deserialize value: %v", err)
+		}
+
+		// The key is only valid during the transaction.
+		return fn(append([]byte{}, k...), msg)
+	})
+}
+
+// writer reads and writes the values in a transaction.
+//
+// - implements storage.Transaction
+type writer struct {
+	disk   *Disk
+	bucket kv.Bucket
+}
+
+// Read implements storage.Transaction. It sees the writes done before in the
+// transaction.
+func (w writer) Read(key []byte) (serde.Message, error) {
+	return reader{disk: w.disk, bucket: w.bucket}.Read(key)
+}
+
+// Store implements storage.Transaction
+func (w writer) Store(key []byte, value serde.Message) error {
+	data, err := value.Serialize(w.disk.context)
+	if err != nil {
+		return xerrors.Errorf("failed to serialize value: %v", err)
+	}
+
+	return w.bucket.Set(key, data)
+}
+
+// Delete implements storage.Transaction
+func (w writer) Delete(key []byte) error {
+	return w.bucket.Delete(key)
+}
@@ -0,0 +1,35 @@
+package disk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/storagetest"
+	"go.dedis.ch/dela/core/store/kv"
+)
+
+func TestDisk_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.KeyValue {
+		dir, err := ioutil.TempDir(os.TempDir(), "calypso-disk")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := kv.New(filepath.Join(dir, "records.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		store := NewDisk(db, storagetest.MessageFactory{})
+
+		t.Cleanup(func() {
+			store.Close()
+			os.RemoveAll(dir)
+		})
+
+		return store
+	})
+}
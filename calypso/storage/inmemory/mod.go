@@ -1,6 +1,11 @@
 package inmemory
 
 import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"go.dedis.ch/dela-apps/calypso/storage"
 	"go.dedis.ch/dela/serde"
 	"golang.org/x/xerrors"
 )
@@ -16,22 +21,181 @@ func NewInMemory() *InMemory {
 //
 // implements storage.KeyValue
 type InMemory struct {
+	sync.RWMutex
 	database map[string]serde.Message
 }
 
 // Store implements storage.KeyValue
 func (i *InMemory) Store(key []byte, value serde.Message) error {
+	i.Lock()
 	i.database[string(key)] = value
+	i.Unlock()
 
 	return nil
 }
 
 // Read implements storage.Read
 func (i *InMemory) Read(key []byte) (serde.Message, error) {
-	res, found := i.database[string(key)]
+	i.RLock()
+	defer i.RUnlock()
+
+	return read(i.database, key)
+}
+
+// Delete implements storage.KeyValue
+func (i *InMemory) Delete(key []byte) error {
+	i.Lock()
+	delete(i.database, string(key))
+	i.Unlock()
+
+	return nil
+}
+
+// Scan implements storage.KeyValue
+func (i *InMemory) Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error {
+	i.RLock()
+	defer i.RUnlock()
+
+	return scan(i.database, prefix, fn)
+}
+
+// Batch implements storage.KeyValue. The lock is held during the call, and the
+// operations are buffered and applied once the function returns.
+func (i *InMemory) Batch(fn func(tx storage.Transaction) error) error {
+	i.Lock()
+	defer i.Unlock()
+
+	b := &batch{database: i.database}
+
+	err := fn(b)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range b.ops {
+		if op.value == nil {
+			delete(i.database, op.key)
+		} else {
+			i.database[op.key] = op.value
+		}
+	}
+
+	return nil
+}
+
+// Snapshot implements storage.KeyValue. The writes are blocked during the
+// call.
+func (i *InMemory) Snapshot(fn func(r storage.Reader) error) error {
+	i.RLock()
+	defer i.RUnlock()
+
+	return fn(snapshot{database: i.database})
+}
+
+// Len implements storage.Counter
+func (i *InMemory) Len() (int, error) {
+	i.RLock()
+	defer i.RUnlock()
+
+	return len(i.database), nil
+}
+
+// snapshot is a reader on the database whose lock is held by the caller.
+//
+// - implements storage.Reader
+type snapshot struct {
+	database map[string]serde.Message
+}
+
+// Read implements storage.Reader
+func (s snapshot) Read(key []byte) (serde.Message, error) {
+	return read(s.database, key)
+}
+
+// Scan implements storage.Reader
+func (s snapshot) Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error {
+	return scan(s.database, prefix, fn)
+}
+
+// operation is a write of a batch. A nil value is a deletion.
+type operation struct {
+	key   string
+	value serde.Message
+}
+
+// batch buffers the operations of a batch on the database whose lock is held
+// by the caller.
+//
+// - implements storage.Transaction
+type batch struct {
+	database map[string]serde.Message
+	ops      []operation
+}
+
+// Read implements storage.Transaction. The last operation of the batch on the
+// key takes precedence over the database.
+func (b *batch) Read(key []byte) (serde.Message, error) {
+	for i := len(b.ops) - 1; i >= 0; i-- {
+		if b.ops[i].key != string(key) {
+			continue
+		}
+
+		if b.ops[i].value == nil {
+			return nil, xerrors.Errorf("failed to read %#x: %w", key,
+				storage.ErrNotFound)
+		}
+
+		return b.ops[i].value, nil
+	}
+
+	return read(b.database, key)
+}
+
+// Store implements storage.Transaction
+func (b *batch) Store(key []byte, value serde.Message) error {
+	if value == nil {
+		return xerrors.New("value is nil")
+	}
+
+	b.ops = append(b.ops, operation{key: string(key), value: value})
+
+	return nil
+}
+
+// Delete implements storage.Transaction
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, operation{key: string(key)})
+
+	return nil
+}
+
+func read(database map[string]serde.Message, key []byte) (serde.Message, error) {
+	res, found := database[string(key)]
 	if !found {
-		return nil, xerrors.New("key not found")
+		return nil, xerrors.Errorf("failed to read %#x: %w", key, storage.ErrNotFound)
 	}
 
 	return res, nil
 }
+
+func scan(database map[string]serde.Message, prefix []byte,
+	fn func(key []byte, value serde.Message) error) error {
+
+	keys := make([]string, 0, len(database))
+	for key := range database {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := fn([]byte(key), database[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
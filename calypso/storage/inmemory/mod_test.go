@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/storagetest"
+)
+
+func TestInMemory_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.KeyValue {
+		return NewInMemory()
+	})
+}
@@ -1,9 +1,75 @@
 package storage
 
-import "go.dedis.ch/dela/serde"
+import (
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
 
-// KeyValue defines a simple key value storage
-type KeyValue interface {
+// ErrNotFound is returned by a storage when the key doesn't exist.
+var ErrNotFound = xerrors.New("key not found")
+
+// Reader defines the read operations of a storage.
+type Reader interface {
+	// Read returns the value stored at the key, or an error wrapping
+	// ErrNotFound if the key doesn't exist.
+	Read(key []byte) (serde.Message, error)
+
+	// Scan calls the function for each key that starts with the prefix, in
+	// ascending order of the keys. An empty prefix matches all the keys. The
+	// iteration stops when the function returns an error, which is then
+	// returned. The storage must not be modified by the function.
+	Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error
+}
+
+// Writer defines the write operations of a storage.
+type Writer interface {
 	Store(key []byte, value serde.Message) error
+
+	// Delete removes the value stored at the key. It doesn't return an error
+	// if the key doesn't exist.
+	Delete(key []byte) error
+}
+
+// Transaction defines the operations of a batch. The reads see the values of
+// the storage with the writes of the batch done so far.
+type Transaction interface {
+	Writer
+
+	// Read returns the value stored at the key, or an error wrapping
+	// ErrNotFound if the key doesn't exist.
 	Read(key []byte) (serde.Message, error)
 }
+
+// KeyValue defines a simple key value storage. The implementations must be
+// safe to use concurrently.
+type KeyValue interface {
+	Reader
+	Writer
+
+	// Batch calls the function with a transaction whose writes are applied
+	// atomically when the function returns. No other write is applied during
+	// the call, so that a value can be checked and written in one step. The
+	// writes are discarded if the function returns an error, which is then
+	// returned. The storage must not be used by the function.
+	Batch(fn func(tx Transaction) error) error
+
+	// Snapshot calls the function with a reader on a consistent state of the
+	// storage, that is not affected by concurrent writes during the call. The
+	// storage must not be modified by the function.
+	Snapshot(fn func(r Reader) error) error
+}
+
+// Counter is implemented by the storages that can count the values they hold.
+type Counter interface {
+	Len() (int, error)
+}
+
+// Updater is implemented by the transactions of the storages that forward the
+// writes to other nodes. Those nodes refuse to replace a value, unless the
+// replacement proves that it is allowed.
+type Updater interface {
+	// Update replaces the value stored at the key by the new one, which is
+	// forwarded to the other nodes. The batch fails if the update doesn't
+	// reach enough of them.
+	Update(key []byte, value serde.Message) error
+}
@@ -0,0 +1,165 @@
+package json
+
+import (
+	"encoding/json"
+
+	"go.dedis.ch/dela-apps/calypso/storage/replicated/types"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	types.RegisterMessageFormat(serde.FormatJSON, msgFormat{})
+}
+
+// Push is the JSON message to store a record.
+type Push struct {
+	Key    []byte
+	Record json.RawMessage
+}
+
+// Query is the JSON message to fetch a record.
+type Query struct {
+	Key []byte
+}
+
+// QueryReply is the JSON reply of a query. The record is empty if it is not
+// found.
+type QueryReply struct {
+	Key    []byte
+	Record json.RawMessage `json:",omitempty"`
+}
+
+// Update is the JSON message to replace the access control of a record.
+type Update struct {
+	Key    []byte
+	Record json.RawMessage
+}
+
+// Ack is the JSON acknowledgement.
+type Ack struct{}
+
+// Message is a JSON container for the replication messages.
+type Message struct {
+	Push       *Push       `json:",omitempty"`
+	Query      *Query      `json:",omitempty"`
+	QueryReply *QueryReply `json:",omitempty"`
+	Update     *Update     `json:",omitempty"`
+	Ack        *Ack        `json:",omitempty"`
+}
+
+// msgFormat is the engine to encode and decode the replication messages in
+// JSON format.
+//
+// - implements serde.FormatEngine
+type msgFormat struct{}
+
+// Encode implements serde.FormatEngine. It returns the serialized data for the
+// message in JSON format.
+func (f msgFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	var m Message
+
+	switch in := msg.(type) {
+	case types.Push:
+		record, err := in.GetRecord().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't serialize record: %v", err)
+		}
+
+		m = Message{Push: &Push{Key: in.GetKey(), Record: record}}
+	case types.Query:
+		m = Message{Query: &Query{Key: in.GetKey()}}
+	case types.QueryReply:
+		reply := QueryReply{Key: in.GetKey()}
+
+		if in.GetRecord() != nil {
+			record, err := in.GetRecord().Serialize(ctx)
+			if err != nil {
+				return nil, xerrors.Errorf("couldn't serialize record: %v", err)
+			}
+
+			reply.Record = record
+		}
+
+		m = Message{QueryReply: &reply}
+	case types.Update:
+		record, err := in.GetRecord().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't serialize record: %v", err)
+		}
+
+		m = Message{Update: &Update{
+			Key:    in.GetKey(),
+			Record: record,
+		}}
+	case types.Ack:
+		m = Message{Ack: &Ack{}}
+	default:
+		return nil, xerrors.Errorf("unsupported message of type '%T'", msg)
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It populates the message from the JSON
+// data if appropriate, otherwise it returns an error.
+func (f msgFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := Message{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't deserialize message: %v", err)
+	}
+
+	switch {
+	case m.Push != nil:
+		record, err := decodeRecord(ctx, m.Push.Record)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't decode record: %v", err)
+		}
+
+		return types.NewPush(m.Push.Key, record), nil
+	case m.Query != nil:
+		return types.NewQuery(m.Query.Key), nil
+	case m.QueryReply != nil:
+		if len(m.QueryReply.Record) == 0 {
+			return types.NewQueryReply(m.QueryReply.Key, nil), nil
+		}
+
+		record, err := decodeRecord(ctx, m.QueryReply.Record)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't decode record: %v", err)
+		}
+
+		return types.NewQueryReply(m.QueryReply.Key, record), nil
+	case m.Update != nil:
+		record, err := decodeRecord(ctx, m.Update.Record)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't decode record: %v", err)
+		}
+
+		return types.NewUpdate(m.Update.Key, record), nil
+	case m.Ack != nil:
+		return types.NewAck(), nil
+	}
+
+	return nil, xerrors.New("message is empty")
+}
+
+func decodeRecord(ctx serde.Context, data []byte) (serde.Message, error) {
+	fac := ctx.GetFactory(types.RecordKey{})
+	if fac == nil {
+		return nil, xerrors.New("record factory is missing")
+	}
+
+	record, err := fac.Deserialize(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't deserialize: %v", err)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,626 @@
+// Package replicated implements a storage that replicates the Calypso records
+// on all the members of the collective authority, so that any member can serve
+// any record.
+//
+// A record stored on one member is pushed to the others. A member that misses
+// a record, for instance because it was down during the push, fetches it from
+// the others when it is read and then stores it locally (read-repair). The
+// records are versioned, so that a member only replaces a record by a newer
+// one, and an update fails unless it reaches a threshold of the members.
+//
+// The members are the participants of the DKG, which each node learns by
+// taking part in it, so that no member has to announce them to the others.
+// A member is not trusted with the records it sends either: they are
+// validated as the writes are, and a record is never replaced unless the
+// update is authenticated by its admin.
+package replicated
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated/types"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"golang.org/x/xerrors"
+
+	// Register the JSON format of the replication messages.
+	_ "go.dedis.ch/dela-apps/calypso/storage/replicated/json"
+)
+
+// rpcName is the name of the RPC used by the members to replicate the
+// records.
+const rpcName = "calypso-replication"
+
+// defaultTimeout is the default time to wait for the other members.
+const defaultTimeout = 10 * time.Second
+
+// defaultRetries is the default number of times an update is sent again to
+// the members that failed to store it.
+const defaultRetries = 2
+
+// ErrNotReplicated is returned when an update doesn't reach the threshold of
+// the members. The update is kept by the members that stored it.
+var ErrNotReplicated = xerrors.New("update not replicated")
+
+// Option is the type of option to create a replicated storage.
+type Option func(*Store)
+
+// WithTimeout is an option to set the time to wait for the other members when
+// a record is pushed or queried.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Store) {
+		s.timeout = timeout
+	}
+}
+
+// WithRetries is an option to set the number of times an update is sent
+// again to the members that failed to store it.
+func WithRetries(retries int) Option {
+	return func(s *Store) {
+		s.retries = retries
+	}
+}
+
+// Membership provides the members of the collective authority.
+type Membership interface {
+	// GetMembers returns the addresses of the members, or nil if they are not
+	// known yet.
+	GetMembers() ([]mino.Address, error)
+
+	// GetThreshold returns the number of members that must hold an update,
+	// or zero if the members are not known yet.
+	GetThreshold() (int, error)
+}
+
+// Versioned is implemented by the records that can be updated. A record only
+// replaces another one with a lower version. The records that don't
+// implement it have the version zero.
+type Versioned interface {
+	GetVersion() uint64
+}
+
+// Validator verifies the records received from the other members before they
+// are stored.
+type Validator interface {
+	// ValidateRecord returns an error if the record can't be stored under the
+	// key.
+	ValidateRecord(key []byte, record serde.Message) error
+
+	// ValidateUpdate returns an error if the previous record stored under the
+	// key can't be replaced by the next one.
+	ValidateUpdate(key []byte, prev, next serde.Message) error
+}
+
+// Store is a storage that replicates the records on the members of the
+// collective authority. The records are kept in a local storage.
+//
+// - implements storage.KeyValue
+type Store struct {
+	local     storage.KeyValue
+	rpc       mino.RPC
+	me        mino.Address
+	members   Membership
+	validator Validator
+	timeout   time.Duration
+	retries   int
+}
+
+// NewStore creates a new replicated storage on top of the local one. The
+// factory is used to deserialize the records received from the other members,
+// which are then verified by the validator.
+func NewStore(m mino.Mino, local storage.KeyValue, fac serde.Factory,
+	members Membership, validator Validator, opts ...Option) (*Store, error) {
+
+	s := &Store{
+		local:     local,
+		me:        m.GetAddress(),
+		members:   members,
+		validator: validator,
+		timeout:   defaultTimeout,
+		retries:   defaultRetries,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	msgFac := types.NewMessageFactory(fac)
+
+	rpc, err := m.CreateRPC(rpcName, handler{store: s}, msgFac)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create rpc: %v", err)
+	}
+
+	s.rpc = rpc
+
+	return s, nil
+}
+
+// GetPlayers returns the members of the collective authority, or nil if they
+// are not known yet.
+func (s *Store) GetPlayers() ([]mino.Address, error) {
+	players, err := s.members.GetMembers()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get members: %v", err)
+	}
+
+	return players, nil
+}
+
+// Store implements storage.KeyValue. It stores the record locally and pushes
+// it to the other members. A member that fails to store it will repair it
+// later when it is read.
+func (s *Store) Store(key []byte, value serde.Message) error {
+	err := s.local.Store(key, value)
+	if err != nil {
+		return xerrors.Errorf("failed to store locally: %v", err)
+	}
+
+	_, err = s.call(types.NewPush(key, value))
+	if err != nil {
+		dela.Logger.Warn().Err(err).Msgf("record %x not fully replicated", key)
+	}
+
+	return nil
+}
+
+// Read implements storage.KeyValue. It reads the record locally, or fetches it
+// from the other members and stores it locally if it is missing.
+func (s *Store) Read(key []byte) (serde.Message, error) {
+	value, err := s.local.Read(key)
+	if err == nil {
+		return value, nil
+	}
+
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		return nil, xerrors.Errorf("failed to read locally: %v", err)
+	}
+
+	replies, err := s.call(types.NewQuery(key))
+	if err != nil {
+		dela.Logger.Debug().Err(err).Msgf("query of %x incomplete", key)
+	}
+
+	// A stale member may still hold a version of the record from before an
+	// update, so that the newest valid one is repaired.
+	var best serde.Message
+
+	for _, reply := range replies {
+		qr, ok := reply.(types.QueryReply)
+		if !ok || qr.GetRecord() == nil {
+			continue
+		}
+
+		err = s.validator.ValidateRecord(key, qr.GetRecord())
+		if err != nil {
+			dela.Logger.Warn().Err(err).Msgf("invalid record %x", key)
+			continue
+		}
+
+		if best == nil || version(qr.GetRecord()) > version(best) {
+			best = qr.GetRecord()
+		}
+	}
+
+	if best == nil {
+		return nil, xerrors.Errorf("failed to read %#x: %w", key, storage.ErrNotFound)
+	}
+
+	value, err = s.storeNewer(key, best)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to repair: %v", err)
+	}
+
+	dela.Logger.Info().Msgf("record %x repaired", key)
+
+	return value, nil
+}
+
+// Delete implements storage.KeyValue. The record is only deleted locally, as
+// each member is expected to delete its own replicas.
+func (s *Store) Delete(key []byte) error {
+	return s.local.Delete(key)
+}
+
+// Scan implements storage.KeyValue. It iterates over the records held locally.
+func (s *Store) Scan(prefix []byte, fn func(key []byte, value serde.Message) error) error {
+	return s.local.Scan(prefix, fn)
+}
+
+// Batch implements storage.KeyValue. The batch is applied atomically to the
+// local storage, and the stored records are then pushed to the other members
+// as in Store. The transaction is a storage.Updater, so that the records can be
+// replaced on the other members too. An update is sent again to the members
+// that failed to store it, and the batch returns an error wrapping
+// ErrNotReplicated if it doesn't reach the threshold of the members. The local
+// storage keeps the update in that case, as do the members that stored it.
+func (s *Store) Batch(fn func(tx storage.Transaction) error) error {
+	var msgs []serde.Message
+
+	err := s.local.Batch(func(tx storage.Transaction) error {
+		return fn(recorder{Transaction: tx, msgs: &msgs})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		update, ok := msg.(types.Update)
+		if ok {
+			err = s.replicate(update)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		_, err = s.call(msg)
+		if err != nil {
+			dela.Logger.Warn().Err(err).Msg("record not fully replicated")
+		}
+	}
+
+	return nil
+}
+
+// replicate sends the update to the other members until the threshold of the
+// members, this node included, stores it.
+func (s *Store) replicate(update types.Update) error {
+	threshold, err := s.members.GetThreshold()
+	if err != nil {
+		return xerrors.Errorf("failed to get threshold: %v", err)
+	}
+
+	others := s.others()
+	pending := others
+
+	for i := 0; i <= s.retries && len(pending) > 0; i++ {
+		pending, err = s.callTo(update, pending)
+		if err != nil {
+			dela.Logger.Warn().Err(err).Msgf("update of %x not fully "+
+				"replicated (attempt %d)", update.GetKey(), i+1)
+		}
+	}
+
+	stored := len(others) - len(pending) + 1
+
+	if stored < threshold {
+		return xerrors.Errorf("update of %#x stored by %d/%d members: %w",
+			update.GetKey(), stored, threshold, ErrNotReplicated)
+	}
+
+	return nil
+}
+
+// Snapshot implements storage.KeyValue. It reads the records held locally.
+func (s *Store) Snapshot(fn func(r storage.Reader) error) error {
+	return s.local.Snapshot(fn)
+}
+
+// Len implements storage.Counter. It returns the number of records held
+// locally, which misses the ones that have not been replicated to this node.
+func (s *Store) Len() (int, error) {
+	counter, ok := s.local.(storage.Counter)
+	if !ok {
+		return 0, xerrors.Errorf("local storage '%T' can't count", s.local)
+	}
+
+	return counter.Len()
+}
+
+// Close closes the local storage if it supports it.
+func (s *Store) Close() error {
+	closer, ok := s.local.(interface{ Close() error })
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+// recorder is a transaction that records the values that are stored, so that
+// they can be sent to the other members once the batch is applied.
+//
+// - implements storage.Transaction
+// - implements storage.Updater
+type recorder struct {
+	storage.Transaction
+	msgs *[]serde.Message
+}
+
+// Store implements storage.Transaction. The value is pushed to the other
+// members, which refuse it if they already hold the key.
+func (r recorder) Store(key []byte, value serde.Message) error {
+	err := r.Transaction.Store(key, value)
+	if err != nil {
+		return err
+	}
+
+	*r.msgs = append(*r.msgs, types.NewPush(key, value))
+
+	return nil
+}
+
+// Update implements storage.Updater. The value is sent to the other members,
+// which verify it before they replace their value.
+func (r recorder) Update(key []byte, value serde.Message) error {
+	err := r.Transaction.Store(key, value)
+	if err != nil {
+		return err
+	}
+
+	*r.msgs = append(*r.msgs, types.NewUpdate(key, value))
+
+	return nil
+}
+
+// storeNewer stores the value locally unless the key already exists with the
+// same or a newer version, and returns the value stored at the key.
+func (s *Store) storeNewer(key []byte, value serde.Message) (serde.Message, error) {
+	err := s.local.Batch(func(tx storage.Transaction) error {
+		current, err := tx.Read(key)
+		if err == nil && version(current) >= version(value) {
+			value = current
+			return nil
+		}
+
+		if err == nil {
+			return tx.Store(key, value)
+		}
+
+		if !xerrors.Is(err, storage.ErrNotFound) {
+			return xerrors.Errorf("failed to read: %v", err)
+		}
+
+		return tx.Store(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// call sends the message to the other members and returns the replies. It
+// returns an error if at least one of the members failed to reply.
+func (s *Store) call(msg serde.Message) ([]serde.Message, error) {
+	others := s.others()
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	replies, _, err := s.send(msg, others)
+
+	return replies, err
+}
+
+// callTo sends the message to the members and returns the ones that failed to
+// reply, with an error if there are any.
+func (s *Store) callTo(msg serde.Message, addrs []mino.Address) ([]mino.Address, error) {
+	_, failed, err := s.send(msg, addrs)
+
+	return failed, err
+}
+
+// send sends the message to the members, and returns the replies and the
+// members that failed to reply.
+func (s *Store) send(msg serde.Message,
+	addrs []mino.Address) ([]serde.Message, []mino.Address, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	resps, err := s.rpc.Call(ctx, msg, mino.NewAddresses(addrs...))
+	if err != nil {
+		return nil, addrs, xerrors.Errorf("failed to call: %v", err)
+	}
+
+	replies := make([]serde.Message, 0, len(addrs))
+	replied := make([]mino.Address, 0, len(addrs))
+	var lastErr error
+
+	for resp := range resps {
+		reply, err := resp.GetMessageOrError()
+		if err != nil {
+			lastErr = xerrors.Errorf("%v: %v", resp.GetFrom(), err)
+			continue
+		}
+
+		replies = append(replies, reply)
+		replied = append(replied, resp.GetFrom())
+	}
+
+	failed := make([]mino.Address, 0, len(addrs)-len(replied))
+	for _, addr := range addrs {
+		if !contains(replied, addr) {
+			failed = append(failed, addr)
+		}
+	}
+
+	if lastErr != nil {
+		return replies, failed, lastErr
+	}
+
+	if len(failed) > 0 {
+		return replies, failed, xerrors.Errorf("only %d/%d replies",
+			len(replies), len(addrs))
+	}
+
+	return replies, nil, nil
+}
+
+// others returns the members other than this node.
+func (s *Store) others() []mino.Address {
+	players, err := s.members.GetMembers()
+	if err != nil {
+		dela.Logger.Warn().Err(err).Msg("failed to get members")
+		return nil
+	}
+
+	others := make([]mino.Address, 0, len(players))
+	for _, addr := range players {
+		if !addr.Equal(s.me) {
+			others = append(others, addr)
+		}
+	}
+
+	return others
+}
+
+// isPlayer returns true if the address is one of the members.
+func (s *Store) isPlayer(addr mino.Address) bool {
+	players, err := s.members.GetMembers()
+	if err != nil {
+		dela.Logger.Warn().Err(err).Msg("failed to get members")
+		return false
+	}
+
+	return contains(players, addr)
+}
+
+// contains returns true if the address is in the list.
+func contains(addrs []mino.Address, addr mino.Address) bool {
+	for _, a := range addrs {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameRecord returns true if both records have the same serialization.
+func sameRecord(a, b serde.Message) bool {
+	ctx := json.NewContext()
+
+	aBuf, err := a.Serialize(ctx)
+	if err != nil {
+		return false
+	}
+
+	bBuf, err := b.Serialize(ctx)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(aBuf, bBuf)
+}
+
+// version returns the version of the record, or zero if it is not versioned.
+func version(record serde.Message) uint64 {
+	versioned, ok := record.(Versioned)
+	if !ok {
+		return 0
+	}
+
+	return versioned.GetVersion()
+}
+
+// handler processes the messages of the other members.
+//
+// - implements mino.Handler
+type handler struct {
+	mino.UnsupportedHandler
+
+	store *Store
+}
+
+// Process implements mino.Handler.
+func (h handler) Process(req mino.Request) (serde.Message, error) {
+	if !h.store.isPlayer(req.Address) {
+		return nil, xerrors.Errorf("message from unknown member %v", req.Address)
+	}
+
+	switch msg := req.Message.(type) {
+	case types.Push:
+		err := h.push(msg)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to push %#x: %v", msg.GetKey(), err)
+		}
+
+		return types.NewAck(), nil
+	case types.Update:
+		err := h.update(msg)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to update %#x: %v",
+				msg.GetKey(), err)
+		}
+
+		return types.NewAck(), nil
+	case types.Query:
+		record, err := h.store.local.Read(msg.GetKey())
+		if xerrors.Is(err, storage.ErrNotFound) {
+			return types.NewQueryReply(msg.GetKey(), nil), nil
+		}
+
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read: %v", err)
+		}
+
+		return types.NewQueryReply(msg.GetKey(), record), nil
+	default:
+		return nil, xerrors.Errorf("unexpected message '%T'", req.Message)
+	}
+}
+
+// push stores the record of the message if it is valid and the key doesn't
+// exist yet.
+func (h handler) push(msg types.Push) error {
+	err := h.store.validator.ValidateRecord(msg.GetKey(), msg.GetRecord())
+	if err != nil {
+		return xerrors.Errorf("invalid record: %v", err)
+	}
+
+	return h.store.local.Batch(func(tx storage.Transaction) error {
+		_, err := tx.Read(msg.GetKey())
+		if err == nil {
+			return xerrors.New("record already exists")
+		}
+
+		if !xerrors.Is(err, storage.ErrNotFound) {
+			return xerrors.Errorf("failed to read: %v", err)
+		}
+
+		return tx.Store(msg.GetKey(), msg.GetRecord())
+	})
+}
+
+// update replaces the record of the message if the update is valid. A member
+// that missed the write stores the updated record if it is valid, so that
+// the update reaches the members that joined after the write.
+func (h handler) update(msg types.Update) error {
+	return h.store.local.Batch(func(tx storage.Transaction) error {
+		prev, err := tx.Read(msg.GetKey())
+		if xerrors.Is(err, storage.ErrNotFound) {
+			err = h.store.validator.ValidateRecord(msg.GetKey(), msg.GetRecord())
+			if err != nil {
+				return xerrors.Errorf("invalid record: %v", err)
+			}
+
+			return tx.Store(msg.GetKey(), msg.GetRecord())
+		}
+
+		if err != nil {
+			return xerrors.Errorf("failed to read: %v", err)
+		}
+
+		// The update is sent again when the acknowledgement is lost.
+		if sameRecord(prev, msg.GetRecord()) {
+			return nil
+		}
+
+		err = h.store.validator.ValidateUpdate(msg.GetKey(), prev,
+			msg.GetRecord())
+		if err != nil {
+			return xerrors.Errorf("invalid update: %v", err)
+		}
+
+		return tx.Store(msg.GetKey(), msg.GetRecord())
+	})
+}
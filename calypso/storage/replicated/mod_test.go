@@ -0,0 +1,622 @@
+package replicated
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso"
+	"go.dedis.ch/dela-apps/calypso/arc"
+	calycrypto "go.dedis.ch/dela-apps/calypso/crypto"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela-apps/calypso/storage/replicated/types"
+	"go.dedis.ch/dela/core/access"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minoch"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+
+	// The records are sent in JSON through minoch.
+	_ "go.dedis.ch/dela-apps/calypso/json"
+)
+
+var suite = suites.MustFind("Ed25519")
+
+var (
+	alice = bls.NewSigner()
+	bob   = bls.NewSigner()
+	eve   = bls.NewSigner()
+)
+
+func TestStore_Push(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{}
+	stores, minos := makeStores(t, manager, members, 3)
+
+	members.set(addresses(minos)...)
+
+	caly := calypso.NewCalypso(nil, calypso.WithStorage(stores[0]))
+
+	record, id := makeRecord(t, alice)
+
+	_, err := caly.Write(record, record.GetAccess())
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	for i, s := range stores {
+		_, err = s.local.Read(id)
+		if err != nil {
+			t.Fatalf("record not stored on %d: %v", i, err)
+		}
+	}
+
+	h := handler{store: stores[1]}
+	from := minos[0].GetAddress()
+
+	// A member can't overwrite a record, even with a valid one.
+	_, err = h.Process(mino.Request{Address: from, Message: types.NewPush(id, record)})
+	checkError(t, err, "record already exists")
+
+	other, otherID := makeRecord(t, alice)
+
+	// The key must be the ID of the record.
+	_, err = h.Process(mino.Request{Address: from, Message: types.NewPush(id, other)})
+	checkError(t, err, "invalid record")
+
+	// The write proof binds the access control.
+	forged := calypso.NewRecord(other.GetK(), other.GetC(), makeAccess(t, eve),
+		calypso.WithProof(other.GetProof()))
+
+	_, err = h.Process(mino.Request{Address: from,
+		Message: types.NewPush(otherID, forged)})
+	checkError(t, err, "invalid write proof")
+
+	_, err = stores[1].local.Read(otherID)
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("forged record stored: %v", err)
+	}
+
+	_, err = h.Process(mino.Request{Address: from,
+		Message: types.NewPush(otherID, other)})
+	if err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+}
+
+func TestStore_Members(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{}
+	stores, minos := makeStores(t, manager, members, 2)
+
+	outsider := minoch.MustCreate(manager, "outsider")
+
+	record, id := makeRecord(t, alice)
+	push := types.NewPush(id, record)
+
+	h := handler{store: stores[1]}
+
+	// The members are not known before the DKG is setup.
+	_, err := h.Process(mino.Request{Address: minos[0].GetAddress(), Message: push})
+	checkError(t, err, "message from unknown member")
+
+	err = stores[0].Store(id, record)
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+
+	_, err = stores[1].local.Read(id)
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("record pushed without members: %v", err)
+	}
+
+	members.set(addresses(minos)...)
+
+	for _, msg := range []serde.Message{push, types.NewQuery(id)} {
+		_, err = h.Process(mino.Request{Address: outsider.GetAddress(), Message: msg})
+		checkError(t, err, "message from unknown member")
+	}
+
+	_, err = h.Process(mino.Request{Address: minos[0].GetAddress(), Message: push})
+	if err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	// The members follow the DKG, for instance after a reshare.
+	members.set(minos[1].GetAddress(), outsider.GetAddress())
+
+	_, err = h.Process(mino.Request{Address: minos[0].GetAddress(),
+		Message: types.NewQuery(id)})
+	checkError(t, err, "message from unknown member")
+
+	players, err := stores[1].GetPlayers()
+	if err != nil || len(players) != 2 || !players[1].Equal(outsider.GetAddress()) {
+		t.Fatalf("unexpected players %v: %v", players, err)
+	}
+}
+
+func TestStore_Update(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{threshold: 3}
+	stores, minos := makeStores(t, manager, members, 3)
+
+	members.set(addresses(minos)...)
+
+	// The last member drops the updates while drops is not zero, and keeps
+	// them to be replayed.
+	var drops int32 = 1
+	updates := make(chan types.Update, 10)
+
+	minos[2].AddFilter(func(req mino.Request) bool {
+		update, ok := req.Message.(types.Update)
+		if !ok || atomic.LoadInt32(&drops) == 0 {
+			return true
+		}
+
+		atomic.AddInt32(&drops, -1)
+		updates <- update
+
+		return false
+	})
+
+	stores[0].timeout = 100 * time.Millisecond
+
+	caly := calypso.NewCalypso(nil, calypso.WithStorage(stores[0]))
+
+	record, id := makeRecord(t, alice)
+
+	_, err := caly.Write(record, record.GetAccess())
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	bobAc := makeAccess(t, bob)
+
+	// The update is sent again to the member that missed it.
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, bobAc, alice), bobAc)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	for _, s := range stores {
+		expectAdmin(t, s, id, bob)
+	}
+
+	first := <-updates
+
+	// The update fails when it doesn't reach the threshold, but is kept by
+	// the members that stored it.
+	atomic.StoreInt32(&drops, -1)
+
+	aliceAc := makeAccess(t, alice)
+
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, aliceAc, bob), aliceAc)
+	if !xerrors.Is(err, ErrNotReplicated) {
+		t.Fatalf("expected an update not replicated, got: %v", err)
+	}
+
+	checkError(t, err, "stored by 2/3 members")
+
+	expectAdmin(t, stores[1], id, alice)
+	expectAdmin(t, stores[2], id, bob)
+
+	atomic.StoreInt32(&drops, 0)
+
+	update := <-updates
+
+	h := handler{store: stores[2]}
+	from := minos[0].GetAddress()
+
+	// A member can't store the updated record as a new one.
+	_, err = h.Process(mino.Request{Address: from,
+		Message: types.NewPush(id, update.GetRecord())})
+	checkError(t, err, "record already exists")
+
+	// The update of bob is only valid for the access control he signed.
+	updated := update.GetRecord().(calypso.Record)
+	last := updated.GetUpdates()[1]
+
+	forged := calypso.NewRecord(record.GetK(), record.GetC(), record.GetAccess(),
+		calypso.WithProof(record.GetProof()),
+		calypso.WithUpdates(updated.GetUpdates()[0],
+			calypso.NewAccessUpdate(makeAccess(t, eve), last.GetProof())))
+
+	_, err = h.Process(mino.Request{Address: from,
+		Message: types.NewUpdate(id, forged)})
+	checkError(t, err, "invalid update")
+
+	_, err = h.Process(mino.Request{Address: from, Message: update})
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	expectAdmin(t, stores[2], id, alice)
+
+	// The same update is acknowledged again, but a member can't bring back
+	// the access control of bob with the first update.
+	_, err = h.Process(mino.Request{Address: from, Message: update})
+	if err != nil {
+		t.Fatalf("failed to update again: %v", err)
+	}
+
+	_, err = h.Process(mino.Request{Address: from, Message: first})
+	checkError(t, err, "version 1 is not newer than 2")
+
+	expectAdmin(t, stores[2], id, alice)
+
+	// A member that missed the write, for instance because it joined after
+	// it, stores the updated record.
+	err = stores[2].local.Delete(id)
+	if err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	_, err = h.Process(mino.Request{Address: from, Message: update})
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	expectAdmin(t, stores[2], id, alice)
+
+	_, otherID := makeRecord(t, alice)
+
+	_, err = h.Process(mino.Request{Address: from,
+		Message: types.NewUpdate(otherID, update.GetRecord())})
+	checkError(t, err, "invalid record")
+}
+
+func TestStore_ReadRepair(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{}
+	stores, minos := makeStores(t, manager, members, 2)
+
+	members.set(addresses(minos)...)
+
+	record, id := makeRecord(t, alice)
+
+	// The record is only stored by the second member, as if the first one
+	// was down during the push.
+	err := stores[1].local.Store(id, record)
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+
+	value, err := stores[0].Read(id)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	if !value.(calypso.Record).GetK().Equal(record.GetK()) {
+		t.Fatal("wrong record repaired")
+	}
+
+	_, err = stores[0].local.Read(id)
+	if err != nil {
+		t.Fatalf("record not repaired: %v", err)
+	}
+
+	// A miss of all the members is not found.
+	_, err = stores[0].Read([]byte{0xaa})
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+}
+
+func TestStore_ReadRepair_Newest(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{}
+	stores, minos := makeStores(t, manager, members, 3)
+
+	members.set(addresses(minos)...)
+
+	record, id := makeRecord(t, alice)
+	updated := updateRecord(t, record, id, alice, bob)
+
+	// The second member missed the update of the access control, and must
+	// not bring back the access control of alice.
+	for _, order := range [][]calypso.Record{{record, updated}, {updated, record}} {
+		for i, r := range order {
+			err := stores[i+1].local.Store(id, r)
+			if err != nil {
+				t.Fatalf("failed to store: %v", err)
+			}
+		}
+
+		err := stores[0].local.Delete(id)
+		if err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+
+		value, err := stores[0].Read(id)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+
+		if value.(calypso.Record).GetVersion() != 1 {
+			t.Fatalf("stale record repaired")
+		}
+
+		expectAdmin(t, stores[0], id, bob)
+	}
+}
+
+func TestStore_ReadRepair_Invalid(t *testing.T) {
+	manager := minoch.NewManager()
+	members := &fakeMembers{}
+	stores, minos := makeStores(t, manager, members, 1)
+
+	// The second member replies with a record that is not the one queried.
+	evil := &fakeReplier{}
+
+	m := minoch.MustCreate(manager, "evil")
+
+	_, err := m.CreateRPC(rpcName, evil, types.NewMessageFactory(recordFactory()))
+	if err != nil {
+		t.Fatalf("failed to create rpc: %v", err)
+	}
+
+	members.set(minos[0].GetAddress(), m.GetAddress())
+
+	record, id := makeRecord(t, alice)
+	other, _ := makeRecord(t, alice)
+
+	// The access control of the write is bound to the write proof.
+	stolen := calypso.NewRecord(record.GetK(), record.GetC(), makeAccess(t, bob),
+		calypso.WithProof(record.GetProof()))
+
+	forged := calypso.NewRecord(record.GetK(), record.GetC(), makeAccess(t, eve))
+
+	// The updates must be authenticated by the admin.
+	updated := updateRecord(t, record, id, alice, bob)
+
+	unsigned := calypso.NewRecord(record.GetK(), record.GetC(), record.GetAccess(),
+		calypso.WithProof(record.GetProof()),
+		calypso.WithUpdates(calypso.NewAccessUpdate(makeAccess(t, eve),
+			updated.GetUpdates()[0].GetProof())))
+
+	for _, reply := range []calypso.Record{other, stolen, forged, unsigned} {
+		evil.set(reply)
+
+		_, err = stores[0].Read(id)
+		if !xerrors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("expected not found, got: %v", err)
+		}
+
+		_, err = stores[0].local.Read(id)
+		if !xerrors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("invalid record repaired: %v", err)
+		}
+	}
+
+	evil.set(record)
+
+	_, err = stores[0].Read(id)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeStores(t *testing.T, manager *minoch.Manager, members Membership,
+	n int) ([]*Store, []*minoch.Minoch) {
+
+	t.Helper()
+
+	stores := make([]*Store, n)
+	minos := make([]*minoch.Minoch, n)
+
+	for i := range stores {
+		minos[i] = minoch.MustCreate(manager, fmt.Sprintf("node%d", i))
+
+		s, err := NewStore(minos[i], inmemory.NewInMemory(), recordFactory(),
+			members, calypso.NewReplicaValidator(), WithTimeout(time.Second))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		stores[i] = s
+	}
+
+	return stores, minos
+}
+
+func recordFactory() serde.Factory {
+	return calypso.NewRecordFactory(arc.NewFactory())
+}
+
+func addresses(minos []*minoch.Minoch) []mino.Address {
+	addrs := make([]mino.Address, len(minos))
+	for i, m := range minos {
+		addrs[i] = m.GetAddress()
+	}
+
+	return addrs
+}
+
+// makeRecord returns a new record administered by the signer, and its ID.
+func makeRecord(t *testing.T, admin crypto.Signer) (calypso.Record, []byte) {
+	t.Helper()
+
+	ac := makeAccess(t, admin)
+
+	pubkey := suite.Point().Pick(random.New())
+
+	k, K, C, err := calycrypto.Encrypt([]byte("secret"), pubkey, random.New())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	proof, err := calypso.NewWriteProof(k, K, C, nil, time.Time{}, ac)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	id, err := calypso.RecordID(K, C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	return calypso.NewRecord(K, C, ac, calypso.WithProof(proof)), id
+}
+
+func makeAccess(t *testing.T, admin crypto.Signer) access.Service {
+	t.Helper()
+
+	ac, err := calypso.NewAccess(admin.GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	return ac
+}
+
+func authenticateUpdate(t *testing.T, caly *calypso.Calypso, id []byte,
+	newAc access.Service, signer crypto.Signer) calypso.Authentication {
+
+	t.Helper()
+
+	nonce, _, err := caly.Challenge(id)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+
+	digest, err := calypso.UpdateDigest(nonce, id, newAc)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	auth, err := calypso.NewAuthentication(nonce, digest, signer)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	return auth
+}
+
+// updateRecord returns the record with its access control updated from the
+// admin to the new one.
+func updateRecord(t *testing.T, record calypso.Record, id []byte,
+	admin, newAdmin crypto.Signer) calypso.Record {
+
+	t.Helper()
+
+	local := inmemory.NewInMemory()
+	caly := calypso.NewCalypso(nil, calypso.WithStorage(local))
+
+	_, err := caly.Write(record, record.GetAccess())
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	newAc := makeAccess(t, newAdmin)
+
+	err = caly.UpdateAccess(id, authenticateUpdate(t, caly, id, newAc, admin), newAc)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	value, err := local.Read(id)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	return value.(calypso.Record)
+}
+
+// expectAdmin checks that the signer is the admin of the record held locally
+// by the store.
+func expectAdmin(t *testing.T, s *Store, id []byte, admin crypto.Signer) {
+	t.Helper()
+
+	value, err := s.local.Read(id)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	creds := calypso.NewCredential(id, calypso.ArcRuleUpdate)
+
+	err = value.(calypso.Record).GetAccess().Match(nil, creds, admin.GetPublicKey())
+	if err != nil {
+		t.Fatalf("unexpected admin: %v", err)
+	}
+}
+
+func checkError(t *testing.T, err error, substr string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error containing %q", substr)
+	}
+
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// fakeMembers is a membership that can be changed by the tests.
+//
+// - implements replicated.Membership
+type fakeMembers struct {
+	sync.Mutex
+	addrs     []mino.Address
+	threshold int
+}
+
+func (m *fakeMembers) set(addrs ...mino.Address) {
+	m.Lock()
+	m.addrs = addrs
+	m.Unlock()
+}
+
+func (m *fakeMembers) GetMembers() ([]mino.Address, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.addrs, nil
+}
+
+func (m *fakeMembers) GetThreshold() (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.threshold, nil
+}
+
+// fakeReplier is a member that replies to the queries with a record of its
+// choice.
+//
+// - implements mino.Handler
+type fakeReplier struct {
+	mino.UnsupportedHandler
+	sync.Mutex
+
+	record serde.Message
+}
+
+func (r *fakeReplier) set(record serde.Message) {
+	r.Lock()
+	r.record = record
+	r.Unlock()
+}
+
+func (r *fakeReplier) Process(req mino.Request) (serde.Message, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	query, ok := req.Message.(types.Query)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected message '%T'", req.Message)
+	}
+
+	return types.NewQueryReply(query.GetKey(), r.record), nil
+}
@@ -0,0 +1,202 @@
+// Package types defines the messages exchanged by the members of the
+// collective authority to replicate the Calypso records.
+package types
+
+import (
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
+	"golang.org/x/xerrors"
+)
+
+var msgFormats = registry.NewSimpleRegistry()
+
+// RegisterMessageFormat registers the engine for the provided format.
+func RegisterMessageFormat(c serde.Format, f serde.FormatEngine) {
+	msgFormats.Register(c, f)
+}
+
+// Push is the message sent to the other members when a record is stored, so
+// that they store it too.
+//
+// - implements serde.Message
+type Push struct {
+	key    []byte
+	record serde.Message
+}
+
+// NewPush creates a new push message.
+func NewPush(key []byte, record serde.Message) Push {
+	return Push{
+		key:    key,
+		record: record,
+	}
+}
+
+// GetKey returns the key of the record.
+func (p Push) GetKey() []byte {
+	return append([]byte{}, p.key...)
+}
+
+// GetRecord returns the record.
+func (p Push) GetRecord() serde.Message {
+	return p.record
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data for the push message.
+func (p Push) Serialize(ctx serde.Context) ([]byte, error) {
+	return serialize(ctx, p)
+}
+
+// Query is the message sent to the other members to fetch a record that is
+// missing locally.
+//
+// - implements serde.Message
+type Query struct {
+	key []byte
+}
+
+// NewQuery creates a new query message.
+func NewQuery(key []byte) Query {
+	return Query{
+		key: key,
+	}
+}
+
+// GetKey returns the key of the record.
+func (q Query) GetKey() []byte {
+	return append([]byte{}, q.key...)
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data for the query message.
+func (q Query) Serialize(ctx serde.Context) ([]byte, error) {
+	return serialize(ctx, q)
+}
+
+// QueryReply is the reply to a query. The record is nil if the member doesn't
+// have it.
+//
+// - implements serde.Message
+type QueryReply struct {
+	key    []byte
+	record serde.Message
+}
+
+// NewQueryReply creates a new query reply. The record is nil when it is not
+// found.
+func NewQueryReply(key []byte, record serde.Message) QueryReply {
+	return QueryReply{
+		key:    key,
+		record: record,
+	}
+}
+
+// GetKey returns the key of the record.
+func (r QueryReply) GetKey() []byte {
+	return append([]byte{}, r.key...)
+}
+
+// GetRecord returns the record, or nil if it is not found.
+func (r QueryReply) GetRecord() serde.Message {
+	return r.record
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data for the query reply.
+func (r QueryReply) Serialize(ctx serde.Context) ([]byte, error) {
+	return serialize(ctx, r)
+}
+
+// Update is the message sent to the other members when the access control of a
+// record is replaced, so that they replace it too. The record is verified by
+// the members before they replace theirs.
+//
+// - implements serde.Message
+type Update struct {
+	key    []byte
+	record serde.Message
+}
+
+// NewUpdate creates a new update message.
+func NewUpdate(key []byte, record serde.Message) Update {
+	return Update{
+		key:    key,
+		record: record,
+	}
+}
+
+// GetKey returns the key of the record.
+func (u Update) GetKey() []byte {
+	return append([]byte{}, u.key...)
+}
+
+// GetRecord returns the updated record.
+func (u Update) GetRecord() serde.Message {
+	return u.record
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data for the update message.
+func (u Update) Serialize(ctx serde.Context) ([]byte, error) {
+	return serialize(ctx, u)
+}
+
+// Ack is the reply of the messages that don't expect a result.
+//
+// - implements serde.Message
+type Ack struct{}
+
+// NewAck creates a new acknowledgement.
+func NewAck() Ack {
+	return Ack{}
+}
+
+// Serialize implements serde.Message. It looks up the format and returns the
+// serialized data for the acknowledgement.
+func (a Ack) Serialize(ctx serde.Context) ([]byte, error) {
+	return serialize(ctx, a)
+}
+
+func serialize(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, msg)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't encode message: %v", err)
+	}
+
+	return data, nil
+}
+
+// RecordKey is the key for the record factory.
+type RecordKey struct{}
+
+// MessageFactory is a message factory for the replication messages.
+//
+// - implements serde.Factory
+type MessageFactory struct {
+	recordFactory serde.Factory
+}
+
+// NewMessageFactory returns a message factory for the replication. The
+// records are deserialized with the factory.
+func NewMessageFactory(rf serde.Factory) MessageFactory {
+	return MessageFactory{
+		recordFactory: rf,
+	}
+}
+
+// Deserialize implements serde.Factory.
+func (f MessageFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	ctx = serde.WithFactory(ctx, RecordKey{}, f.recordFactory)
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't decode message: %v", err)
+	}
+
+	return msg, nil
+}
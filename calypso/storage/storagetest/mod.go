@@ -0,0 +1,407 @@
+// Package storagetest provides the conformance tests that every implementation
+// of storage.KeyValue must pass.
+//
+// A backend runs them from its own tests with:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.KeyValue {
+//			return NewBackend(storagetest.MessageFactory{})
+//		})
+//	}
+package storagetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// Message is the value stored by the conformance tests.
+//
+// - implements serde.Message
+type Message struct {
+	Value string
+}
+
+// Serialize implements serde.Message. It returns the value as is.
+func (m Message) Serialize(ctx serde.Context) ([]byte, error) {
+	return []byte(m.Value), nil
+}
+
+// MessageFactory deserializes the messages of the conformance tests, for the
+// backends that store the serialized values.
+//
+// - implements serde.Factory
+type MessageFactory struct{}
+
+// Deserialize implements serde.Factory.
+func (MessageFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return Message{Value: string(data)}, nil
+}
+
+// Run runs the conformance tests. The function must return a new empty storage
+// for each test.
+func Run(t *testing.T, newStore func(t *testing.T) storage.KeyValue) {
+	t.Run("StoreRead", func(t *testing.T) { testStoreRead(t, newStore(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newStore(t)) })
+	t.Run("Scan", func(t *testing.T) { testScan(t, newStore(t)) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, newStore(t)) })
+	t.Run("CheckAndStore", func(t *testing.T) { testCheckAndStore(t, newStore(t)) })
+	t.Run("Snapshot", func(t *testing.T) { testSnapshot(t, newStore(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newStore(t)) })
+}
+
+func testStoreRead(t *testing.T, store storage.KeyValue) {
+	_, err := store.Read([]byte("a"))
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+
+	mustStore(t, store, "a", "1")
+	expectValue(t, store, "a", "1")
+
+	mustStore(t, store, "a", "2")
+	expectValue(t, store, "a", "2")
+}
+
+func testDelete(t *testing.T, store storage.KeyValue) {
+	mustStore(t, store, "a", "1")
+
+	err := store.Delete([]byte("a"))
+	if err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	_, err = store.Read([]byte("a"))
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+
+	err = store.Delete([]byte("unknown"))
+	if err != nil {
+		t.Fatalf("failed to delete unknown key: %v", err)
+	}
+}
+
+func testScan(t *testing.T, store storage.KeyValue) {
+	for _, key := range []string{"b", "abc", "a", "ab", "ba"} {
+		mustStore(t, store, key, "value of "+key)
+	}
+
+	expectKeys(t, store, "a", "a", "ab", "abc")
+	expectKeys(t, store, "b", "b", "ba")
+	expectKeys(t, store, "", "a", "ab", "abc", "b", "ba")
+	expectKeys(t, store, "c")
+
+	stop := xerrors.New("stop")
+	n := 0
+
+	err := store.Scan(nil, func(key []byte, value serde.Message) error {
+		n++
+		return stop
+	})
+	if !xerrors.Is(err, stop) {
+		t.Fatalf("expected the error of the function, got: %v", err)
+	}
+
+	if n != 1 {
+		t.Fatalf("scan didn't stop: %d", n)
+	}
+
+	err = store.Scan([]byte("ab"), func(key []byte, value serde.Message) error {
+		msg, ok := value.(Message)
+		if !ok || msg.Value != "value of "+string(key) {
+			return xerrors.Errorf("unexpected value for %s: %v", key, value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+}
+
+func testBatch(t *testing.T, store storage.KeyValue) {
+	mustStore(t, store, "a", "1")
+
+	err := store.Batch(func(tx storage.Transaction) error {
+		err := tx.Store([]byte("b"), Message{Value: "2"})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Store([]byte("c"), Message{Value: "3"})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		// The reads see the writes done before in the batch.
+		value, err := tx.Read([]byte("b"))
+		if err != nil {
+			return err
+		}
+
+		if value.(Message).Value != "2" {
+			return xerrors.Errorf("unexpected value: %v", value)
+		}
+
+		_, err = tx.Read([]byte("a"))
+		if !xerrors.Is(err, storage.ErrNotFound) {
+			return xerrors.Errorf("expected not found, got: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to apply batch: %v", err)
+	}
+
+	expectKeys(t, store, "", "b", "c")
+
+	abort := xerrors.New("abort")
+
+	err = store.Batch(func(tx storage.Transaction) error {
+		err := tx.Store([]byte("d"), Message{Value: "4"})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete([]byte("b"))
+		if err != nil {
+			return err
+		}
+
+		return abort
+	})
+	if !xerrors.Is(err, abort) {
+		t.Fatalf("expected the error of the function, got: %v", err)
+	}
+
+	// Nothing of the failed batch must have been applied.
+	expectKeys(t, store, "", "b", "c")
+	expectValue(t, store, "b", "2")
+}
+
+// testCheckAndStore verifies that a value checked and stored in a batch can't
+// be stored by a concurrent batch in the meantime.
+func testCheckAndStore(t *testing.T, store storage.KeyValue) {
+	const workers = 16
+
+	exists := xerrors.New("exists")
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	errs := make([]error, workers)
+
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = store.Batch(func(tx storage.Transaction) error {
+				_, err := tx.Read([]byte("a"))
+				if err == nil {
+					return exists
+				}
+
+				if !xerrors.Is(err, storage.ErrNotFound) {
+					return err
+				}
+
+				return tx.Store([]byte("a"), Message{Value: fmt.Sprint(i)})
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	winner := -1
+
+	for i, err := range errs {
+		if err == nil {
+			if winner >= 0 {
+				t.Fatalf("both %d and %d stored the value", winner, i)
+			}
+
+			winner = i
+		} else if !xerrors.Is(err, exists) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if winner < 0 {
+		t.Fatal("no batch stored the value")
+	}
+
+	expectValue(t, store, "a", fmt.Sprint(winner))
+}
+
+func testSnapshot(t *testing.T, store storage.KeyValue) {
+	mustStore(t, store, "a", "1")
+
+	var wg sync.WaitGroup
+
+	err := store.Snapshot(func(r storage.Reader) error {
+		first, err := r.Read([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		// A concurrent write must not be seen by the snapshot, whether it
+		// waits for the snapshot or not.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := store.Store([]byte("a"), Message{Value: "2"})
+			if err != nil {
+				t.Errorf("failed to store: %v", err)
+			}
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		second, err := r.Read([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		if first != second {
+			return xerrors.Errorf("snapshot changed: %v != %v", first, second)
+		}
+
+		n := 0
+		err = r.Scan(nil, func(key []byte, value serde.Message) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if n != 1 {
+			return xerrors.Errorf("unexpected number of keys: %d", n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+
+	wg.Wait()
+
+	expectValue(t, store, "a", "2")
+}
+
+func testConcurrency(t *testing.T, store storage.KeyValue) {
+	const workers = 8
+	const n = 50
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < n; j++ {
+				key := []byte(fmt.Sprintf("%d-%d", i, j))
+
+				err := store.Store(key, Message{Value: "value"})
+				if err != nil {
+					t.Errorf("failed to store: %v", err)
+					return
+				}
+
+				_, err = store.Read(key)
+				if err != nil {
+					t.Errorf("failed to read: %v", err)
+					return
+				}
+
+				err = store.Scan([]byte(fmt.Sprintf("%d-", i)),
+					func(key []byte, value serde.Message) error { return nil })
+				if err != nil {
+					t.Errorf("failed to scan: %v", err)
+					return
+				}
+
+				if j%2 == 0 {
+					err = store.Delete(key)
+					if err != nil {
+						t.Errorf("failed to delete: %v", err)
+						return
+					}
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	count := 0
+	err := store.Scan(nil, func(key []byte, value serde.Message) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	if count != workers*n/2 {
+		t.Fatalf("expected %d keys, got %d", workers*n/2, count)
+	}
+}
+
+func mustStore(t *testing.T, store storage.KeyValue, key, value string) {
+	t.Helper()
+
+	err := store.Store([]byte(key), Message{Value: value})
+	if err != nil {
+		t.Fatalf("failed to store %s: %v", key, err)
+	}
+}
+
+func expectValue(t *testing.T, store storage.KeyValue, key, value string) {
+	t.Helper()
+
+	msg, err := store.Read([]byte(key))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", key, err)
+	}
+
+	if msg != (Message{Value: value}) {
+		t.Fatalf("unexpected value for %s: %v", key, msg)
+	}
+}
+
+func expectKeys(t *testing.T, store storage.KeyValue, prefix string,
+	keys ...string) {
+
+	t.Helper()
+
+	var res []string
+
+	err := store.Scan([]byte(prefix), func(key []byte, value serde.Message) error {
+		res = append(res, string(key))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan %q: %v", prefix, err)
+	}
+
+	if fmt.Sprint(res) != fmt.Sprint(keys) {
+		t.Fatalf("unexpected keys for %q: %v != %v", prefix, res, keys)
+	}
+}
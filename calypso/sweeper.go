@@ -0,0 +1,158 @@
+package calypso
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// Sweeper deletes the expired records of a storage in the background.
+type Sweeper struct {
+	sync.Mutex
+	store    storage.KeyValue
+	interval time.Duration
+	swept    int
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSweeper creates a new sweeper that sweeps the storage at each interval
+// once started.
+func NewSweeper(store storage.KeyValue, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Start starts to sweep the storage in the background. It does nothing if the
+// sweeper is already running.
+func (s *Sweeper) Start() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the sweeper and waits for the current sweep to finish.
+func (s *Sweeper) Stop() {
+	s.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+}
+
+// Sweep deletes the records that have expired and returns how many were
+// deleted.
+func (s *Sweeper) Sweep() (int, error) {
+	keys, err := s.expired(time.Now())
+	if err != nil {
+		return 0, xerrors.Errorf("failed to find expired records: %v", err)
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// The keys are deleted once the iteration is over as the storage can't
+	// be modified in the meantime.
+	err = s.store.Batch(func(tx storage.Transaction) error {
+		for _, key := range keys {
+			err := tx.Delete(key)
+			if err != nil {
+				return xerrors.Errorf("failed to delete %#x: %v", key, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("failed to delete: %v", err)
+	}
+
+	s.Lock()
+	s.swept += len(keys)
+	s.Unlock()
+
+	return len(keys), nil
+}
+
+// CountExpired returns the number of records that have expired but have not
+// been deleted yet.
+func (s *Sweeper) CountExpired() (int, error) {
+	keys, err := s.expired(time.Now())
+	if err != nil {
+		return 0, xerrors.Errorf("failed to find expired records: %v", err)
+	}
+
+	return len(keys), nil
+}
+
+// GetSwept returns the number of records deleted since the sweeper has been
+// created.
+func (s *Sweeper) GetSwept() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.swept
+}
+
+func (s *Sweeper) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			n, err := s.Sweep()
+			if err != nil {
+				dela.Logger.Warn().Err(err).Msg("failed to sweep records")
+			}
+
+			if n > 0 {
+				dela.Logger.Info().Msgf("%d expired records deleted", n)
+			}
+		}
+	}
+}
+
+// expired returns the keys of the records that are expired at the given time.
+func (s *Sweeper) expired(now time.Time) ([][]byte, error) {
+	var keys [][]byte
+
+	err := s.store.Scan(nil, func(key []byte, value serde.Message) error {
+		record, ok := value.(Record)
+		if ok && record.IsExpired(now) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to iterate: %v", err)
+	}
+
+	return keys, nil
+}
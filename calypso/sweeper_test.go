@@ -0,0 +1,188 @@
+package calypso
+
+import (
+	"testing"
+	"time"
+
+	"go.dedis.ch/dela-apps/calypso/storage"
+	"go.dedis.ch/dela-apps/calypso/storage/inmemory"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	store := &countingStorage{KeyValue: inmemory.NewInMemory()}
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	expired := [][]byte{
+		storeRecord(t, store, past),
+		storeRecord(t, store, past),
+		storeRecord(t, store, past),
+	}
+
+	live := [][]byte{
+		storeRecord(t, store, future),
+		storeRecord(t, store, time.Time{}),
+	}
+
+	store.batches = 0
+
+	sweeper := NewSweeper(store, time.Hour)
+
+	n, err := sweeper.CountExpired()
+	if err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+
+	if n != len(expired) {
+		t.Fatalf("expected %d expired records, got %d", len(expired), n)
+	}
+
+	n, err = sweeper.Sweep()
+	if err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+
+	if n != len(expired) || sweeper.GetSwept() != len(expired) {
+		t.Fatalf("expected %d records deleted, got %d (%d swept)",
+			len(expired), n, sweeper.GetSwept())
+	}
+
+	// The expired records are deleted in one batch.
+	if store.batches != 1 {
+		t.Fatalf("expected 1 batch, got %d", store.batches)
+	}
+
+	for _, key := range expired {
+		_, err := store.Read(key)
+		if err == nil {
+			t.Fatalf("expired record %#x not deleted", key)
+		}
+	}
+
+	for _, key := range live {
+		_, err := store.Read(key)
+		if err != nil {
+			t.Fatalf("live record %#x deleted: %v", key, err)
+		}
+	}
+
+	n, err = sweeper.CountExpired()
+	if err != nil || n != 0 {
+		t.Fatalf("expected no expired record, got %d: %v", n, err)
+	}
+
+	// Nothing is written when no record has expired.
+	n, err = sweeper.Sweep()
+	if err != nil || n != 0 {
+		t.Fatalf("expected no record deleted, got %d: %v", n, err)
+	}
+
+	if store.batches != 1 || sweeper.GetSwept() != len(expired) {
+		t.Fatalf("unexpected batch for an empty sweep")
+	}
+}
+
+func TestSweeper_StartStop(t *testing.T) {
+	store := inmemory.NewInMemory()
+
+	sweeper := NewSweeper(store, 10*time.Millisecond)
+
+	// Stopping a sweeper that is not running does nothing.
+	sweeper.Stop()
+
+	sweeper.Start()
+	sweeper.Start()
+
+	storeRecord(t, store, time.Now().Add(-time.Minute))
+
+	waitUntil(t, func() bool { return sweeper.GetSwept() == 1 })
+
+	done := sweeper.done
+
+	sweeper.Stop()
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("sweeper still running after stop")
+	}
+
+	// No sweep happens once stopped.
+	key := storeRecord(t, store, time.Now().Add(-time.Minute))
+
+	time.Sleep(50 * time.Millisecond)
+
+	_, err := store.Read(key)
+	if err != nil {
+		t.Fatalf("record deleted after stop: %v", err)
+	}
+
+	sweeper.Stop()
+
+	// The sweeper can be started again.
+	sweeper.Start()
+	defer sweeper.Stop()
+
+	waitUntil(t, func() bool { return sweeper.GetSwept() == 2 })
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// storeRecord stores a new record with the expiry and returns its key.
+func storeRecord(t *testing.T, store storage.KeyValue, expiry time.Time) []byte {
+	t.Helper()
+
+	ac, err := NewAccess(bls.NewSigner().GetPublicKey())
+	if err != nil {
+		t.Fatalf("failed to create access: %v", err)
+	}
+
+	K := suite.Point().Pick(random.New())
+	C := suite.Point().Pick(random.New())
+
+	key, err := RecordID(K, C)
+	if err != nil {
+		t.Fatalf("failed to compute ID: %v", err)
+	}
+
+	record := NewRecord(K, C, ac, WithExpiry(expiry))
+
+	err = store.Batch(func(tx storage.Transaction) error {
+		return tx.Store(key, record)
+	})
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+
+	return key
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// countingStorage is a storage that counts its batches.
+type countingStorage struct {
+	storage.KeyValue
+	batches int
+}
+
+func (s *countingStorage) Batch(fn func(tx storage.Transaction) error) error {
+	s.batches++
+
+	return s.KeyValue.Batch(fn)
+}
@@ -1,6 +1,6 @@
 module go.dedis.ch/dela-apps
 
-go 1.14
+go 1.16
 
 require (
 	go.dedis.ch/dela v0.0.0-20211018150429-1fdbe35cd189